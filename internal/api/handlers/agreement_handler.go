@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"loan/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type AgreementHandler struct {
+	loanService *service.LoanService
+}
+
+func NewAgreementHandler(loanService *service.LoanService) *AgreementHandler {
+	return &AgreementHandler{
+		loanService: loanService,
+	}
+}
+
+// GetAgreementLetter serves the PDF agreement letter of an invested loan
+func (h *AgreementHandler) GetAgreementLetter(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	letter, err := h.loanService.GetAgreementLetter(r.Context(), loanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(letter)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(letter)
+}
@@ -1,11 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
 	"loan/internal/domain"
 	"loan/internal/service"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -31,16 +29,16 @@ func (h *ApprovalHandler) ApproveLoan(w http.ResponseWriter, r *http.Request) {
 	loanID := vars["id"]
 
 	var req ApprovalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body")
-		writeJSON(w, http.StatusBadRequest, response)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	approvalDate, err := time.Parse("2006-01-02", req.ApprovalDate)
-	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid approval date format. Use YYYY-MM-DD")
-		writeJSON(w, http.StatusBadRequest, response)
+	var v fieldValidator
+	v.required("proof_picture_url", req.ProofPictureURL)
+	v.required("field_validator_id", req.FieldValidatorID)
+	approvalDate := v.date("approval_date", req.ApprovalDate)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -53,8 +51,7 @@ func (h *ApprovalHandler) ApproveLoan(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, err.Error())
-		writeJSON(w, http.StatusBadRequest, response)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+	"strconv"
+)
+
+// AuditHandler serves the admin endpoints for querying and verifying the
+// audit trail
+type AuditHandler struct {
+	loanService *service.LoanService
+}
+
+func NewAuditHandler(loanService *service.LoanService) *AuditHandler {
+	return &AuditHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *AuditHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var v fieldValidator
+	filter := domain.AuditFilter{
+		LoanID: query.Get("loan_id"),
+		Actor:  query.Get("actor"),
+		From:   v.queryTime("from", query.Get("from")),
+		To:     v.queryTime("to", query.Get("to")),
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page := 1
+	pageSize := 10
+
+	if p := query.Get("page"); p != "" {
+		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if ps := query.Get("page_size"); ps != "" {
+		if parsedPageSize, err := strconv.Atoi(ps); err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	entries, total, err := h.loanService.ListAuditEntries(r.Context(), filter, page, pageSize)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Audit entries retrieved successfully",
+		domain.NewPaginatedResponse(entries, total, page, pageSize),
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *AuditHandler) VerifyTrail(w http.ResponseWriter, r *http.Request) {
+	verification, err := h.loanService.VerifyAuditTrail(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	message := "Audit trail verified"
+	if !verification.Valid {
+		message = "Audit trail has been tampered with"
+	}
+
+	response := domain.NewSuccessResponse(http.StatusOK, message, verification)
+	writeJSON(w, http.StatusOK, response)
+}
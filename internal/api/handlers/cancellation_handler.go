@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type CancellationHandler struct {
+	loanService *service.LoanService
+}
+
+func NewCancellationHandler(loanService *service.LoanService) *CancellationHandler {
+	return &CancellationHandler{
+		loanService: loanService,
+	}
+}
+
+type CancellationRequest struct {
+	Reason           string `json:"reason"`
+	CancelledBy      string `json:"cancelled_by"`
+	CancellationDate string `json:"cancellation_date"` // Format: YYYY-MM-DD
+}
+
+func (h *CancellationHandler) CancelLoan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	var req CancellationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var v fieldValidator
+	v.required("reason", req.Reason)
+	v.required("cancelled_by", req.CancelledBy)
+	cancellationDate := v.date("cancellation_date", req.CancellationDate)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	loan, err := h.loanService.CancelLoan(
+		r.Context(),
+		loanID,
+		req.Reason,
+		req.CancelledBy,
+		cancellationDate,
+	)
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Loan cancelled successfully",
+		loan,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -1,11 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
 	"loan/internal/domain"
 	"loan/internal/service"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -31,16 +29,16 @@ func (h *DisbursementHandler) DisburseLoan(w http.ResponseWriter, r *http.Reques
 	loanID := vars["id"]
 
 	var req DisbursementRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body")
-		writeJSON(w, http.StatusBadRequest, response)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	disbursementDate, err := time.Parse("2006-01-02", req.DisbursementDate)
-	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid disbursement date format. Use YYYY-MM-DD")
-		writeJSON(w, http.StatusBadRequest, response)
+	var v fieldValidator
+	v.required("agreement_document_url", req.AgreementDocumentURL)
+	v.required("field_officer_id", req.FieldOfficerID)
+	disbursementDate := v.date("disbursement_date", req.DisbursementDate)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -53,8 +51,7 @@ func (h *DisbursementHandler) DisburseLoan(w http.ResponseWriter, r *http.Reques
 	)
 
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, err.Error())
-		writeJSON(w, http.StatusBadRequest, response)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type EventHandler struct {
+	loanService *service.LoanService
+}
+
+func NewEventHandler(loanService *service.LoanService) *EventHandler {
+	return &EventHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *EventHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	events, err := h.loanService.GetLoanEvents(r.Context(), loanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Loan events retrieved successfully",
+		events,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
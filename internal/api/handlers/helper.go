@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"loan/internal/domain"
 	"net/http"
 )
 
@@ -10,3 +12,32 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
+
+// writeError answers with the status and error code for err's kind. Errors
+// of no known kind are internal: they are logged, and the caller only
+// learns that the request failed.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusFor(err)
+	if status == http.StatusInternalServerError {
+		domain.LoggerFromContext(r.Context()).Error("internal error", "error", err)
+		writeJSON(w, status, domain.NewErrorResponse(status, "Internal server error"))
+		return
+	}
+
+	writeJSON(w, status, domain.NewErrorResponseFor(status, err))
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidState), errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"loan/internal/domain"
 	"loan/internal/service"
 	"net/http"
@@ -20,8 +19,9 @@ func NewInvestmentHandler(loanService *service.LoanService) *InvestmentHandler {
 }
 
 type InvestmentRequest struct {
-	InvestorID string  `json:"investor_id"`
-	Amount     float64 `json:"amount"`
+	InvestorID string `json:"investor_id"`
+	Amount     string `json:"amount"`   // Decimal string, e.g. "250000.00"
+	Currency   string `json:"currency"` // Must match the loan currency
 }
 
 func (h *InvestmentHandler) AddInvestment(w http.ResponseWriter, r *http.Request) {
@@ -29,9 +29,15 @@ func (h *InvestmentHandler) AddInvestment(w http.ResponseWriter, r *http.Request
 	loanID := vars["id"]
 
 	var req InvestmentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body")
-		writeJSON(w, http.StatusBadRequest, response)
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var v fieldValidator
+	v.required("investor_id", req.InvestorID)
+	amount := v.positiveMoney("amount", req.Amount, "currency", req.Currency)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -39,12 +45,11 @@ func (h *InvestmentHandler) AddInvestment(w http.ResponseWriter, r *http.Request
 		r.Context(),
 		loanID,
 		req.InvestorID,
-		req.Amount,
+		amount,
 	)
 
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, err.Error())
-		writeJSON(w, http.StatusBadRequest, response)
+		writeError(w, r, err)
 		return
 	}
 
@@ -63,26 +68,19 @@ func (h *InvestmentHandler) GetInvestments(w http.ResponseWriter, r *http.Reques
 
 	investments, err := h.loanService.GetLoanInvestments(r.Context(), loanID)
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusInternalServerError, err.Error())
-		writeJSON(w, http.StatusInternalServerError, response)
+		writeError(w, r, err)
 		return
 	}
 
 	loan, err := h.loanService.GetLoan(r.Context(), loanID)
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusInternalServerError, err.Error())
-		writeJSON(w, http.StatusInternalServerError, response)
+		writeError(w, r, err)
 		return
 	}
 
-	var totalInvested float64
-	for _, inv := range investments {
-		totalInvested += inv.Amount
-	}
-
 	investmentSummary := domain.NewInvestmentSummary(
 		investments,
-		totalInvested,
+		loan.TotalInvestedAmount(),
 		loan.PrincipalAmount,
 	)
 
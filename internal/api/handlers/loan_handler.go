@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"loan/internal/domain"
 	"loan/internal/service"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -22,7 +22,8 @@ func NewLoanHandler(loanService *service.LoanService) *LoanHandler {
 
 type CreateLoanRequest struct {
 	BorrowerID      string  `json:"borrower_id"`
-	PrincipalAmount float64 `json:"principal_amount"`
+	PrincipalAmount string  `json:"principal_amount"` // Decimal string, e.g. "1000000.00"
+	Currency        string  `json:"currency"`         // ISO-4217 code, e.g. "IDR"
 	Rate            float64 `json:"rate"`
 	ROI             float64 `json:"roi"`
 }
@@ -30,16 +31,23 @@ type CreateLoanRequest struct {
 func (h *LoanHandler) CreateLoan(w http.ResponseWriter, r *http.Request) {
 	var req CreateLoanRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body")
-		writeJSON(w, http.StatusBadRequest, response)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
-	loan, err := h.loanService.CreateLoan(r.Context(), req.BorrowerID, req.PrincipalAmount, req.Rate, req.ROI)
+	var v fieldValidator
+	v.required("borrower_id", req.BorrowerID)
+	principalAmount := v.positiveMoney("principal_amount", req.PrincipalAmount, "currency", req.Currency)
+	v.nonNegative("rate", req.Rate)
+	v.nonNegative("roi", req.ROI)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	loan, err := h.loanService.CreateLoan(r.Context(), req.BorrowerID, principalAmount, req.Rate, req.ROI)
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusBadRequest, err.Error())
-		writeJSON(w, http.StatusBadRequest, response)
+		writeError(w, r, err)
 		return
 	}
 
@@ -58,8 +66,7 @@ func (h *LoanHandler) GetLoan(w http.ResponseWriter, r *http.Request) {
 
 	loan, err := h.loanService.GetLoan(r.Context(), id)
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusNotFound, err.Error())
-		writeJSON(w, http.StatusNotFound, response)
+		writeError(w, r, err)
 		return
 	}
 
@@ -75,14 +82,43 @@ func (h *LoanHandler) GetLoan(w http.ResponseWriter, r *http.Request) {
 func (h *LoanHandler) ListLoans(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	filters := make(map[string]interface{})
+	var (
+		filter domain.LoanFilter
+		v      fieldValidator
+	)
+
+	// state may be repeated or comma-separated, e.g. state=REJECTED,CANCELLED
+	for _, value := range query["state"] {
+		for _, name := range strings.Split(value, ",") {
+			state, err := domain.ParseLoanState(strings.TrimSpace(name))
+			if err != nil {
+				v.add("state", domain.FieldUnsupported, err.Error())
+				continue
+			}
+			filter.States = append(filter.States, state)
+		}
+	}
+
+	filter.BorrowerID = query.Get("borrower_id")
+
+	// Principal bounds are decimal amounts in the currency given by currency
+	filter.MinPrincipal = v.queryMoney("min_principal", query.Get("min_principal"), "currency", query.Get("currency"))
+	filter.MaxPrincipal = v.queryMoney("max_principal", query.Get("max_principal"), "currency", query.Get("currency"))
+	filter.CreatedFrom = v.queryTime("created_from", query.Get("created_from"))
+	filter.CreatedTo = v.queryTime("created_to", query.Get("created_to"))
+	filter.MinFundedPercent = v.queryNumber("min_funded_percent", query.Get("min_funded_percent"))
+	filter.MaxFundedPercent = v.queryNumber("max_funded_percent", query.Get("max_funded_percent"))
 
-	if state := query.Get("state"); state != "" {
-		filters["state"] = domain.LoanState(state)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	if borrowerID := query.Get("borrower_id"); borrowerID != "" {
-		filters["borrower_id"] = borrowerID
+	// sort is a field, prefixed with "-" for descending order
+	order, err := domain.ParseLoanSort(query.Get("sort"))
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	page := 1
@@ -100,14 +136,54 @@ func (h *LoanHandler) ListLoans(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	loans, total, err := h.loanService.ListLoans(r.Context(), page, pageSize)
+	// A cursor continues a listing after the last loan of a previous page,
+	// and takes the place of page
+	var (
+		loans []*domain.Loan
+		total int
+		next  *domain.LoanCursor
+	)
+	if cursor := query.Get("cursor"); cursor != "" {
+		if query.Get("page") != "" {
+			writeError(w, r, domain.ValidationError("page and cursor cannot be combined"))
+			return
+		}
+
+		after, err := domain.DecodeLoanCursor(cursor, order)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		page = 0
+		loans, total, next, err = h.loanService.ListLoansAfter(r.Context(), filter, order, after, pageSize)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	} else {
+		loans, total, err = h.loanService.ListLoans(r.Context(), filter, order, page, pageSize)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		if page*pageSize < total && len(loans) > 0 {
+			next = domain.NewLoanCursor(order, loans[len(loans)-1])
+		}
+	}
+
+	totals, err := h.loanService.SummarizeLoans(r.Context(), filter)
 	if err != nil {
-		response := domain.NewErrorResponse(http.StatusInternalServerError, err.Error())
-		writeJSON(w, http.StatusInternalServerError, response)
+		writeError(w, r, err)
 		return
 	}
 
 	paginatedResponse := domain.NewPaginatedResponse(loans, total, page, pageSize)
+	paginatedResponse.Totals = totals
+	if next != nil {
+		paginatedResponse.NextCursor = next.Encode()
+	}
 
 	response := domain.NewSuccessResponse(
 		http.StatusOK,
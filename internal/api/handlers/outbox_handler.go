@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// OutboxHandler serves the admin endpoints for inspecting and replaying
+// outbox deliveries
+type OutboxHandler struct {
+	loanService *service.LoanService
+}
+
+func NewOutboxHandler(loanService *service.LoanService) *OutboxHandler {
+	return &OutboxHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *OutboxHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var status domain.OutboxStatus
+	if s := query.Get("status"); s != "" {
+		parsed, err := domain.ParseOutboxStatus(s)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		status = parsed
+	}
+
+	page := 1
+	pageSize := 10
+
+	if p := query.Get("page"); p != "" {
+		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if ps := query.Get("page_size"); ps != "" {
+		if parsedPageSize, err := strconv.Atoi(ps); err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	messages, total, err := h.loanService.ListOutboxMessages(r.Context(), status, page, pageSize)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Outbox messages retrieved successfully",
+		domain.NewPaginatedResponse(messages, total, page, pageSize),
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *OutboxHandler) ReplayMessage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	message, err := h.loanService.ReplayOutboxMessage(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Outbox message queued for redelivery",
+		message,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
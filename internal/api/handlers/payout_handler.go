@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type PayoutHandler struct {
+	loanService *service.LoanService
+}
+
+func NewPayoutHandler(loanService *service.LoanService) *PayoutHandler {
+	return &PayoutHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *PayoutHandler) GetPayouts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	ledger, err := h.loanService.GetPayoutLedger(r.Context(), loanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Payouts retrieved successfully",
+		ledger,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type RejectionHandler struct {
+	loanService *service.LoanService
+}
+
+func NewRejectionHandler(loanService *service.LoanService) *RejectionHandler {
+	return &RejectionHandler{
+		loanService: loanService,
+	}
+}
+
+type RejectionRequest struct {
+	Reason        string `json:"reason"`
+	ReviewerID    string `json:"reviewer_id"`
+	RejectionDate string `json:"rejection_date"` // Format: YYYY-MM-DD
+}
+
+func (h *RejectionHandler) RejectLoan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	var req RejectionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var v fieldValidator
+	v.required("reason", req.Reason)
+	v.required("reviewer_id", req.ReviewerID)
+	rejectionDate := v.date("rejection_date", req.RejectionDate)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	loan, err := h.loanService.RejectLoan(
+		r.Context(),
+		loanID,
+		req.Reason,
+		req.ReviewerID,
+		rejectionDate,
+	)
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Loan rejected successfully",
+		loan,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type RepaymentHandler struct {
+	loanService *service.LoanService
+}
+
+func NewRepaymentHandler(loanService *service.LoanService) *RepaymentHandler {
+	return &RepaymentHandler{
+		loanService: loanService,
+	}
+}
+
+type RepaymentRequest struct {
+	Amount      string `json:"amount"`       // Decimal string, e.g. "91666.67"
+	Currency    string `json:"currency"`     // Must match the loan currency
+	PaymentDate string `json:"payment_date"` // Format: YYYY-MM-DD
+}
+
+func (h *RepaymentHandler) RecordRepayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	var req RepaymentRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var v fieldValidator
+	amount := v.positiveMoney("amount", req.Amount, "currency", req.Currency)
+	paymentDate := v.date("payment_date", req.PaymentDate)
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	receipt, err := h.loanService.RecordRepayment(r.Context(), loanID, amount, paymentDate)
+
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusCreated,
+		"Repayment recorded successfully",
+		receipt,
+	)
+
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (h *RepaymentHandler) GetRepayments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	status, err := h.loanService.GetRepaymentStatus(r.Context(), loanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Repayments retrieved successfully",
+		status,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ScheduleHandler struct {
+	loanService *service.LoanService
+}
+
+func NewScheduleHandler(loanService *service.LoanService) *ScheduleHandler {
+	return &ScheduleHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	loanID := vars["id"]
+
+	schedule, err := h.loanService.GetRepaymentSchedule(r.Context(), loanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Repayment schedule retrieved successfully",
+		schedule,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"loan/internal/domain"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodyBytes limits the size of a request body
+const maxBodyBytes = 64 << 10
+
+// dateLayout is the format of every date in a request body
+const dateLayout = "2006-01-02"
+
+// decodeBody reads a request body holding a single JSON object into v.
+// Bodies over maxBodyBytes are refused with 413. Unknown fields, values of
+// the wrong JSON type and anything after the object are refused with 400.
+// On failure it answers the request and returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil {
+		if _, trailing := decoder.Token(); trailing != io.EOF {
+			err = errors.New("unexpected data after the JSON object")
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		message := fmt.Sprintf("Request body exceeds %d bytes", maxBodyBytes)
+		writeJSON(w, http.StatusRequestEntityTooLarge, domain.NewErrorResponse(http.StatusRequestEntityTooLarge, message))
+		return false
+	}
+
+	response := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body")
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		response.Message = "Request body is required"
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		response.Errors = []domain.FieldError{{
+			Field:   typeErr.Field,
+			Code:    domain.FieldInvalidType,
+			Message: "must be a JSON " + jsonType(typeErr.Type),
+		}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		response.Errors = []domain.FieldError{{Field: field, Code: domain.FieldUnknown, Message: "is not a known field"}}
+	default:
+		response.Message = "Invalid request body: " + err.Error()
+	}
+	writeJSON(w, http.StatusBadRequest, response)
+	return false
+}
+
+// jsonType names the JSON type that decodes into t
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// fieldValidator collects every invalid field of a decoded request body, so
+// that they are reported together
+type fieldValidator struct {
+	errs domain.FieldErrors
+}
+
+func (v *fieldValidator) add(field, code, message string) {
+	v.errs = append(v.errs, domain.FieldError{Field: field, Code: code, Message: message})
+}
+
+// required reports whether value is set, recording an error when it is not
+func (v *fieldValidator) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, domain.FieldRequired, "is required")
+		return false
+	}
+	return true
+}
+
+// date parses a required YYYY-MM-DD date
+func (v *fieldValidator) date(field, value string) time.Time {
+	if !v.required(field, value) {
+		return time.Time{}
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		v.add(field, domain.FieldInvalidFormat, "must be a date in YYYY-MM-DD format")
+	}
+	return date
+}
+
+// positiveMoney parses a required amount greater than zero in a required,
+// supported currency
+func (v *fieldValidator) positiveMoney(amountField, amount, currencyField, currency string) domain.Money {
+	amountOK := v.required(amountField, amount)
+	currencyOK := v.required(currencyField, currency)
+	if currencyOK && !domain.IsSupportedCurrency(currency) {
+		v.add(currencyField, domain.FieldUnsupported, fmt.Sprintf("currency %q is not supported", currency))
+		currencyOK = false
+	}
+
+	if !amountOK || !currencyOK {
+		return domain.Money{}
+	}
+
+	money, err := domain.ParseMoney(amount, currency)
+	if err != nil {
+		v.add(amountField, domain.FieldInvalidFormat, err.Error())
+		return domain.Money{}
+	}
+	if !money.IsPositive() {
+		v.add(amountField, domain.FieldOutOfRange, "must be greater than zero")
+	}
+	return money
+}
+
+// httpURL checks a required absolute http or https URL
+func (v *fieldValidator) httpURL(field, value string) {
+	if !v.required(field, value) {
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		v.add(field, domain.FieldInvalidFormat, "must be an absolute http or https URL")
+	}
+}
+
+func (v *fieldValidator) nonNegative(field string, value float64) {
+	if value < 0 {
+		v.add(field, domain.FieldOutOfRange, "cannot be negative")
+	}
+}
+
+// queryTime parses an optional RFC 3339 time from a query parameter,
+// returning the zero time when it is absent
+func (v *fieldValidator) queryTime(field, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.add(field, domain.FieldInvalidFormat, "must be an RFC 3339 time, e.g. 2024-01-31T00:00:00Z")
+	}
+	return parsed
+}
+
+// queryNumber parses an optional number from a query parameter, returning
+// nil when it is absent
+func (v *fieldValidator) queryNumber(field, value string) *float64 {
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		v.add(field, domain.FieldInvalidFormat, "must be a finite number")
+		return nil
+	}
+	return &number
+}
+
+// queryMoney parses an optional amount from a query parameter, in the
+// currency given by another, returning nil when the amount is absent
+func (v *fieldValidator) queryMoney(amountField, amount, currencyField, currency string) *domain.Money {
+	if amount == "" {
+		return nil
+	}
+	if currency == "" {
+		v.add(currencyField, domain.FieldRequired, "is required with "+amountField)
+		return nil
+	}
+	if !domain.IsSupportedCurrency(currency) {
+		v.add(currencyField, domain.FieldUnsupported, fmt.Sprintf("currency %q is not supported", currency))
+		return nil
+	}
+
+	money, err := domain.ParseMoney(amount, currency)
+	if err != nil {
+		v.add(amountField, domain.FieldInvalidFormat, err.Error())
+		return nil
+	}
+	return &money
+}
+
+// err returns the collected errors, or nil when every field is valid
+func (v *fieldValidator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"loan/internal/api/handlers"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func createLoan(t *testing.T, body string) (int, domain.Response) {
+	t.Helper()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	handler := handlers.NewLoanHandler(loanService)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/loans", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.CreateLoan(rec, req)
+
+	var resp domain.Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestCreateLoanReportsEveryInvalidField(t *testing.T) {
+	// Act
+	status, resp := createLoan(t, `{"borrower_id": " ", "principal_amount": "1.234", "currency": "IDR", "rate": -0.1, "roi": -1}`)
+
+	// Assert
+	if status != http.StatusUnprocessableEntity || resp.ErrorCode != domain.CodeValidationFailed {
+		t.Fatalf("Expected 422 VALIDATION_FAILED, got %d %s", status, resp.ErrorCode)
+	}
+
+	want := []domain.FieldError{
+		{Field: "borrower_id", Code: domain.FieldRequired},
+		{Field: "principal_amount", Code: domain.FieldInvalidFormat},
+		{Field: "rate", Code: domain.FieldOutOfRange},
+		{Field: "roi", Code: domain.FieldOutOfRange},
+	}
+	if len(resp.Errors) != len(want) {
+		t.Fatalf("Expected %d field errors, got %+v", len(want), resp.Errors)
+	}
+	for i, fieldErr := range resp.Errors {
+		if fieldErr.Field != want[i].Field || fieldErr.Code != want[i].Code || fieldErr.Message == "" {
+			t.Errorf("Expected %s %s, got %+v", want[i].Field, want[i].Code, fieldErr)
+		}
+	}
+}
+
+func TestCreateLoanReportsMissingAndUnsupportedMoneyFields(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		want []domain.FieldError
+	}{
+		"missing amount and currency": {
+			`{"borrower_id": "b1"}`,
+			[]domain.FieldError{{Field: "principal_amount", Code: domain.FieldRequired}, {Field: "currency", Code: domain.FieldRequired}},
+		},
+		"unsupported currency": {
+			`{"borrower_id": "b1", "principal_amount": "100", "currency": "XXX"}`,
+			[]domain.FieldError{{Field: "currency", Code: domain.FieldUnsupported}},
+		},
+		"zero amount": {
+			`{"borrower_id": "b1", "principal_amount": "0", "currency": "IDR"}`,
+			[]domain.FieldError{{Field: "principal_amount", Code: domain.FieldOutOfRange}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			status, resp := createLoan(t, tt.body)
+
+			// Assert
+			if status != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected status 422, got %d", status)
+			}
+			if len(resp.Errors) != len(tt.want) {
+				t.Fatalf("Expected %d field errors, got %+v", len(tt.want), resp.Errors)
+			}
+			for i, fieldErr := range resp.Errors {
+				if fieldErr.Field != tt.want[i].Field || fieldErr.Code != tt.want[i].Code {
+					t.Errorf("Expected %s %s, got %+v", tt.want[i].Field, tt.want[i].Code, fieldErr)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBodyRejectsMalformedBodies(t *testing.T) {
+	valid := `{"borrower_id": "b1", "principal_amount": "100", "currency": "IDR", "rate": 0.1, "roi": 0.08}`
+
+	tests := map[string]struct {
+		body       string
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		"valid":          {valid, http.StatusCreated, "", ""},
+		"empty":          {"", http.StatusBadRequest, domain.CodeBadRequest, ""},
+		"not JSON":       {"borrower_id=b1", http.StatusBadRequest, domain.CodeBadRequest, ""},
+		"unknown field":  {`{"borrower_id": "b1", "principal": "100"}`, http.StatusBadRequest, domain.CodeBadRequest, "principal"},
+		"wrong type":     {`{"borrower_id": "b1", "rate": "0.1"}`, http.StatusBadRequest, domain.CodeBadRequest, "rate"},
+		"trailing data":  {valid + `{}`, http.StatusBadRequest, domain.CodeBadRequest, ""},
+		"trailing space": {valid + "\n", http.StatusCreated, "", ""},
+		"too large":      {`{"borrower_id": "` + strings.Repeat("b", 1<<20) + `"}`, http.StatusRequestEntityTooLarge, domain.CodePayloadTooLarge, ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			status, resp := createLoan(t, tt.body)
+
+			// Assert
+			if status != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d (%s)", tt.wantStatus, status, resp.Message)
+			}
+			if resp.ErrorCode != tt.wantCode {
+				t.Errorf("Expected error code %q, got %q", tt.wantCode, resp.ErrorCode)
+			}
+			if tt.wantField != "" && (len(resp.Errors) != 1 || resp.Errors[0].Field != tt.wantField) {
+				t.Errorf("Expected an error for field %s, got %+v", tt.wantField, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestListLoansReportsInvalidQueryParameters(t *testing.T) {
+	tests := map[string]struct {
+		query      string
+		wantFields []string
+	}{
+		"unparsable filters": {
+			"state=OPEN&created_from=yesterday&min_funded_percent=half&min_principal=1.234&currency=IDR",
+			[]string{"state", "min_principal", "created_from", "min_funded_percent"},
+		},
+		"bound without a currency": {"max_principal=100", []string{"currency"}},
+		"non-finite percentages":   {"min_funded_percent=NaN&max_funded_percent=Inf", []string{"min_funded_percent", "max_funded_percent"}},
+		"bounds out of order":      {"min_funded_percent=60&max_funded_percent=40", nil},
+		"unknown sort":             {"sort=rate", nil},
+		"bad cursor":               {"cursor=nonsense", nil},
+		"page and cursor":          {"page=2&cursor=nonsense", nil},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+			handler := handlers.NewLoanHandler(loanService)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/loans?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ListLoans(rec, req)
+
+			// Assert
+			var resp domain.Response
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if rec.Code != http.StatusUnprocessableEntity || resp.ErrorCode != domain.CodeValidationFailed {
+				t.Fatalf("Expected 422 VALIDATION_FAILED, got %d %s (%s)", rec.Code, resp.ErrorCode, resp.Message)
+			}
+			if len(resp.Errors) != len(tt.wantFields) {
+				t.Fatalf("Expected errors for %v, got %+v", tt.wantFields, resp.Errors)
+			}
+			for i, fieldErr := range resp.Errors {
+				if fieldErr.Field != tt.wantFields[i] {
+					t.Errorf("Expected an error for %s, got %+v", tt.wantFields[i], fieldErr)
+				}
+			}
+		})
+	}
+}
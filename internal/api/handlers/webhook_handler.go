@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// WebhookHandler manages webhook subscriptions and their delivery logs
+type WebhookHandler struct {
+	loanService *service.LoanService
+}
+
+func NewWebhookHandler(loanService *service.LoanService) *WebhookHandler {
+	return &WebhookHandler{
+		loanService: loanService,
+	}
+}
+
+type WebhookSubscriptionRequest struct {
+	URL    string                    `json:"url"`
+	Events []domain.WebhookEventType `json:"events"` // Omit to subscribe to every event
+}
+
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req WebhookSubscriptionRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var v fieldValidator
+	v.httpURL("url", req.URL)
+	for i, event := range req.Events {
+		if _, err := domain.ParseWebhookEventType(string(event)); err != nil {
+			v.add(fmt.Sprintf("events[%d]", i), domain.FieldUnsupported, err.Error())
+		}
+	}
+	if err := v.err(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	subscription, err := h.loanService.CreateWebhookSubscription(r.Context(), req.URL, req.Events)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusCreated,
+		"Webhook subscription created successfully. Store the secret, it will not be shown again",
+		subscription,
+	)
+
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.loanService.ListWebhookSubscriptions(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Webhook subscriptions retrieved successfully",
+		subscriptions,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	subscription, err := h.loanService.GetWebhookSubscription(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Webhook subscription retrieved successfully",
+		subscription,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	subscription, err := h.loanService.DeleteWebhookSubscription(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Webhook subscription deleted successfully",
+		subscription,
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	query := r.URL.Query()
+
+	page := 1
+	pageSize := 10
+
+	if p := query.Get("page"); p != "" {
+		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if ps := query.Get("page_size"); ps != "" {
+		if parsedPageSize, err := strconv.Atoi(ps); err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	deliveries, total, err := h.loanService.ListWebhookDeliveries(r.Context(), id, page, pageSize)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := domain.NewSuccessResponse(
+		http.StatusOK,
+		"Webhook deliveries retrieved successfully",
+		domain.NewPaginatedResponse(deliveries, total, page, pageSize),
+	)
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,313 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AnonymousActor is recorded for requests when authentication is disabled
+const AnonymousActor = "anonymous"
+
+// JWTConfig configures the keys and claims that bearer tokens are checked
+// against. At least one of HMACSecret and RSAKeys must be set.
+type JWTConfig struct {
+	HMACSecret []byte                    // verifies HS256 tokens
+	RSAKeys    map[string]*rsa.PublicKey // verifies RS256 tokens, by key ID
+	Issuer     string                    // required "iss" when set
+	Audience   string                    // required in "aud" when set
+	Leeway     time.Duration             // clock skew allowed on "exp" and "nbf"
+}
+
+// JWTAuthenticator validates JWT bearer tokens
+type JWTAuthenticator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if len(config.HMACSecret) == 0 && len(config.RSAKeys) == 0 {
+		return nil, errors.New("JWT authentication needs an HS256 secret or RS256 keys")
+	}
+
+	return &JWTAuthenticator{config: config, now: time.Now}, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string        `json:"sub"`
+	Issuer    string        `json:"iss"`
+	Audience  jwtAudience   `json:"aud"`
+	ExpiresAt *float64      `json:"exp"`
+	NotBefore *float64      `json:"nbf"`
+	Roles     []domain.Role `json:"roles"`
+}
+
+// jwtAudience accepts "aud" as a single string or an array of strings
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+func (a jwtAudience) contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks a compact JWT's signature and claims and returns the caller
+// it names. Only HS256 and RS256 are accepted, each with its own keys, so a
+// token cannot choose "none" or sign with the RSA public key as an HMAC
+// secret. Tokens must carry "sub" and "exp".
+func (a *JWTAuthenticator) Verify(token string) (*domain.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a compact JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid token signature encoding")
+	}
+
+	if err := a.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	if err := a.checkClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &domain.Principal{Subject: claims.Subject, Roles: claims.Roles}, nil
+}
+
+func (a *JWTAuthenticator) verifySignature(header jwtHeader, signingInput string, signature []byte) error {
+	switch header.Algorithm {
+	case "HS256":
+		if len(a.config.HMACSecret) == 0 {
+			return errors.New("HS256 tokens are not accepted")
+		}
+
+		mac := hmac.New(sha256.New, a.config.HMACSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case "RS256":
+		key, err := a.rsaKey(header.KeyID)
+		if err != nil {
+			return err
+		}
+
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", header.Algorithm)
+	}
+}
+
+// rsaKey finds the key named by kid, or the only key when the token names
+// none
+func (a *JWTAuthenticator) rsaKey(kid string) (*rsa.PublicKey, error) {
+	if len(a.config.RSAKeys) == 0 {
+		return nil, errors.New("RS256 tokens are not accepted")
+	}
+
+	if kid == "" {
+		if len(a.config.RSAKeys) == 1 {
+			for _, key := range a.config.RSAKeys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token does not name its signing key")
+	}
+
+	key, exists := a.config.RSAKeys[kid]
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (a *JWTAuthenticator) checkClaims(claims *jwtClaims) error {
+	now := a.now()
+
+	if claims.Subject == "" {
+		return errors.New("token has no subject")
+	}
+
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if !now.Before(numericDate(*claims.ExpiresAt).Add(a.config.Leeway)) {
+		return errors.New("token has expired")
+	}
+
+	if claims.NotBefore != nil && now.Add(a.config.Leeway).Before(numericDate(*claims.NotBefore)) {
+		return errors.New("token is not valid yet")
+	}
+
+	if a.config.Issuer != "" && claims.Issuer != a.config.Issuer {
+		return errors.New("token has the wrong issuer")
+	}
+
+	if a.config.Audience != "" && !claims.Audience.contains(a.config.Audience) {
+		return errors.New("token is not intended for this service")
+	}
+
+	return nil
+}
+
+// Authenticate requires a valid "Authorization: Bearer" token on every
+// request, recording its principal in the request context. Other requests
+// are answered with 401.
+func (a *JWTAuthenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized(w, "Bearer", "Missing bearer token")
+			return
+		}
+
+		principal, err := a.Verify(token)
+		if err != nil {
+			unauthorized(w, `Bearer error="invalid_token"`, "Invalid bearer token: "+err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, withPrincipal(r, principal))
+	})
+}
+
+// Anonymous records every request as made by AnonymousActor, as an admin.
+// It stands in for Authenticate when authentication is disabled.
+func Anonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := &domain.Principal{Subject: AnonymousActor, Roles: []domain.Role{domain.RoleAdmin}}
+		next.ServeHTTP(w, withPrincipal(r, principal))
+	})
+}
+
+// withPrincipal records the caller in the request context, and in the
+// request's logger
+func withPrincipal(r *http.Request, principal *domain.Principal) *http.Request {
+	ctx := domain.ContextWithPrincipal(r.Context(), principal)
+	ctx = domain.ContextWithLogger(ctx, domain.LoggerFromContext(ctx).With("actor", principal.Subject))
+	return r.WithContext(ctx)
+}
+
+func unauthorized(w http.ResponseWriter, challenge, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(domain.NewErrorResponse(http.StatusUnauthorized, message))
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// LoadJWKS reads the RSA signing keys from a JWKS file, keyed by "kid".
+// Keys that are not RSA signature keys are skipped.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS: %w", err)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			KeyType   string `json:"kty"`
+			KeyID     string `json:"kid"`
+			Use       string `json:"use"`
+			Algorithm string `json:"alg"`
+			N         string `json:"n"`
+			E         string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") || (jwk.Algorithm != "" && jwk.Algorithm != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: invalid modulus", jwk.KeyID)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("JWKS key %q: invalid exponent", jwk.KeyID)
+		}
+
+		if _, exists := keys[jwk.KeyID]; exists {
+			return nil, fmt.Errorf("JWKS key %q appears more than once", jwk.KeyID)
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no RS256 signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,246 @@
+package middleware_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"loan/internal/api/middleware"
+	"loan/internal/domain"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var hmacSecret = []byte("test-secret")
+
+func segment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode token segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hs256Token(t *testing.T, header, claims map[string]interface{}) string {
+	input := segment(t, header) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, hmacSecret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	input := segment(t, map[string]interface{}{"alg": "RS256", "kid": kid}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "user-1",
+		"iss":   "https://auth.example.com",
+		"aud":   []string{"loan-api"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"investor"},
+	}
+}
+
+// writeJWKS publishes key's public half under kid in a temporary JWKS file
+func writeJWKS(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ignored", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := json.Marshal(jwks)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestJWTAuthenticatorVerify(t *testing.T) {
+	// Arrange
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	keys, err := middleware.LoadJWKS(writeJWKS(t, "key-1", rsaKey))
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("Expected only the RSA key to be loaded, got %d keys", len(keys))
+	}
+
+	authenticator, err := middleware.NewJWTAuthenticator(middleware.JWTConfig{
+		HMACSecret: hmacSecret,
+		RSAKeys:    keys,
+		Issuer:     "https://auth.example.com",
+		Audience:   "loan-api",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	with := func(changes map[string]interface{}) map[string]interface{} {
+		claims := validClaims()
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	hs256Valid := hs256Token(t, hs256, validClaims())
+	parts := strings.Split(hs256Valid, ".")
+	tampered := parts[0] + "." + segment(t, with(map[string]interface{}{"sub": "admin"})) + "." + parts[2]
+	unsigned := segment(t, map[string]interface{}{"alg": "none"}) + "." + segment(t, validClaims()) + "."
+
+	// The RSA public key used as an HMAC secret must not verify
+	publicKeyAsSecret := segment(t, map[string]interface{}{"alg": "HS256"}) + "." + segment(t, validClaims())
+	mac := hmac.New(sha256.New, rsaKey.N.Bytes())
+	mac.Write([]byte(publicKeyAsSecret))
+	publicKeyAsSecret += "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := map[string]struct {
+		token   string
+		wantErr string
+	}{
+		"HS256":                 {hs256Valid, ""},
+		"RS256":                 {rs256Token(t, rsaKey, "key-1", validClaims()), ""},
+		"RS256 without kid":     {rs256Token(t, rsaKey, "", validClaims()), ""},
+		"audience as a string":  {hs256Token(t, hs256, with(map[string]interface{}{"aud": "loan-api"})), ""},
+		"tampered claims":       {tampered, "invalid token signature"},
+		"wrong HMAC secret":     {publicKeyAsSecret, "invalid token signature"},
+		"unknown RSA key":       {rs256Token(t, otherKey, "key-1", validClaims()), "invalid token signature"},
+		"unknown kid":           {rs256Token(t, rsaKey, "key-2", validClaims()), "unknown signing key"},
+		"alg none":              {unsigned, "unsupported token algorithm"},
+		"expired":               {hs256Token(t, hs256, with(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})), "expired"},
+		"no expiry":             {hs256Token(t, hs256, with(map[string]interface{}{"exp": nil})), "no expiry"},
+		"not valid yet":         {hs256Token(t, hs256, with(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})), "not valid yet"},
+		"no subject":            {hs256Token(t, hs256, with(map[string]interface{}{"sub": nil})), "no subject"},
+		"wrong issuer":          {hs256Token(t, hs256, with(map[string]interface{}{"iss": "https://evil.example.com"})), "wrong issuer"},
+		"wrong audience":        {hs256Token(t, hs256, with(map[string]interface{}{"aud": []string{"other-api"}})), "not intended"},
+		"not a JWT":             {"not-a-token", "not a compact JWT"},
+		"invalid header base64": {"!!!." + segment(t, validClaims()) + ".sig", "invalid token header"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			principal, err := authenticator.Verify(tt.token)
+
+			// Assert
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected the token to verify, got %v", err)
+				}
+				if principal.Subject != "user-1" || len(principal.Roles) != 1 || principal.Roles[0] != "investor" {
+					t.Errorf("Expected principal user-1 with role investor, got %+v", principal)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateMiddleware(t *testing.T) {
+	// Arrange
+	authenticator, err := middleware.NewJWTAuthenticator(middleware.JWTConfig{HMACSecret: hmacSecret})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	var seen *domain.Principal
+	handler := authenticator.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = domain.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]struct {
+		authorization string
+		wantStatus    int
+	}{
+		"valid token":      {"Bearer " + hs256Token(t, map[string]interface{}{"alg": "HS256"}, validClaims()), http.StatusOK},
+		"lowercase scheme": {"bearer " + hs256Token(t, map[string]interface{}{"alg": "HS256"}, validClaims()), http.StatusOK},
+		"missing header":   {"", http.StatusUnauthorized},
+		"basic auth":       {"Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		"invalid token":    {"Bearer not-a-token", http.StatusUnauthorized},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/loans", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if seen == nil || seen.Subject != "user-1" {
+					t.Errorf("Expected principal user-1 in the request context, got %+v", seen)
+				}
+				return
+			}
+
+			if seen != nil {
+				t.Error("Expected the handler not to be called")
+			}
+			if rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+
+			var resp domain.Response
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Code != http.StatusUnauthorized || resp.ErrorCode != domain.CodeUnauthorized || resp.Message == "" {
+				t.Errorf("Expected a 401 error envelope, got %+v", resp)
+			}
+		})
+	}
+}
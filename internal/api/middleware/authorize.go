@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"encoding/json"
+	"loan/internal/domain"
+	"net/http"
+)
+
+// RequireRole allows a request through when its principal has one of roles
+// or is an admin, and answers it with 403 otherwise. It must run after
+// Authenticate.
+func RequireRole(roles ...domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := domain.PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "Bearer", "Missing bearer token")
+				return
+			}
+
+			if principal.HasRole(domain.RoleAdmin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(domain.NewErrorResponse(http.StatusForbidden, "You do not have a role allowed to perform this action"))
+		})
+	}
+}
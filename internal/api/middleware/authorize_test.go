@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"loan/internal/api/middleware"
+	"loan/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRole(t *testing.T) {
+	// Arrange
+	handler := middleware.RequireRole(domain.RoleFieldValidator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]struct {
+		principal  *domain.Principal
+		wantStatus int
+	}{
+		"allowed role":      {&domain.Principal{Subject: "v1", Roles: []domain.Role{domain.RoleFieldValidator}}, http.StatusOK},
+		"admin":             {&domain.Principal{Subject: "a1", Roles: []domain.Role{domain.RoleAdmin}}, http.StatusOK},
+		"other role":        {&domain.Principal{Subject: "i1", Roles: []domain.Role{domain.RoleInvestor}}, http.StatusForbidden},
+		"no roles":          {&domain.Principal{Subject: "u1"}, http.StatusForbidden},
+		"not authenticated": {nil, http.StatusUnauthorized},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/loans/1/approve", nil)
+			if tt.principal != nil {
+				req = req.WithContext(domain.ContextWithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"loan/internal/domain"
+	"net/http"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" on replayed responses
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength limits the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodyBytes limits the bodies read for hashing. Handlers apply
+// their own, smaller, limits.
+const maxIdempotentBodyBytes = 1 << 20
+
+// IdempotencyStore keeps the responses to requests made with an idempotency
+// key
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) error
+}
+
+// Idempotency makes POST requests that carry an Idempotency-Key header safe
+// to retry. The first request with a key runs and its response is stored
+// for the caller and key; later requests with the same key get that response
+// again without running. Reusing a key for a different method, path or body
+// is answered with 422, and retrying while the first request is still
+// running with 409. Responses with a 5xx status are not stored, so the
+// request can be retried. It must run after Authenticate.
+func Idempotency(store IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				respond(w, domain.NewErrorResponse(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters"))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				respond(w, domain.NewErrorResponse(http.StatusRequestEntityTooLarge, "Request body is too large"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := domain.NewIdempotencyRecord(domain.ActorFromContext(r.Context()), key, r.Method, r.URL.Path, body)
+			existing, err := store.ReserveIdempotencyKey(r.Context(), record)
+			if err != nil {
+				domain.LoggerFromContext(r.Context()).Error("reserve idempotency key", "error", err)
+				respond(w, domain.NewErrorResponse(http.StatusInternalServerError, "Internal server error"))
+				return
+			}
+
+			if existing != nil {
+				replay(w, existing, record)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			// The outcome is stored even if the caller has gone away, so
+			// that its retry is answered from the store
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.ReleaseIdempotencyKey(ctx, record); err != nil {
+						domain.LoggerFromContext(ctx).Error("release idempotency key", "error", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status() >= http.StatusInternalServerError {
+				return
+			}
+			record.Complete(recorder.status(), recorder.body.Bytes())
+			if err := store.CompleteIdempotencyKey(ctx, record); err != nil {
+				domain.LoggerFromContext(ctx).Error("complete idempotency key", "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay answers a repeated request from the record of the first one
+func replay(w http.ResponseWriter, existing, record *domain.IdempotencyRecord) {
+	switch {
+	case !existing.Matches(record):
+		response := domain.NewErrorResponse(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+		response.ErrorCode = domain.CodeIdempotencyKeyReused
+		respond(w, response)
+	case !existing.Completed():
+		respond(w, domain.NewErrorResponse(http.StatusConflict, "A request with this Idempotency-Key is still in progress"))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
+
+func respond(w http.ResponseWriter, response *domain.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.Code)
+	json.NewEncoder(w).Encode(response)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) status() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"loan/internal/api/middleware"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdempotency(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	calls := 0
+	status := http.StatusCreated
+	handler := middleware.Idempotency(loanService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d,"body":%q}`, calls, body)
+	}))
+
+	send := func(subject, method, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/loans/loan1/investments", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(domain.ContextWithPrincipal(req.Context(), &domain.Principal{Subject: subject}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	errorCode := func(rec *httptest.ResponseRecorder) string {
+		var resp domain.Response
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp.ErrorCode
+	}
+
+	// Act
+	first := send("alice", http.MethodPost, "key-1", `{"amount":"10"}`)
+	replayed := send("alice", http.MethodPost, "key-1", `{"amount":"10"}`)
+
+	// Assert
+	if calls != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", calls)
+	}
+	if replayed.Code != first.Code || replayed.Body.String() != first.Body.String() {
+		t.Errorf("Expected the identical response %d %s, got %d %s", first.Code, first.Body, replayed.Code, replayed.Body)
+	}
+	if replayed.Header().Get(middleware.IdempotentReplayedHeader) != "true" || first.Header().Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Error("Expected only the replay to be marked as replayed")
+	}
+
+	if rec := send("alice", http.MethodPost, "key-1", `{"amount":"20"}`); rec.Code != http.StatusUnprocessableEntity || errorCode(rec) != domain.CodeIdempotencyKeyReused {
+		t.Errorf("Expected 422 for a different body, got %d", rec.Code)
+	}
+
+	if send("bob", http.MethodPost, "key-1", `{"amount":"10"}`); calls != 2 {
+		t.Errorf("Expected another caller's key to run the request, ran %d times", calls)
+	}
+
+	send("alice", http.MethodPost, "", `{"amount":"10"}`)
+	send("alice", http.MethodPost, "", `{"amount":"10"}`)
+	if calls != 4 {
+		t.Errorf("Expected requests without a key to always run, ran %d times", calls)
+	}
+
+	status = http.StatusServiceUnavailable
+	send("alice", http.MethodPost, "key-2", `{}`)
+	status = http.StatusCreated
+	if rec := send("alice", http.MethodPost, "key-2", `{}`); rec.Code != http.StatusCreated || calls != 6 {
+		t.Errorf("Expected a request that failed with 5xx to run again, got %d after %d calls", rec.Code, calls)
+	}
+
+	inProgress := domain.NewIdempotencyRecord("alice", "key-3", http.MethodPost, "/api/v1/loans/loan1/investments", []byte(`{}`))
+	loanService.ReserveIdempotencyKey(context.Background(), inProgress)
+	if rec := send("alice", http.MethodPost, "key-3", `{}`); rec.Code != http.StatusConflict || errorCode(rec) != domain.CodeConflict {
+		t.Errorf("Expected 409 while the first request is in progress, got %d", rec.Code)
+	}
+
+	if rec := send("alice", http.MethodPost, strings.Repeat("k", 256), `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an overlong key, got %d", rec.Code)
+	}
+}
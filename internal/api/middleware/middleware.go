@@ -2,29 +2,74 @@ package middleware
 
 import (
 	"encoding/json"
+	"fmt"
 	"loan/internal/domain"
-	"log"
+	"loan/util"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"time"
 )
 
-// Logger is a middleware that logs incoming requests
+const RequestIDHeader = "X-Request-ID"
+
+// Logger logs every request once it has been answered, with its status,
+// response size and duration. The layers below log through the logger it
+// records in the request context, which is tagged with the request ID, so
+// it must run after RequestID.
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		logger := slog.Default().With("request_id", domain.RequestIDFromContext(r.Context()))
+		writer := &loggingResponseWriter{ResponseWriter: w}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(writer, r.WithContext(domain.ContextWithLogger(r.Context(), logger)))
 
-		// Log the request
-		log.Printf(
-			"%s %s %s",
-			r.Method,
-			r.RequestURI,
-			time.Since(start),
+		level := slog.LevelInfo
+		if writer.status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(r.Context(), level, "request completed",
+			"method", r.Method,
+			"path", r.URL.RequestURI(),
+			"status", writer.status(),
+			"bytes", writer.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
 		)
 	})
 }
 
+// loggingResponseWriter notes the status and size of a response
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int
+}
+
+func (w *loggingResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *loggingResponseWriter) status() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+	return w.statusCode
+}
+
 // ContentTypeJSON sets the Content-Type header to application/json
 func ContentTypeJSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +83,7 @@ func ErrorHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic: %v", err)
+				domain.LoggerFromContext(r.Context()).Error("panic", "error", fmt.Sprint(err), "stack", string(debug.Stack()))
 
 				resp := domain.NewErrorResponse(http.StatusInternalServerError, "Internal server error")
 
@@ -51,3 +96,37 @@ func ErrorHandler(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequestID tags each request with the caller's X-Request-ID, or a new one,
+// echoing it in the response and recording it in the request context.
+// Request IDs that are too long or hold characters other than letters,
+// digits and "-_.:" are replaced, as they end up in logs and the audit
+// trail.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = "req_" + util.GenerateUUID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// maxRequestIDLength limits the X-Request-ID header
+const maxRequestIDLength = 128
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', strings.ContainsRune("-_.:", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
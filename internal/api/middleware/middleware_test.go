@@ -0,0 +1,106 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"loan/internal/api/middleware"
+	"loan/internal/domain"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		incoming string
+		kept     bool
+	}{
+		"missing":             {"", false},
+		"valid":               {"client-42.retry:1", true},
+		"with spaces":         {"client 42", false},
+		"with a line break":   {"client\n42", false},
+		"too long":            {strings.Repeat("a", 129), false},
+		"at the length limit": {strings.Repeat("a", 128), true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var seen string
+			handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = domain.RequestIDFromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(middleware.RequestIDHeader, tt.incoming)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			echoed := rec.Header().Get(middleware.RequestIDHeader)
+			if echoed == "" || echoed != seen {
+				t.Fatalf("Expected the request ID in the context to be echoed, got %q and %q", seen, echoed)
+			}
+			if kept := echoed == tt.incoming; kept != tt.kept {
+				t.Errorf("Expected the incoming ID to be kept: %v, got %q", tt.kept, echoed)
+			}
+		})
+	}
+}
+
+func TestLoggerCorrelatesRequestLogs(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&out, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	handler := middleware.RequestID(middleware.Logger(middleware.Anonymous(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		domain.LoggerFromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/loans?x=1", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	var lines []map[string]interface{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var line map[string]interface{}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %s", len(lines), out.String())
+	}
+
+	handling, completed := lines[0], lines[1]
+	if handling["msg"] != "handling" || handling["request_id"] != "req-1" || handling["actor"] != middleware.AnonymousActor {
+		t.Errorf("Expected the handler's log to carry the request ID and actor, got %v", handling)
+	}
+	want := map[string]interface{}{
+		"msg":        "request completed",
+		"request_id": "req-1",
+		"method":     "POST",
+		"path":       "/api/v1/loans?x=1",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(len("hello")),
+	}
+	for key, value := range want {
+		if completed[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, completed[key])
+		}
+	}
+	if _, ok := completed["duration_ms"].(float64); !ok {
+		t.Errorf("Expected a duration, got %v", completed["duration_ms"])
+	}
+}
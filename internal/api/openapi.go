@@ -0,0 +1,631 @@
+package api
+
+import (
+	"encoding/json"
+	"loan/internal/domain"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPIPath is where the OpenAPI document of the API is served. Unlike the
+// other routes, it needs no authentication.
+const OpenAPIPath = "/api/v1/openapi.json"
+
+// schema is a JSON Schema as understood by OpenAPI 3.0
+type schema map[string]interface{}
+
+// operation documents a route of SetupRouter. Every route must have one,
+// which the router tests check.
+type operation struct {
+	method  string
+	path    string // relative to /api/v1, in mux's template syntax
+	id      string
+	tag     string
+	summary string
+	query   []schema // query parameters
+	body    string   // name of the request body schema, if any
+	status  int      // of the success response
+	data    schema   // of the success response, wrapped in the response envelope
+
+	public  bool   // served without authentication
+	raw     schema // of a success response that is not wrapped in the envelope
+	content string // media type of a raw success response, if not JSON
+}
+
+var operations = []operation{
+	{method: "GET", path: "/openapi.json", id: "getOpenAPI", tag: "Meta", summary: "This document", public: true, raw: schema{"type": "object"}},
+
+	{method: "POST", path: "/loans", id: "createLoan", tag: "Loans", summary: "Propose a loan", body: "CreateLoanRequest", status: http.StatusCreated, data: ref("Loan")},
+	{method: "GET", path: "/loans/{id}", id: "getLoan", tag: "Loans", summary: "Get a loan", status: http.StatusOK, data: ref("Loan")},
+	{method: "GET", path: "/loans", id: "listLoans", tag: "Loans", summary: "List loans", query: loanListParameters, status: http.StatusOK, data: ref("LoanPage")},
+	{method: "POST", path: "/loans/{id}/approve", id: "approveLoan", tag: "Loans", summary: "Approve a proposed loan", body: "ApprovalRequest", status: http.StatusOK, data: ref("Loan")},
+	{method: "POST", path: "/loans/{id}/disburse", id: "disburseLoan", tag: "Loans", summary: "Disburse an invested loan", body: "DisbursementRequest", status: http.StatusOK, data: ref("Loan")},
+	{method: "POST", path: "/loans/{id}/reject", id: "rejectLoan", tag: "Loans", summary: "Reject a proposed loan", body: "RejectionRequest", status: http.StatusOK, data: ref("Loan")},
+	{method: "POST", path: "/loans/{id}/cancel", id: "cancelLoan", tag: "Loans", summary: "Cancel a loan before it is disbursed", body: "CancellationRequest", status: http.StatusOK, data: ref("Loan")},
+	{method: "GET", path: "/loans/{id}/agreement-letter", id: "getAgreementLetter", tag: "Loans", summary: "Download the agreement letter of an invested loan, as its borrower or one of its investors", status: http.StatusOK, raw: schema{"type": "string", "format": "binary"}, content: "application/pdf"},
+	{method: "GET", path: "/loans/{id}/events", id: "listLoanEvents", tag: "Loans", summary: "List the history of a loan", status: http.StatusOK, data: arrayOf(ref("LoanEvent"))},
+
+	{method: "POST", path: "/loans/{id}/investments", id: "addInvestment", tag: "Investments", summary: "Invest in an approved loan", body: "InvestmentRequest", status: http.StatusCreated, data: ref("Investment")},
+	{method: "GET", path: "/loans/{id}/investments", id: "listInvestments", tag: "Investments", summary: "List the investments in a loan", status: http.StatusOK, data: ref("InvestmentSummary")},
+
+	{method: "GET", path: "/loans/{id}/schedule", id: "getSchedule", tag: "Repayments", summary: "Get the repayment schedule of a disbursed loan", status: http.StatusOK, data: ref("RepaymentSchedule")},
+	{method: "POST", path: "/loans/{id}/repayments", id: "recordRepayment", tag: "Repayments", summary: "Record a repayment", body: "RepaymentRequest", status: http.StatusCreated, data: ref("RepaymentReceipt")},
+	{method: "GET", path: "/loans/{id}/repayments", id: "getRepaymentStatus", tag: "Repayments", summary: "Get the repayment status of a loan", status: http.StatusOK, data: ref("RepaymentStatus")},
+	{method: "GET", path: "/loans/{id}/payouts", id: "getPayouts", tag: "Repayments", summary: "Get the investor payouts of a loan", status: http.StatusOK, data: ref("PayoutLedger")},
+
+	{method: "POST", path: "/webhooks", id: "createWebhookSubscription", tag: "Webhooks", summary: "Subscribe to loan events", body: "WebhookSubscriptionRequest", status: http.StatusCreated, data: ref("WebhookSubscription")},
+	{method: "GET", path: "/webhooks", id: "listWebhookSubscriptions", tag: "Webhooks", summary: "List webhook subscriptions", status: http.StatusOK, data: arrayOf(ref("WebhookSubscription"))},
+	{method: "GET", path: "/webhooks/{id}", id: "getWebhookSubscription", tag: "Webhooks", summary: "Get a webhook subscription", status: http.StatusOK, data: ref("WebhookSubscription")},
+	{method: "DELETE", path: "/webhooks/{id}", id: "deleteWebhookSubscription", tag: "Webhooks", summary: "Delete a webhook subscription", status: http.StatusOK, data: ref("WebhookSubscription")},
+	{method: "GET", path: "/webhooks/{id}/deliveries", id: "listWebhookDeliveries", tag: "Webhooks", summary: "List the delivery attempts of a subscription", query: pageParameters, status: http.StatusOK, data: ref("WebhookDeliveryPage")},
+
+	{method: "GET", path: "/admin/outbox", id: "listOutboxMessages", tag: "Admin", summary: "List outbox messages", query: append([]schema{queryParameter("status", "Only messages with this status", ref("OutboxStatus"))}, pageParameters...), status: http.StatusOK, data: ref("OutboxMessagePage")},
+	{method: "POST", path: "/admin/outbox/{id}/replay", id: "replayOutboxMessage", tag: "Admin", summary: "Deliver a dead outbox message again", status: http.StatusOK, data: ref("OutboxMessage")},
+	{method: "GET", path: "/admin/audit", id: "listAuditEntries", tag: "Admin", summary: "List audit trail entries", query: auditListParameters, status: http.StatusOK, data: ref("AuditEntryPage")},
+	{method: "GET", path: "/admin/audit/verify", id: "verifyAuditTrail", tag: "Admin", summary: "Verify the audit trail hash chain", status: http.StatusOK, data: ref("AuditVerification")},
+}
+
+var pageParameters = []schema{
+	queryParameter("page", "Page number, from 1", schema{"type": "integer", "minimum": 1, "default": 1}),
+	queryParameter("page_size", "Items per page", schema{"type": "integer", "minimum": 1, "default": 10}),
+}
+
+var loanListParameters = append([]schema{
+	queryParameter("state", "Only loans in these states; may be repeated or comma-separated", str()),
+	queryParameter("borrower_id", "Only loans of this borrower", str()),
+	queryParameter("currency", "Currency of min_principal and max_principal", str()),
+	queryParameter("min_principal", "Smallest principal amount, inclusive", decimal()),
+	queryParameter("max_principal", "Largest principal amount, inclusive", decimal()),
+	queryParameter("created_from", "Earliest creation time, inclusive", dateTime()),
+	queryParameter("created_to", "Latest creation time, exclusive", dateTime()),
+	queryParameter("min_funded_percent", "Smallest funded percentage, inclusive", schema{"type": "number", "minimum": 0, "maximum": 100}),
+	queryParameter("max_funded_percent", "Largest funded percentage, inclusive", schema{"type": "number", "minimum": 0, "maximum": 100}),
+	queryParameter("sort", "Field to order by, prefixed with - for descending order", enum(
+		string(domain.LoanSortCreatedAt), "-"+string(domain.LoanSortCreatedAt),
+		string(domain.LoanSortPrincipalAmount), "-"+string(domain.LoanSortPrincipalAmount),
+		string(domain.LoanSortFundedPercent), "-"+string(domain.LoanSortFundedPercent),
+	)),
+	queryParameter("cursor", "next_cursor of the previous page; cannot be combined with page", str()),
+}, pageParameters...)
+
+var auditListParameters = append([]schema{
+	queryParameter("loan_id", "Only entries about this loan", str()),
+	queryParameter("actor", "Only entries made by this actor", str()),
+	queryParameter("from", "Earliest time, inclusive", dateTime()),
+	queryParameter("to", "Latest time, exclusive", dateTime()),
+}, pageParameters...)
+
+// schemas are the component schemas of the document. Objects list every
+// property a response may hold, so the router tests catch undocumented ones.
+var schemas = map[string]schema{
+	"Money": object(map[string]schema{
+		"amount":   decimal(),
+		"currency": schema{"type": "string", "example": "IDR"},
+	}, "amount", "currency"),
+	"Error": object(map[string]schema{
+		"code":       integer(),
+		"error_code": str(),
+		"message":    str(),
+		"errors":     arrayOf(ref("FieldError")),
+	}, "code", "error_code", "message"),
+	"FieldError": object(map[string]schema{
+		"field":   str(),
+		"code":    enum(domain.FieldRequired, domain.FieldInvalidFormat, domain.FieldInvalidType, domain.FieldUnsupported, domain.FieldOutOfRange, domain.FieldUnknown),
+		"message": str(),
+	}, "field", "code", "message"),
+
+	"LoanState":        enum(domain.LoanStateProposed, domain.LoanStateApproved, domain.LoanStateInvested, domain.LoanStateDisbursed, domain.LoanStateRejected, domain.LoanStateCancelled, domain.LoanStateRepaid, domain.LoanStateDefaulted),
+	"InvestmentStatus": enum(domain.InvestmentStatusActive, domain.InvestmentStatusRefunded),
+	"InstallmentState": enum(domain.InstallmentStatePending, domain.InstallmentStatePartial, domain.InstallmentStatePaid, domain.InstallmentStateOverdue),
+	"OutboxStatus":     enum(domain.OutboxStatusPending, domain.OutboxStatusDelivered, domain.OutboxStatusDead),
+	"WebhookEventType": enum(domain.WebhookLoanApproved, domain.WebhookInvestmentAdded, domain.WebhookLoanInvested, domain.WebhookLoanDisbursed),
+	"LoanEventType":    enum(domain.LoanEventCreated, domain.LoanEventApproved, domain.LoanEventInvestmentAdded, domain.LoanEventInvested, domain.LoanEventAgreementLetterAttached, domain.LoanEventDisbursed, domain.LoanEventRejected, domain.LoanEventCancelled, domain.LoanEventRepaid, domain.LoanEventDefaulted),
+	"AuditAction":      enum(domain.AuditCreateLoan, domain.AuditApproveLoan, domain.AuditAddInvestment, domain.AuditDisburseLoan, domain.AuditRecordRepayment, domain.AuditRejectLoan, domain.AuditCancelLoan, domain.AuditCreateWebhook, domain.AuditDeleteWebhook, domain.AuditReplayOutboxMessage),
+
+	"Loan": object(map[string]schema{
+		"id":                   str(),
+		"borrower_id":          str(),
+		"principal_amount":     ref("Money"),
+		"rate":                 number(),
+		"roi":                  number(),
+		"state":                ref("LoanState"),
+		"agreement_letter_url": str(),
+		"created_at":           dateTime(),
+		"updated_at":           dateTime(),
+		"version":              integer(),
+		"approval":             ref("Approval"),
+		"investments":          arrayOf(ref("Investment")),
+		"disbursement":         ref("Disbursement"),
+		"rejection":            ref("Rejection"),
+		"cancellation":         ref("Cancellation"),
+	}, "id", "borrower_id", "principal_amount", "rate", "roi", "state", "created_at", "updated_at", "version"),
+	"Approval": object(map[string]schema{
+		"loan_id":            str(),
+		"proof_picture_url":  str(),
+		"field_validator_id": str(),
+		"approval_date":      dateTime(),
+	}, "loan_id", "proof_picture_url", "field_validator_id", "approval_date"),
+	"Investment": object(map[string]schema{
+		"id":          str(),
+		"loan_id":     str(),
+		"investor_id": str(),
+		"amount":      ref("Money"),
+		"status":      ref("InvestmentStatus"),
+		"invested_at": dateTime(),
+		"refunded_at": dateTime(),
+	}, "id", "loan_id", "investor_id", "amount", "status", "invested_at"),
+	"Disbursement": object(map[string]schema{
+		"loan_id":                str(),
+		"agreement_document_url": str(),
+		"field_officer_id":       str(),
+		"disbursement_date":      dateTime(),
+	}, "loan_id", "agreement_document_url", "field_officer_id", "disbursement_date"),
+	"Rejection": object(map[string]schema{
+		"loan_id":     str(),
+		"reason":      str(),
+		"reviewer_id": str(),
+		"rejected_at": dateTime(),
+	}, "loan_id", "reason", "reviewer_id", "rejected_at"),
+	"Cancellation": object(map[string]schema{
+		"loan_id":      str(),
+		"reason":       str(),
+		"cancelled_by": str(),
+		"cancelled_at": dateTime(),
+	}, "loan_id", "reason", "cancelled_by", "cancelled_at"),
+	"CurrencyTotal": object(map[string]schema{
+		"currency":         str(),
+		"loan_count":       integer(),
+		"principal_amount": ref("Money"),
+		"total_invested":   ref("Money"),
+	}, "currency", "loan_count", "principal_amount", "total_invested"),
+	"LoanPage": page("Loan", map[string]schema{
+		"next_cursor": str(),
+		"totals":      schema{"type": "array", "items": ref("CurrencyTotal"), "description": "Totals per currency of every loan matching the filters, not only those on the page"},
+	}),
+	"InvestmentSummary": object(map[string]schema{
+		"investments":      arrayOf(ref("Investment")),
+		"currency":         str(),
+		"total_invested":   ref("Money"),
+		"principal_amount": ref("Money"),
+	}, "investments", "currency", "total_invested", "principal_amount"),
+
+	"LoanEvent": object(map[string]schema{
+		"id":          str(),
+		"loan_id":     str(),
+		"sequence":    integer(),
+		"type":        ref("LoanEventType"),
+		"data":        schema{"oneOf": []schema{ref("LoanCreated"), ref("LoanApproved"), ref("InvestmentAdded"), ref("LoanInvested"), ref("AgreementLetterAttached"), ref("LoanDisbursed"), ref("LoanRejected"), ref("LoanCancelled"), ref("LoanRepaid"), ref("LoanDefaulted")}},
+		"occurred_at": dateTime(),
+	}, "id", "loan_id", "sequence", "type", "data", "occurred_at"),
+	"LoanCreated": object(map[string]schema{
+		"borrower_id":      str(),
+		"principal_amount": ref("Money"),
+		"rate":             number(),
+		"roi":              number(),
+	}, "borrower_id", "principal_amount", "rate", "roi"),
+	"LoanApproved":            object(map[string]schema{"approval": ref("Approval")}, "approval"),
+	"InvestmentAdded":         object(map[string]schema{"investment": ref("Investment")}, "investment"),
+	"LoanInvested":            object(map[string]schema{"total_invested": ref("Money")}, "total_invested"),
+	"AgreementLetterAttached": object(map[string]schema{"url": str()}, "url"),
+	"LoanDisbursed":           object(map[string]schema{"disbursement": ref("Disbursement")}, "disbursement"),
+	"LoanRejected":            object(map[string]schema{"rejection": ref("Rejection")}, "rejection"),
+	"LoanCancelled":           object(map[string]schema{"cancellation": ref("Cancellation")}, "cancellation"),
+	"LoanRepaid":              object(map[string]schema{"total_paid": ref("Money")}, "total_paid"),
+	"LoanDefaulted":           object(map[string]schema{"days_past_due": integer()}, "days_past_due"),
+
+	"ScheduleTerms": object(map[string]schema{
+		"tenor":           integer(),
+		"frequency":       enum(domain.RepaymentFrequencyWeekly, domain.RepaymentFrequencyMonthly),
+		"interest_method": enum(domain.InterestMethodFlat, domain.InterestMethodAnnuity),
+	}, "tenor", "frequency", "interest_method"),
+	"Installment": object(installmentProperties(nil), "number", "due_date", "principal", "interest", "amount"),
+	"RepaymentSchedule": object(map[string]schema{
+		"loan_id":         str(),
+		"terms":           ref("ScheduleTerms"),
+		"total_principal": ref("Money"),
+		"total_interest":  ref("Money"),
+		"total_amount":    ref("Money"),
+		"installments":    arrayOf(ref("Installment")),
+		"created_at":      dateTime(),
+	}, "loan_id", "terms", "total_principal", "total_interest", "total_amount", "installments", "created_at"),
+	"InstallmentStatus": object(installmentProperties(map[string]schema{
+		"paid":        ref("Money"),
+		"outstanding": ref("Money"),
+		"state":       ref("InstallmentState"),
+		"paid_at":     dateTime(),
+	}), "number", "due_date", "principal", "interest", "amount", "paid", "outstanding", "state"),
+	"Repayment": object(map[string]schema{
+		"id":          str(),
+		"loan_id":     str(),
+		"amount":      ref("Money"),
+		"paid_at":     dateTime(),
+		"recorded_at": dateTime(),
+	}, "id", "loan_id", "amount", "paid_at", "recorded_at"),
+	"RepaymentStatus": object(map[string]schema{
+		"loan_id":       str(),
+		"as_of":         dateTime(),
+		"total_due":     ref("Money"),
+		"total_paid":    ref("Money"),
+		"outstanding":   ref("Money"),
+		"overpaid":      ref("Money"),
+		"days_past_due": integer(),
+		"delinquent":    boolean(),
+		"installments":  arrayOf(ref("InstallmentStatus")),
+		"repayments":    arrayOf(ref("Repayment")),
+	}, "loan_id", "as_of", "total_due", "total_paid", "outstanding", "overpaid", "days_past_due", "delinquent", "installments", "repayments"),
+	"RepaymentReceipt": object(map[string]schema{
+		"repayment":    ref("Repayment"),
+		"distribution": ref("PayoutDistribution"),
+		"loan_state":   ref("LoanState"),
+		"status":       ref("RepaymentStatus"),
+	}, "repayment", "distribution", "loan_state", "status"),
+	"Payout": object(map[string]schema{
+		"id":            str(),
+		"loan_id":       str(),
+		"repayment_id":  str(),
+		"investment_id": str(),
+		"investor_id":   str(),
+		"amount":        ref("Money"),
+		"created_at":    dateTime(),
+	}, "id", "loan_id", "repayment_id", "investment_id", "investor_id", "amount", "created_at"),
+	"PayoutDistribution": object(map[string]schema{
+		"repayment_id":   str(),
+		"repayment":      ref("Money"),
+		"investor_share": ref("Money"),
+		"platform_share": ref("Money"),
+		"unapplied":      ref("Money"),
+		"payouts":        arrayOf(ref("Payout")),
+	}, "repayment_id", "repayment", "investor_share", "platform_share", "unapplied", "payouts"),
+	"InvestorPayoutTotal": object(map[string]schema{
+		"investor_id": str(),
+		"total":       ref("Money"),
+	}, "investor_id", "total"),
+	"PayoutLedger": object(map[string]schema{
+		"loan_id":         str(),
+		"total_paid_out":  ref("Money"),
+		"investor_totals": arrayOf(ref("InvestorPayoutTotal")),
+		"entries":         arrayOf(ref("Payout")),
+	}, "loan_id", "total_paid_out", "investor_totals", "entries"),
+
+	"WebhookSubscription": object(map[string]schema{
+		"id":         str(),
+		"url":        str(),
+		"secret":     schema{"type": "string", "description": "Only returned when the subscription is created"},
+		"events":     schema{"type": "array", "items": ref("WebhookEventType"), "description": "Empty when subscribed to every event"},
+		"active":     boolean(),
+		"created_at": dateTime(),
+		"updated_at": dateTime(),
+	}, "id", "url", "events", "active", "created_at", "updated_at"),
+	"WebhookDelivery": object(map[string]schema{
+		"id":              str(),
+		"subscription_id": str(),
+		"event_id":        str(),
+		"event_type":      ref("WebhookEventType"),
+		"url":             str(),
+		"attempt":         integer(),
+		"status_code":     integer(),
+		"error":           str(),
+		"succeeded":       boolean(),
+		"duration_ms":     integer(),
+		"attempted_at":    dateTime(),
+	}, "id", "subscription_id", "event_id", "event_type", "url", "attempt", "succeeded", "duration_ms", "attempted_at"),
+	"WebhookDeliveryPage": page("WebhookDelivery", nil),
+
+	"OutboxMessage": object(map[string]schema{
+		"id":              str(),
+		"topic":           str(),
+		"payload":         schema{"description": "The message as delivered"},
+		"status":          ref("OutboxStatus"),
+		"attempts":        integer(),
+		"last_error":      str(),
+		"next_attempt_at": dateTime(),
+		"created_at":      dateTime(),
+		"updated_at":      dateTime(),
+		"delivered_at":    dateTime(),
+	}, "id", "topic", "payload", "status", "attempts", "next_attempt_at", "created_at", "updated_at"),
+	"OutboxMessagePage": page("OutboxMessage", nil),
+	"AuditEntry": object(map[string]schema{
+		"id":          str(),
+		"sequence":    integer(),
+		"actor":       str(),
+		"action":      ref("AuditAction"),
+		"loan_id":     str(),
+		"request_id":  str(),
+		"before":      schema{"description": "The record before the change; absent when the change created it"},
+		"after":       schema{"description": "The record after the change"},
+		"occurred_at": dateTime(),
+		"prev_hash":   str(),
+		"hash":        str(),
+	}, "id", "sequence", "actor", "action", "occurred_at", "prev_hash", "hash"),
+	"AuditEntryPage": page("AuditEntry", nil),
+	"AuditVerification": object(map[string]schema{
+		"valid":           boolean(),
+		"entries_checked": integer(),
+		"broken_at":       integer(),
+		"error":           str(),
+	}, "valid", "entries_checked"),
+
+	"CreateLoanRequest": object(map[string]schema{
+		"borrower_id":      str(),
+		"principal_amount": decimal(),
+		"currency":         schema{"type": "string", "example": "IDR"},
+		"rate":             schema{"type": "number", "minimum": 0},
+		"roi":              schema{"type": "number", "minimum": 0},
+	}, "borrower_id", "principal_amount", "currency"),
+	"ApprovalRequest": object(map[string]schema{
+		"proof_picture_url":  str(),
+		"field_validator_id": str(),
+		"approval_date":      date(),
+	}, "proof_picture_url", "field_validator_id", "approval_date"),
+	"InvestmentRequest": object(map[string]schema{
+		"investor_id": str(),
+		"amount":      decimal(),
+		"currency":    schema{"type": "string", "description": "Must match the loan currency"},
+	}, "investor_id", "amount", "currency"),
+	"DisbursementRequest": object(map[string]schema{
+		"agreement_document_url": str(),
+		"field_officer_id":       str(),
+		"disbursement_date":      date(),
+	}, "agreement_document_url", "field_officer_id", "disbursement_date"),
+	"RejectionRequest": object(map[string]schema{
+		"reason":         str(),
+		"reviewer_id":    str(),
+		"rejection_date": date(),
+	}, "reason", "reviewer_id", "rejection_date"),
+	"CancellationRequest": object(map[string]schema{
+		"reason":            str(),
+		"cancelled_by":      str(),
+		"cancellation_date": date(),
+	}, "reason", "cancelled_by", "cancellation_date"),
+	"RepaymentRequest": object(map[string]schema{
+		"amount":       decimal(),
+		"currency":     schema{"type": "string", "description": "Must match the loan currency"},
+		"payment_date": date(),
+	}, "amount", "currency", "payment_date"),
+	"WebhookSubscriptionRequest": object(map[string]schema{
+		"url":    schema{"type": "string", "format": "uri"},
+		"events": schema{"type": "array", "items": ref("WebhookEventType"), "description": "Omit to subscribe to every event"},
+	}, "url"),
+}
+
+// errorResponses name the error responses by status
+var errorResponses = map[int]string{
+	http.StatusBadRequest:            "BadRequest",
+	http.StatusUnauthorized:          "Unauthorized",
+	http.StatusForbidden:             "Forbidden",
+	http.StatusNotFound:              "NotFound",
+	http.StatusConflict:              "Conflict",
+	http.StatusRequestEntityTooLarge: "PayloadTooLarge",
+	http.StatusUnprocessableEntity:   "UnprocessableEntity",
+	http.StatusInternalServerError:   "InternalServerError",
+}
+
+// openAPIDocument is the OpenAPI 3 document of the routes in operations
+var openAPIDocument = mustMarshal(buildOpenAPIDocument())
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+func buildOpenAPIDocument() schema {
+	paths := map[string]schema{}
+	for _, op := range operations {
+		if paths[op.path] == nil {
+			paths[op.path] = schema{}
+		}
+		paths[op.path][strings.ToLower(op.method)] = op.document()
+	}
+
+	responses := map[string]schema{}
+	for status, name := range errorResponses {
+		responses[name] = schema{
+			"description": http.StatusText(status),
+			"content":     jsonContent(ref("Error")),
+		}
+	}
+
+	return schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":   "Loan Management API",
+			"version": "1.0.0",
+		},
+		"servers":  []schema{{"url": "/api/v1"}},
+		"security": []schema{{"bearerAuth": []string{}}},
+		"paths":    paths,
+		"components": schema{
+			"schemas":   schemas,
+			"responses": responses,
+			"parameters": schema{
+				"IdempotencyKey": schema{
+					"name":        "Idempotency-Key",
+					"in":          "header",
+					"description": "Makes the request safe to retry: the response to the first request with the key is replayed for 24 hours",
+					"schema":      schema{"type": "string", "maxLength": 255},
+				},
+			},
+			"securitySchemes": schema{
+				"bearerAuth": schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+func (op operation) document() schema {
+	parameters := []schema{}
+	if strings.Contains(op.path, "{id}") {
+		parameters = append(parameters, schema{"name": "id", "in": "path", "required": true, "schema": str()})
+	}
+	parameters = append(parameters, op.query...)
+	if op.method == http.MethodPost {
+		parameters = append(parameters, schema{"$ref": "#/components/parameters/IdempotencyKey"})
+	}
+
+	success := op.raw
+	if success == nil {
+		success = envelope(op.data)
+	}
+	responses := schema{}
+	content := jsonContent(success)
+	if op.content != "" {
+		content = schema{op.content: schema{"schema": success}}
+	}
+	responses[strconv.Itoa(op.status)] = schema{
+		"description": http.StatusText(op.status),
+		"content":     content,
+	}
+	for _, status := range op.errorStatuses() {
+		responses[strconv.Itoa(status)] = schema{"$ref": "#/components/responses/" + errorResponses[status]}
+	}
+
+	doc := schema{
+		"operationId": op.id,
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"responses":   responses,
+	}
+	if len(parameters) > 0 {
+		doc["parameters"] = parameters
+	}
+	if op.body != "" {
+		doc["requestBody"] = schema{"required": true, "content": jsonContent(ref(op.body))}
+	}
+	if op.public {
+		doc["security"] = []schema{}
+	}
+	return doc
+}
+
+// errorStatuses lists the statuses of the errors op may answer with
+func (op operation) errorStatuses() []int {
+	statuses := map[int]bool{http.StatusInternalServerError: true}
+	if !op.public {
+		statuses[http.StatusUnauthorized] = true
+		statuses[http.StatusForbidden] = true
+	}
+	if strings.Contains(op.path, "{id}") {
+		statuses[http.StatusNotFound] = true
+	}
+	if len(op.query) > 0 {
+		// Query parameters that do not parse or do not fit together
+		statuses[http.StatusUnprocessableEntity] = true
+	}
+	if op.method != http.MethodGet {
+		// Changes may conflict with the state of what they change
+		statuses[http.StatusConflict] = true
+	}
+	if op.method == http.MethodPost {
+		// POSTs also conflict with a request still running under the same
+		// Idempotency-Key, and a key reused for another request is
+		// unprocessable
+		statuses[http.StatusBadRequest] = true
+		statuses[http.StatusUnprocessableEntity] = true
+	}
+	if op.body != "" {
+		statuses[http.StatusRequestEntityTooLarge] = true
+	}
+
+	var list []int
+	for status := range statuses {
+		list = append(list, status)
+	}
+	sort.Ints(list)
+	return list
+}
+
+func envelope(data schema) schema {
+	return object(map[string]schema{
+		"code":    integer(),
+		"message": str(),
+		"data":    data,
+	}, "code", "message", "data")
+}
+
+// page is a page of items named by item, with the extra properties given
+func page(item string, extra map[string]schema) schema {
+	properties := map[string]schema{
+		"items":     arrayOf(ref(item)),
+		"total":     integer(),
+		"page":      schema{"type": "integer", "description": "Absent when paging by cursor"},
+		"page_size": integer(),
+	}
+	for name, property := range extra {
+		properties[name] = property
+	}
+	return object(properties, "items", "total", "page_size")
+}
+
+// installmentProperties returns the properties of an installment together
+// with extra ones
+func installmentProperties(extra map[string]schema) map[string]schema {
+	properties := map[string]schema{
+		"number":    integer(),
+		"due_date":  dateTime(),
+		"principal": ref("Money"),
+		"interest":  ref("Money"),
+		"amount":    ref("Money"),
+	}
+	for name, property := range extra {
+		properties[name] = property
+	}
+	return properties
+}
+
+func queryParameter(name, description string, s schema) schema {
+	return schema{"name": name, "in": "query", "description": description, "schema": s}
+}
+
+func jsonContent(s schema) schema {
+	return schema{"application/json": schema{"schema": s}}
+}
+
+// object is a closed object: properties not listed are not allowed
+func object(properties map[string]schema, required ...string) schema {
+	return schema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func ref(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+func arrayOf(items schema) schema {
+	return schema{"type": "array", "items": items}
+}
+
+// enum is a string schema allowing only values, which may be of any string
+// type
+func enum[T ~string](values ...T) schema {
+	list := make([]string, len(values))
+	for i, value := range values {
+		list[i] = string(value)
+	}
+	return schema{"type": "string", "enum": list}
+}
+
+func str() schema      { return schema{"type": "string"} }
+func integer() schema  { return schema{"type": "integer"} }
+func number() schema   { return schema{"type": "number"} }
+func boolean() schema  { return schema{"type": "boolean"} }
+func dateTime() schema { return schema{"type": "string", "format": "date-time"} }
+func date() schema     { return schema{"type": "string", "format": "date", "example": "2024-01-31"} }
+
+// decimal is an amount of money as a decimal string, e.g. "1000.50"
+func decimal() schema {
+	return schema{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?$`, "example": "1000.50"}
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
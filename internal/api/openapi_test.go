@@ -0,0 +1,419 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loan/internal/api"
+	"loan/internal/api/middleware"
+	"loan/internal/document"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const apiPrefix = "/api/v1"
+
+// openAPIClient sends requests through the router and checks every response
+// against the OpenAPI document the router serves
+type openAPIClient struct {
+	t       *testing.T
+	router  *mux.Router
+	service *service.LoanService
+	spec    map[string]interface{}
+	// covered records the operations answered with their success status
+	covered map[string]bool
+}
+
+func newOpenAPIClient(t *testing.T) *openAPIClient {
+	t.Helper()
+	// Every notification fails and is dead-lettered on its first attempt, so
+	// that there are dead messages to replay
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), unreachableNotifier{},
+		service.WithAgreementLetterGenerator(service.NewAgreementLetterGenerator(document.NewMemoryStore(), "https://loans.example.com")),
+		service.WithRetryPolicy(service.RetryPolicy{MaxAttempts: 1}))
+	c := &openAPIClient{t: t, router: api.SetupRouter(loanService, nil), service: loanService, covered: map[string]bool{}}
+
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.OpenAPIPath, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected the OpenAPI document as JSON, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &c.spec); err != nil {
+		t.Fatalf("Failed to decode the OpenAPI document: %v", err)
+	}
+	if c.spec["openapi"] != "3.0.3" {
+		t.Fatalf("Expected an OpenAPI 3.0.3 document, got %v", c.spec["openapi"])
+	}
+	return c
+}
+
+// unreachableNotifier fails every notification
+type unreachableNotifier struct{}
+
+func (unreachableNotifier) Notify(ctx context.Context, notification *domain.Notification) error {
+	return errors.New("notification channel unreachable")
+}
+
+// do sends a request, checks the response against the document and returns
+// its data
+func (c *openAPIClient) do(method, path, body string, headers ...string) (int, map[string]interface{}) {
+	c.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	var match mux.RouteMatch
+	if !c.router.Match(req, &match) || match.Route == nil {
+		c.t.Fatalf("%s %s matches no route", method, path)
+	}
+	template, _ := match.Route.GetPathTemplate()
+	operation := strings.TrimPrefix(template, apiPrefix)
+
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, req)
+
+	responses, _ := c.lookup("paths", operation, strings.ToLower(method), "responses").(map[string]interface{})
+	if responses == nil {
+		c.t.Fatalf("%s %s is not documented", method, operation)
+	}
+	status := fmt.Sprint(rec.Code)
+	response, _ := responses[status].(map[string]interface{})
+	if response == nil {
+		c.t.Fatalf("%s %s answered with undocumented status %s: %s", method, operation, status, rec.Body)
+	}
+	response = c.resolve(response)
+	mediaType := rec.Header().Get("Content-Type")
+	if c.lookupIn(response, "content", mediaType) == nil {
+		c.t.Fatalf("%s %s %s answered with undocumented content type %q", method, operation, status, mediaType)
+	}
+
+	if rec.Code < http.StatusBadRequest {
+		c.covered[method+" "+operation] = true
+	}
+	if mediaType != "application/json" {
+		return rec.Code, nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		c.t.Fatalf("%s %s: response is not JSON: %v", method, path, err)
+	}
+	s, _ := c.lookupIn(response, "content", mediaType, "schema").(map[string]interface{})
+	for _, problem := range c.validate(s, decoded, "response") {
+		c.t.Errorf("%s %s %s: %s", method, operation, status, problem)
+	}
+
+	data, _ := decoded.(map[string]interface{})["data"].(map[string]interface{})
+	return rec.Code, data
+}
+
+func (c *openAPIClient) lookup(keys ...string) interface{} {
+	return c.lookupIn(c.spec, keys...)
+}
+
+func (c *openAPIClient) lookupIn(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// resolve follows a $ref within the document
+func (c *openAPIClient) resolve(s map[string]interface{}) map[string]interface{} {
+	ref, ok := s["$ref"].(string)
+	if !ok {
+		return s
+	}
+	resolved, _ := c.lookup(strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]interface{})
+	if resolved == nil {
+		c.t.Fatalf("Unresolved reference %s", ref)
+	}
+	return c.resolve(resolved)
+}
+
+// validate checks value against the parts of JSON Schema the document uses,
+// returning what does not match
+func (c *openAPIClient) validate(s map[string]interface{}, value interface{}, at string) []string {
+	if s == nil {
+		return []string{at + " has no schema"}
+	}
+	s = c.resolve(s)
+
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, option := range oneOf {
+			if len(c.validate(option.(map[string]interface{}), value, at)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s matches %d of its oneOf schemas", at, matches)}
+		}
+		return nil
+	}
+
+	kind, _ := s["type"].(string)
+	if kind == "" {
+		return nil
+	}
+	if value == nil {
+		if s["nullable"] == true {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s is null, want %s", at, kind)}
+	}
+
+	var problems []string
+	switch kind {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want object", at, value)}
+		}
+		for _, name := range s["required"].([]interface{}) {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s lacks required property %s", at, name))
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, documented := properties[name].(map[string]interface{})
+			if !documented {
+				if s["additionalProperties"] == false {
+					problems = append(problems, fmt.Sprintf("%s has undocumented property %s", at, name))
+				}
+				continue
+			}
+			problems = append(problems, c.validate(property, object[name], at+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want array", at, value)}
+		}
+		for i, item := range items {
+			problems = append(problems, c.validate(s["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want string", at, value)}
+		}
+		if enum, ok := s["enum"].([]interface{}); ok && !contains(enum, str) {
+			problems = append(problems, fmt.Sprintf("%s is %q, want one of %v", at, str, enum))
+		}
+		if pattern, ok := s["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(str) {
+			problems = append(problems, fmt.Sprintf("%s is %q, want a match for %s", at, str, pattern))
+		}
+		if s["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s is %q, want a date-time", at, str))
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %T, want %s", at, value, kind)}
+		}
+		if kind == "integer" && number != math.Trunc(number) {
+			problems = append(problems, fmt.Sprintf("%s is %v, want an integer", at, number))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s is %T, want boolean", at, value)}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s has unknown type %s", at, kind))
+	}
+	return problems
+}
+
+func contains(values []interface{}, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	// Arrange
+	c := newOpenAPIClient(t)
+
+	// Act
+	routed := map[string]bool{}
+	err := c.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // the /api/v1 subrouter
+		}
+		for _, method := range methods {
+			routed[strings.ToLower(method)+" "+strings.TrimPrefix(template, apiPrefix)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk the router: %v", err)
+	}
+
+	// Assert
+	documented := map[string]bool{}
+	for path, item := range c.spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			documented[method+" "+path] = true
+		}
+	}
+	for route := range routed {
+		if !documented[route] {
+			t.Errorf("Expected route %s to be documented", route)
+		}
+	}
+	for route := range documented {
+		if !routed[route] {
+			t.Errorf("Expected documented operation %s to be routed", route)
+		}
+	}
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	c := newOpenAPIClient(t)
+	loans := apiPrefix + "/loans"
+
+	create := func(borrower string) string {
+		t.Helper()
+		status, loan := c.do("POST", loans, `{"borrower_id": "`+borrower+`", "principal_amount": "1000000", "currency": "IDR", "rate": 0.12, "roi": 0.1}`)
+		if status != http.StatusCreated {
+			t.Fatalf("Expected status 201 creating a loan, got %d", status)
+		}
+		return loans + "/" + loan["id"].(string)
+	}
+
+	// A loan through its whole life
+	loan := create("b1")
+	c.do("GET", loan, "")
+	c.do("POST", loan+"/approve", `{"proof_picture_url": "https://example.com/proof.jpg", "field_validator_id": "v1", "approval_date": "2024-01-10"}`)
+	c.do("POST", loan+"/investments", `{"investor_id": "i1", "amount": "400000", "currency": "IDR"}`)
+	c.do("POST", loan+"/investments", `{"investor_id": "i2", "amount": "600000", "currency": "IDR"}`, "Idempotency-Key", "invest-i2")
+	c.do("POST", loan+"/investments", `{"investor_id": "i2", "amount": "600000", "currency": "IDR"}`, "Idempotency-Key", "invest-i2")
+	c.do("GET", loan+"/investments", "")
+	c.do("GET", loan+"/agreement-letter", "")
+	c.do("POST", loan+"/disburse", `{"agreement_document_url": "https://example.com/agreement.pdf", "field_officer_id": "o1", "disbursement_date": "2024-01-15"}`)
+	c.do("GET", loan+"/schedule", "")
+	c.do("POST", loan+"/repayments", `{"amount": "100000", "currency": "IDR", "payment_date": "2024-02-15"}`)
+	c.do("GET", loan+"/repayments", "")
+	c.do("GET", loan+"/payouts", "")
+	c.do("GET", loan+"/events", "")
+
+	// Loans that end early
+	rejected := create("b2")
+	c.do("POST", rejected+"/reject", `{"reason": "Incomplete documents", "reviewer_id": "v1", "rejection_date": "2024-01-10"}`)
+	cancelled := create("b3")
+	c.do("POST", cancelled+"/cancel", `{"reason": "No longer needed", "cancelled_by": "b3", "cancellation_date": "2024-01-10"}`)
+	c.do("GET", cancelled+"/events", "")
+
+	c.do("GET", loans+"?page_size=2&sort=-principal_amount", "")
+	_, page := c.do("GET", loans+"?page_size=1", "")
+	if cursor, _ := page["next_cursor"].(string); cursor != "" {
+		c.do("GET", loans+"?page_size=1&cursor="+cursor, "")
+	} else {
+		t.Error("Expected a next_cursor on the first page")
+	}
+
+	// Errors
+	c.do("GET", loans+"/missing", "")
+	c.do("GET", rejected+"/agreement-letter", "")
+	c.do("GET", loans+"?sort=rate", "")
+	c.do("POST", loans, `{"borrower_id": " ", "principal_amount": "1.234", "currency": "IDR"}`)
+	c.do("POST", loans, `{"borrower": "b1"}`)
+	c.do("POST", loans, `{"borrower_id": "`+strings.Repeat("b", 1<<17)+`"}`)
+	c.do("POST", loan+"/approve", `{"proof_picture_url": "https://example.com/proof.jpg", "field_validator_id": "v1", "approval_date": "2024-01-10"}`)
+	c.do("POST", loan+"/investments", `{"investor_id": "i2", "amount": "1", "currency": "IDR"}`, "Idempotency-Key", "invest-i2")
+
+	// Webhooks
+	_, subscription := c.do("POST", apiPrefix+"/webhooks", `{"url": "https://example.com/hooks", "events": ["loan.approved"]}`)
+	webhook := apiPrefix + "/webhooks/" + subscription["id"].(string)
+	c.do("GET", apiPrefix+"/webhooks", "")
+	c.do("GET", webhook, "")
+	c.do("GET", webhook+"/deliveries", "")
+	c.do("DELETE", webhook, "")
+	c.do("DELETE", webhook, "")
+
+	// Administration
+	c.do("GET", apiPrefix+"/admin/outbox?status=PENDING", "")
+	if _, err := c.service.DispatchOutbox(context.Background()); err != nil {
+		t.Fatalf("Expected no error dispatching the outbox, got %v", err)
+	}
+	_, messages := c.do("GET", apiPrefix+"/admin/outbox?status=DEAD", "")
+	items, _ := messages["items"].([]interface{})
+	if len(items) == 0 {
+		t.Fatal("Expected dead outbox messages")
+	}
+	message := apiPrefix + "/admin/outbox/" + items[0].(map[string]interface{})["id"].(string)
+	if status, _ := c.do("POST", message+"/replay", ""); status != http.StatusOK {
+		t.Errorf("Expected status 200 replaying a dead message, got %d", status)
+	}
+	c.do("POST", message+"/replay", "")
+	c.do("GET", apiPrefix+"/admin/audit?loan_id="+strings.TrimPrefix(loan, loans+"/"), "")
+	c.do("GET", apiPrefix+"/admin/audit/verify", "")
+
+	// Every operation must have been answered successfully at least once,
+	// so that each success schema is checked
+	for path, item := range c.spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if operation := strings.ToUpper(method) + " " + path; !c.covered[operation] && path != "/openapi.json" {
+				t.Errorf("Expected %s to be exercised", operation)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocumentNeedsNoAuthentication(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	authenticator, err := middleware.NewJWTAuthenticator(middleware.JWTConfig{HMACSecret: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Failed to create the authenticator: %v", err)
+	}
+	router := api.SetupRouter(loanService, authenticator.Authenticate)
+
+	// Act
+	document := httptest.NewRecorder()
+	router.ServeHTTP(document, httptest.NewRequest(http.MethodGet, api.OpenAPIPath, nil))
+	loans := httptest.NewRecorder()
+	router.ServeHTTP(loans, httptest.NewRequest(http.MethodGet, apiPrefix+"/loans", nil))
+
+	// Assert
+	if document.Code != http.StatusOK {
+		t.Errorf("Expected the document without a token, got %d", document.Code)
+	}
+	if loans.Code != http.StatusUnauthorized {
+		t.Errorf("Expected other routes to need a token, got %d", loans.Code)
+	}
+}
@@ -3,39 +3,109 @@ package api
 import (
 	"loan/internal/api/handlers"
 	"loan/internal/api/middleware"
+	"loan/internal/domain"
 	"loan/internal/service"
+	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRouter(loanService *service.LoanService) *mux.Router {
+// SetupRouter registers the API routes, which all pass through authenticate.
+// A nil authenticate disables authentication, and every request is made by
+// the anonymous actor with the admin role. POST routes accept an
+// Idempotency-Key header. Routes are documented in operations, which is
+// served at OpenAPIPath.
+func SetupRouter(loanService *service.LoanService, authenticate mux.MiddlewareFunc) *mux.Router {
 	router := mux.NewRouter()
 
 	// middlewares
+	router.Use(middleware.RequestID)
 	router.Use(middleware.Logger)
 	router.Use(middleware.ErrorHandler)
 
+	if authenticate == nil {
+		authenticate = middleware.Anonymous
+	}
+
 	loanHandler := handlers.NewLoanHandler(loanService)
 	approvalHandler := handlers.NewApprovalHandler(loanService)
 	investmentHandler := handlers.NewInvestmentHandler(loanService)
 	disbursementHandler := handlers.NewDisbursementHandler(loanService)
+	rejectionHandler := handlers.NewRejectionHandler(loanService)
+	cancellationHandler := handlers.NewCancellationHandler(loanService)
+	scheduleHandler := handlers.NewScheduleHandler(loanService)
+	repaymentHandler := handlers.NewRepaymentHandler(loanService)
+	payoutHandler := handlers.NewPayoutHandler(loanService)
+	outboxHandler := handlers.NewOutboxHandler(loanService)
+	webhookHandler := handlers.NewWebhookHandler(loanService)
+	eventHandler := handlers.NewEventHandler(loanService)
+	auditHandler := handlers.NewAuditHandler(loanService)
+	agreementHandler := handlers.NewAgreementHandler(loanService)
+
+	// The OpenAPI document is public, so it is routed ahead of the API
+	router.HandleFunc(OpenAPIPath, serveOpenAPI).Methods("GET")
 
 	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(authenticate)
+	api.Use(middleware.Idempotency(loanService))
+
+	// Each route names the roles allowed to call it; admins may call every
+	// route. The service further checks that callers only act as themselves.
+	allow := func(handler http.HandlerFunc, roles ...domain.Role) http.Handler {
+		return middleware.RequireRole(roles...)(handler)
+	}
+	adminOnly := func(handler http.HandlerFunc) http.Handler {
+		return allow(handler)
+	}
+	everyone := []domain.Role{domain.RoleBorrower, domain.RoleInvestor, domain.RoleFieldValidator, domain.RoleFieldOfficer}
 
 	// Loan routes
-	api.HandleFunc("/loans", loanHandler.CreateLoan).Methods("POST")
-	api.HandleFunc("/loans/{id}", loanHandler.GetLoan).Methods("GET")
-	api.HandleFunc("/loans", loanHandler.ListLoans).Methods("GET")
+	api.Handle("/loans", allow(loanHandler.CreateLoan, domain.RoleBorrower)).Methods("POST")
+	api.Handle("/loans/{id}", allow(loanHandler.GetLoan, everyone...)).Methods("GET")
+	api.Handle("/loans", allow(loanHandler.ListLoans, everyone...)).Methods("GET")
 
 	// Approval routes
-	api.HandleFunc("/loans/{id}/approve", approvalHandler.ApproveLoan).Methods("POST")
+	api.Handle("/loans/{id}/approve", allow(approvalHandler.ApproveLoan, domain.RoleFieldValidator)).Methods("POST")
 
 	// Investment routes
-	api.HandleFunc("/loans/{id}/investments", investmentHandler.AddInvestment).Methods("POST")
-	api.HandleFunc("/loans/{id}/investments", investmentHandler.GetInvestments).Methods("GET")
+	api.Handle("/loans/{id}/investments", allow(investmentHandler.AddInvestment, domain.RoleInvestor)).Methods("POST")
+	api.Handle("/loans/{id}/investments", allow(investmentHandler.GetInvestments, everyone...)).Methods("GET")
+
+	// Agreement letter routes
+	api.Handle("/loans/{id}/agreement-letter", allow(agreementHandler.GetAgreementLetter, domain.RoleBorrower, domain.RoleInvestor)).Methods("GET")
 
 	// Disbursement routes
-	api.HandleFunc("/loans/{id}/disburse", disbursementHandler.DisburseLoan).Methods("POST")
+	api.Handle("/loans/{id}/disburse", allow(disbursementHandler.DisburseLoan, domain.RoleFieldOfficer)).Methods("POST")
+
+	// Repayment schedule routes
+	api.Handle("/loans/{id}/schedule", allow(scheduleHandler.GetSchedule, everyone...)).Methods("GET")
+
+	// Repayment routes
+	api.Handle("/loans/{id}/repayments", allow(repaymentHandler.RecordRepayment, domain.RoleFieldOfficer)).Methods("POST")
+	api.Handle("/loans/{id}/repayments", allow(repaymentHandler.GetRepayments, everyone...)).Methods("GET")
+
+	// Payout routes
+	api.Handle("/loans/{id}/payouts", allow(payoutHandler.GetPayouts, everyone...)).Methods("GET")
+
+	// Loan history routes
+	api.Handle("/loans/{id}/events", allow(eventHandler.GetEvents, everyone...)).Methods("GET")
+
+	// Rejection and cancellation routes
+	api.Handle("/loans/{id}/reject", allow(rejectionHandler.RejectLoan, domain.RoleFieldValidator)).Methods("POST")
+	api.Handle("/loans/{id}/cancel", allow(cancellationHandler.CancelLoan, domain.RoleBorrower)).Methods("POST")
+
+	// Webhook routes
+	api.Handle("/webhooks", adminOnly(webhookHandler.CreateSubscription)).Methods("POST")
+	api.Handle("/webhooks", adminOnly(webhookHandler.ListSubscriptions)).Methods("GET")
+	api.Handle("/webhooks/{id}", adminOnly(webhookHandler.GetSubscription)).Methods("GET")
+	api.Handle("/webhooks/{id}", adminOnly(webhookHandler.DeleteSubscription)).Methods("DELETE")
+	api.Handle("/webhooks/{id}/deliveries", adminOnly(webhookHandler.ListDeliveries)).Methods("GET")
+
+	// Admin routes
+	api.Handle("/admin/outbox", adminOnly(outboxHandler.ListMessages)).Methods("GET")
+	api.Handle("/admin/outbox/{id}/replay", adminOnly(outboxHandler.ReplayMessage)).Methods("POST")
+	api.Handle("/admin/audit", adminOnly(auditHandler.ListEntries)).Methods("GET")
+	api.Handle("/admin/audit/verify", adminOnly(auditHandler.VerifyTrail)).Methods("GET")
 
 	return router
 }
@@ -0,0 +1,32 @@
+package api_test
+
+import (
+	"loan/internal/api"
+	"loan/internal/api/middleware"
+	"loan/internal/document"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgreementLetterNeedsAuthentication(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithAgreementLetterGenerator(service.NewAgreementLetterGenerator(document.NewMemoryStore(), "https://loans.example.com")))
+	authenticator, err := middleware.NewJWTAuthenticator(middleware.JWTConfig{HMACSecret: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Failed to create the authenticator: %v", err)
+	}
+	router := api.SetupRouter(loanService, authenticator.Authenticate)
+
+	// Act
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, apiPrefix+"/loans/loan_1/agreement-letter", nil))
+
+	// Assert
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package document_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"loan/internal/document"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestLocalStorePut(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	store := document.NewLocalStore(dir)
+
+	// Act
+	err := store.Put(context.Background(), "loan_1/letter.html", "text/html", []byte("<p>hi</p>"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error storing document, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "loan_1", "letter.html"))
+	if err != nil || string(data) != "<p>hi</p>" {
+		t.Errorf("Expected document on disk, got %q (%v)", data, err)
+	}
+
+	for _, name := range []string{"", "/etc/passwd", "../outside", "loan_1/../../outside", "loan_1//letter"} {
+		if err := store.Put(context.Background(), name, "text/plain", nil); err == nil {
+			t.Errorf("Expected error storing %q, got nil", name)
+		}
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	stores := map[string]func(t *testing.T) document.Store{
+		"local":  func(t *testing.T) document.Store { return document.NewLocalStore(t.TempDir()) },
+		"memory": func(t *testing.T) document.Store { return document.NewMemoryStore() },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			store := newStore(t)
+			_ = store.Put(ctx, "loan_1/letter.html", "text/html", []byte("<p>hi</p>"))
+
+			// Act
+			data, err := store.Get(ctx, "loan_1/letter.html")
+			_, missingErr := store.Get(ctx, "loan_2/letter.html")
+			_, escapeErr := store.Get(ctx, "../outside")
+
+			// Assert
+			if err != nil || string(data) != "<p>hi</p>" {
+				t.Errorf("Expected the stored document, got %q (%v)", data, err)
+			}
+			if !errors.Is(missingErr, document.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for a missing document, got %v", missingErr)
+			}
+			if escapeErr == nil || errors.Is(escapeErr, document.ErrNotFound) {
+				t.Errorf("Expected a name outside the store to be rejected, got %v", escapeErr)
+			}
+		})
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	lines := make([]string, 120)
+	for i := range lines {
+		lines[i] = "Line (" + strconv.Itoa(i) + ") \\ é"
+	}
+
+	pdf := document.RenderPDF(lines)
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("Expected PDF header and trailer")
+	}
+
+	if !bytes.Contains(pdf, []byte("/Count 3")) {
+		t.Error("Expected 120 lines to take 3 pages")
+	}
+
+	if !bytes.Contains(pdf, []byte(`(Line \(7\) \\ ?) Tj`)) {
+		t.Error("Expected text to be escaped")
+	}
+
+	// every xref entry must point at the start of its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	offset, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf[offset:], -1)
+	for i, entry := range entries {
+		at, _ := strconv.Atoi(string(entry[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj"; !bytes.HasPrefix(pdf[at:], []byte(want)) {
+			t.Errorf("Expected xref entry %d to point at %q", i+1, want)
+		}
+	}
+}
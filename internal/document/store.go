@@ -0,0 +1,128 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Get for a document that was never stored
+var ErrNotFound = errors.New("document not found")
+
+// Store persists generated documents. Names are slash-separated relative
+// paths such as "loan_123/agreement_letter.pdf"; saving under an existing
+// name replaces it. Stores are not served directly: documents are read back
+// with Get by routes that check who is asking.
+type Store interface {
+	Put(ctx context.Context, name, contentType string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// LocalStore writes documents below a directory on the local filesystem
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{
+		dir: dir,
+	}
+}
+
+func (s *LocalStore) Put(ctx context.Context, name, contentType string, data []byte) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	target := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial document
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (s *LocalStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return data, err
+}
+
+// MemoryStore keeps documents in memory, for tests and local development
+type MemoryStore struct {
+	documents map[string][]byte
+	mutex     sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		documents: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, name, contentType string, data []byte) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.documents[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Get returns a copy of a stored document
+func (s *MemoryStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, exists := s.documents[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// validateName rejects names that are absolute or escape the store root
+func validateName(name string) error {
+	if name == "" {
+		return errors.New("document name cannot be empty")
+	}
+
+	if path.IsAbs(name) || path.Clean(name) != name || strings.HasPrefix(name, "../") || name == ".." {
+		return fmt.Errorf("invalid document name %q", name)
+	}
+
+	return nil
+}
@@ -1,9 +1,6 @@
 package domain
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 type Approval struct {
 	LoanID           string    `json:"loan_id"`
@@ -14,19 +11,19 @@ type Approval struct {
 
 func NewApproval(loanID, proofPictureURL, fieldValidatorID string, approvalDate time.Time) (*Approval, error) {
 	if loanID == "" {
-		return nil, errors.New("loan ID cannot be empty")
+		return nil, ValidationError("loan ID cannot be empty")
 	}
 
 	if proofPictureURL == "" {
-		return nil, errors.New("proof picture URL cannot be empty")
+		return nil, ValidationError("proof picture URL cannot be empty")
 	}
 
 	if fieldValidatorID == "" {
-		return nil, errors.New("field validator ID cannot be empty")
+		return nil, ValidationError("field validator ID cannot be empty")
 	}
 
 	if approvalDate.IsZero() {
-		return nil, errors.New("approval date cannot be empty")
+		return nil, ValidationError("approval date cannot be empty")
 	}
 
 	return &Approval{
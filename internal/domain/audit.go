@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"loan/util"
+	"strconv"
+	"time"
+)
+
+// AuditAction names a change made through the API
+type AuditAction string
+
+const (
+	AuditCreateLoan          AuditAction = "CREATE_LOAN"
+	AuditApproveLoan         AuditAction = "APPROVE_LOAN"
+	AuditAddInvestment       AuditAction = "ADD_INVESTMENT"
+	AuditDisburseLoan        AuditAction = "DISBURSE_LOAN"
+	AuditRecordRepayment     AuditAction = "RECORD_REPAYMENT"
+	AuditRejectLoan          AuditAction = "REJECT_LOAN"
+	AuditCancelLoan          AuditAction = "CANCEL_LOAN"
+	AuditCreateWebhook       AuditAction = "CREATE_WEBHOOK"
+	AuditDeleteWebhook       AuditAction = "DELETE_WEBHOOK"
+	AuditReplayOutboxMessage AuditAction = "REPLAY_OUTBOX_MESSAGE"
+)
+
+// AuditEntry records one change: who made it, through which request, and
+// the state before and after. Each entry includes the hash of the one before
+// it, so altering, removing or reordering any entry breaks the chain from
+// that point on.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	Sequence   int64           `json:"sequence"` // position in the whole trail, from 1
+	Actor      string          `json:"actor"`
+	Action     AuditAction     `json:"action"`
+	LoanID     string          `json:"loan_id,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"` // absent when the change created the record
+	After      json.RawMessage `json:"after,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// NewAuditEntry records action by the context's actor and request. before
+// and after are JSON snapshots of the changed record. The entry must be
+// chained before it is stored.
+func NewAuditEntry(ctx context.Context, action AuditAction, loanID string, before, after json.RawMessage) *AuditEntry {
+	return &AuditEntry{
+		ID:         "aud_" + util.GenerateUUID(),
+		Actor:      ActorFromContext(ctx),
+		Action:     action,
+		LoanID:     loanID,
+		RequestID:  RequestIDFromContext(ctx),
+		Before:     before,
+		After:      after,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// Chain places the entry after prev, or first in the trail when prev is
+// nil, and seals it with its hash
+func (e *AuditEntry) Chain(prev *AuditEntry) {
+	e.Sequence = 1
+	e.PrevHash = ""
+	if prev != nil {
+		e.Sequence = prev.Sequence + 1
+		e.PrevHash = prev.Hash
+	}
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash returns the hex SHA-256 of every field but Hash. Fields are
+// length-prefixed so that no two entries hash the same input.
+func (e *AuditEntry) ComputeHash() string {
+	h := sha256.New()
+	for _, field := range []string{
+		strconv.FormatInt(e.Sequence, 10),
+		e.PrevHash,
+		e.ID,
+		e.Actor,
+		string(e.Action),
+		e.LoanID,
+		e.RequestID,
+		string(e.Before),
+		string(e.After),
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+	} {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditFilter narrows an audit trail listing. Zero-valued fields do not
+// filter.
+type AuditFilter struct {
+	LoanID string
+	Actor  string
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+}
+
+// Matches reports whether entry satisfies every criterion of the filter
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	if f.LoanID != "" && entry.LoanID != f.LoanID {
+		return false
+	}
+
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+
+	if !f.From.IsZero() && entry.OccurredAt.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && !entry.OccurredAt.Before(f.To) {
+		return false
+	}
+
+	return true
+}
+
+// AuditVerification reports whether the audit trail is intact
+type AuditVerification struct {
+	Valid          bool   `json:"valid"`
+	EntriesChecked int    `json:"entries_checked"`
+	BrokenAt       int64  `json:"broken_at,omitempty"` // sequence of the first entry that does not verify
+	Error          string `json:"error,omitempty"`
+}
+
+// VerifyAuditTrail checks the complete trail, in sequence order, by
+// recomputing every hash and link
+func VerifyAuditTrail(entries []*AuditEntry) *AuditVerification {
+	var prev *AuditEntry
+	for i, entry := range entries {
+		broken := func(format string, args ...interface{}) *AuditVerification {
+			return &AuditVerification{
+				EntriesChecked: i + 1,
+				BrokenAt:       entry.Sequence,
+				Error:          fmt.Sprintf(format, args...),
+			}
+		}
+
+		switch {
+		case entry.Sequence != int64(i+1):
+			return broken("expected entry %d, found entry %d", i+1, entry.Sequence)
+		case prev == nil && entry.PrevHash != "":
+			return broken("first entry links to a previous hash")
+		case prev != nil && entry.PrevHash != prev.Hash:
+			return broken("entry %d does not link to entry %d", entry.Sequence, prev.Sequence)
+		case entry.Hash != entry.ComputeHash():
+			return broken("entry %d has been altered", entry.Sequence)
+		}
+		prev = entry
+	}
+
+	return &AuditVerification{Valid: true, EntriesChecked: len(entries)}
+}
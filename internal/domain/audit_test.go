@@ -0,0 +1,68 @@
+package domain_test
+
+import (
+	"context"
+	"loan/internal/domain"
+	"testing"
+)
+
+// auditTrail chains n entries by alice
+func auditTrail(n int) []*domain.AuditEntry {
+	ctx := domain.ContextWithRequestID(domain.ContextWithPrincipal(context.Background(), &domain.Principal{Subject: "alice"}), "req_1")
+
+	var trail []*domain.AuditEntry
+	var prev *domain.AuditEntry
+	for i := 0; i < n; i++ {
+		entry := domain.NewAuditEntry(ctx, domain.AuditApproveLoan, "loan1", []byte(`{"state":"PROPOSED"}`), []byte(`{"state":"APPROVED"}`))
+		entry.Chain(prev)
+		trail = append(trail, entry)
+		prev = entry
+	}
+	return trail
+}
+
+func TestAuditEntryRecordsContext(t *testing.T) {
+	entry := auditTrail(1)[0]
+
+	if entry.Actor != "alice" || entry.RequestID != "req_1" || entry.Sequence != 1 || entry.PrevHash != "" {
+		t.Errorf("Expected the first entry by alice in req_1, got %+v", entry)
+	}
+
+	anonymous := domain.NewAuditEntry(context.Background(), domain.AuditCreateLoan, "loan1", nil, nil)
+	if anonymous.Actor != domain.SystemActor || anonymous.RequestID != "" {
+		t.Errorf("Expected an entry outside a request to be by %s, got %+v", domain.SystemActor, anonymous)
+	}
+}
+
+func TestVerifyAuditTrail(t *testing.T) {
+	if verification := domain.VerifyAuditTrail(auditTrail(3)); !verification.Valid || verification.EntriesChecked != 3 {
+		t.Fatalf("Expected an intact trail of 3 to verify, got %+v", verification)
+	}
+
+	for name, tc := range map[string]struct {
+		tamper   func(trail []*domain.AuditEntry) []*domain.AuditEntry
+		brokenAt int64
+	}{
+		"altered actor": {func(trail []*domain.AuditEntry) []*domain.AuditEntry {
+			trail[1].Actor = "mallory"
+			return trail
+		}, 2},
+		"altered state": {func(trail []*domain.AuditEntry) []*domain.AuditEntry {
+			trail[2].After = []byte(`{"state":"DISBURSED"}`)
+			return trail
+		}, 3},
+		"removed entry": {func(trail []*domain.AuditEntry) []*domain.AuditEntry {
+			return append(trail[:1], trail[2:]...)
+		}, 3},
+		"rehashed entry": {func(trail []*domain.AuditEntry) []*domain.AuditEntry {
+			trail[0].Actor = "mallory"
+			trail[0].Hash = trail[0].ComputeHash()
+			return trail
+		}, 2},
+	} {
+		verification := domain.VerifyAuditTrail(tc.tamper(auditTrail(3)))
+		if verification.Valid || verification.BrokenAt != tc.brokenAt || verification.Error == "" {
+			t.Errorf("Expected %s to break the trail at %d, got %+v", name, tc.brokenAt, verification)
+		}
+	}
+}
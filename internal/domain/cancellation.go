@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type Cancellation struct {
+	LoanID      string    `json:"loan_id"`
+	Reason      string    `json:"reason"`
+	CancelledBy string    `json:"cancelled_by"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+func NewCancellation(loanID, reason, cancelledBy string, cancelledAt time.Time) (*Cancellation, error) {
+	if loanID == "" {
+		return nil, ValidationError("loan ID cannot be empty")
+	}
+
+	if reason == "" {
+		return nil, ValidationError("cancellation reason cannot be empty")
+	}
+
+	if cancelledBy == "" {
+		return nil, ValidationError("cancelled by cannot be empty")
+	}
+
+	if cancelledAt.IsZero() {
+		return nil, ValidationError("cancellation date cannot be empty")
+	}
+
+	return &Cancellation{
+		LoanID:      loanID,
+		Reason:      reason,
+		CancelledBy: cancelledBy,
+		CancelledAt: cancelledAt,
+	}, nil
+}
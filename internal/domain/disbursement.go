@@ -1,9 +1,6 @@
 package domain
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 type Disbursement struct {
 	LoanID               string    `json:"loan_id"`
@@ -14,19 +11,19 @@ type Disbursement struct {
 
 func NewDisbursement(loanID, agreementDocumentURL, fieldOfficerID string, disbursementDate time.Time) (*Disbursement, error) {
 	if loanID == "" {
-		return nil, errors.New("loan ID cannot be empty")
+		return nil, ValidationError("loan ID cannot be empty")
 	}
 
 	if agreementDocumentURL == "" {
-		return nil, errors.New("agreement document URL cannot be empty")
+		return nil, ValidationError("agreement document URL cannot be empty")
 	}
 
 	if fieldOfficerID == "" {
-		return nil, errors.New("field officer ID cannot be empty")
+		return nil, ValidationError("field officer ID cannot be empty")
 	}
 
 	if disbursementDate.IsZero() {
-		return nil, errors.New("disbursement date cannot be empty")
+		return nil, ValidationError("disbursement date cannot be empty")
 	}
 
 	return &Disbursement{
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of failure. Every error of a kind matches it with errors.Is, so
+// callers can tell them apart without comparing messages.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidState = errors.New("invalid state") // the record's current state does not allow the change
+	ErrValidation   = errors.New("validation failed")
+	ErrConflict     = errors.New("conflict") // a concurrent change won
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// Error is a failure of a known kind, with a message that can be shown to
+// the caller as it is
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NotFoundError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func InvalidStateError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrInvalidState, Message: fmt.Sprintf(format, args...)}
+}
+
+func ValidationError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func ConflictError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func ForbiddenError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Codes of a FieldError
+const (
+	FieldRequired      = "required"
+	FieldInvalidFormat = "invalid_format"
+	FieldInvalidType   = "invalid_type"
+	FieldUnsupported   = "unsupported"
+	FieldOutOfRange    = "out_of_range"
+	FieldUnknown       = "unknown_field"
+)
+
+// FieldError describes one invalid field of a request body. Field is the
+// JSON name, with an index for array elements, e.g. "events[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors reports every invalid field of a request at once. It matches
+// ErrValidation.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e FieldErrors) Unwrap() error {
+	return ErrValidation
+}
+
+// Machine-readable codes reported in an error Response
+const (
+	CodeBadRequest           = "BAD_REQUEST"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeConflict             = "CONFLICT"
+	CodeInvalidState         = "INVALID_STATE"
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	CodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	CodeValidationFailed     = "VALIDATION_FAILED"
+	CodeInternal             = "INTERNAL_ERROR"
+)
+
+// ErrorCodeOf returns the code for err's kind, or CodeInternal when it has
+// none
+func ErrorCodeOf(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrInvalidState):
+		return CodeInvalidState
+	case errors.Is(err, ErrValidation):
+		return CodeValidationFailed
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	default:
+		return CodeInternal
+	}
+}
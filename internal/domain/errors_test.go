@@ -0,0 +1,55 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"net/http"
+	"testing"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"not found":      {domain.NotFoundError("loan %s not found", "l1"), domain.CodeNotFound},
+		"invalid state":  {domain.InvalidStateError("loan must be APPROVED"), domain.CodeInvalidState},
+		"validation":     {domain.ValidationError("amount must be positive"), domain.CodeValidationFailed},
+		"conflict":       {domain.ConflictError("modified concurrently"), domain.CodeConflict},
+		"forbidden":      {domain.ForbiddenError("not yours"), domain.CodeForbidden},
+		"wrapped":        {fmt.Errorf("approve: %w", domain.InvalidStateError("too late")), domain.CodeInvalidState},
+		"unknown kind":   {errors.New("disk full"), domain.CodeInternal},
+		"the kind alone": {domain.ErrNotFound, domain.CodeNotFound},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := domain.ErrorCodeOf(tt.err); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorKeepsItsMessage(t *testing.T) {
+	err := domain.ValidationError("unknown loan state %q", "LOST")
+
+	if err.Error() != `unknown loan state "LOST"` {
+		t.Errorf("Expected the message without its kind, got %q", err.Error())
+	}
+	if !errors.Is(err, domain.ErrValidation) || errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expected the error to match only its own kind")
+	}
+}
+
+func TestNewErrorResponseCodes(t *testing.T) {
+	if got := domain.NewErrorResponse(http.StatusBadRequest, "Invalid request body").ErrorCode; got != domain.CodeBadRequest {
+		t.Errorf("Expected %s for 400, got %s", domain.CodeBadRequest, got)
+	}
+
+	response := domain.NewErrorResponseFor(http.StatusConflict, domain.InvalidStateError("loan must be APPROVED"))
+	if response.ErrorCode != domain.CodeInvalidState || response.Message != "loan must be APPROVED" {
+		t.Errorf("Expected the error's own code and message, got %+v", response)
+	}
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// IdempotencyKeyTTL is how long the response to a request made with an
+// idempotency key is replayed. After that the key may be used again.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyRecord holds the response to the first request a caller made
+// with an idempotency key, so that retries get the same response instead of
+// repeating the change. Keys are scoped to the caller.
+type IdempotencyRecord struct {
+	Actor       string
+	Key         string
+	RequestHash string // identifies the method, path and body the key was first used with
+	StatusCode  int    // 0 while the first request is in progress
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+func NewIdempotencyRecord(actor, key, method, path string, body []byte) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		Actor:       actor,
+		Key:         key,
+		RequestHash: hashIdempotentRequest(method, path, body),
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// hashIdempotentRequest returns the hex SHA-256 of the request, with its
+// parts length-prefixed so that no two requests hash the same input
+func hashIdempotentRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(method), []byte(path), body} {
+		h.Write([]byte{byte(len(part) >> 24), byte(len(part) >> 16), byte(len(part) >> 8), byte(len(part))})
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Matches reports whether other repeats the request the record was made for
+func (r *IdempotencyRecord) Matches(other *IdempotencyRecord) bool {
+	return r.RequestHash == other.RequestHash
+}
+
+// Completed reports whether the response has been stored
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// Expired reports whether the key may be used again at now
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.CreatedAt.Add(IdempotencyKeyTTL))
+}
+
+// Complete stores the response to replay
+func (r *IdempotencyRecord) Complete(statusCode int, body []byte) {
+	r.StatusCode = statusCode
+	r.Body = body
+}
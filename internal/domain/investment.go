@@ -1,31 +1,39 @@
 package domain
 
 import (
-	"errors"
 	"loan/util"
 	"time"
 )
 
+type InvestmentStatus string
+
+const (
+	InvestmentStatusActive   InvestmentStatus = "ACTIVE"
+	InvestmentStatusRefunded InvestmentStatus = "REFUNDED"
+)
+
 // Investment represents an investment made in a loan
 type Investment struct {
-	ID         string    `json:"id"`
-	LoanID     string    `json:"loan_id"`
-	InvestorID string    `json:"investor_id"`
-	Amount     float64   `json:"amount"`
-	InvestedAt time.Time `json:"invested_at"`
+	ID         string           `json:"id"`
+	LoanID     string           `json:"loan_id"`
+	InvestorID string           `json:"investor_id"`
+	Amount     Money            `json:"amount"`
+	Status     InvestmentStatus `json:"status"`
+	InvestedAt time.Time        `json:"invested_at"`
+	RefundedAt *time.Time       `json:"refunded_at,omitempty"`
 }
 
-func NewInvestment(loanID, investorID string, amount float64) (*Investment, error) {
+func NewInvestment(loanID, investorID string, amount Money) (*Investment, error) {
 	if loanID == "" {
-		return nil, errors.New("loan ID cannot be empty")
+		return nil, ValidationError("loan ID cannot be empty")
 	}
 
 	if investorID == "" {
-		return nil, errors.New("investor ID cannot be empty")
+		return nil, ValidationError("investor ID cannot be empty")
 	}
 
-	if amount <= 0 {
-		return nil, errors.New("investment amount must be greater than zero")
+	if !amount.IsPositive() {
+		return nil, ValidationError("investment amount must be greater than zero")
 	}
 
 	return &Investment{
@@ -33,6 +41,17 @@ func NewInvestment(loanID, investorID string, amount float64) (*Investment, erro
 		LoanID:     loanID,
 		InvestorID: investorID,
 		Amount:     amount,
+		Status:     InvestmentStatusActive,
 		InvestedAt: time.Now(),
 	}, nil
 }
+
+// Refund marks an active investment as returned to the investor
+func (i *Investment) Refund(refundedAt time.Time) {
+	if i.Status != InvestmentStatusActive {
+		return
+	}
+
+	i.Status = InvestmentStatusRefunded
+	i.RefundedAt = &refundedAt
+}
@@ -1,7 +1,6 @@
 package domain
 
 import (
-	"errors"
 	"loan/util"
 	"time"
 )
@@ -13,42 +12,64 @@ const (
 	LoanStateApproved  LoanState = "APPROVED"
 	LoanStateInvested  LoanState = "INVESTED"
 	LoanStateDisbursed LoanState = "DISBURSED"
+	LoanStateRejected  LoanState = "REJECTED"
+	LoanStateCancelled LoanState = "CANCELLED"
+	LoanStateRepaid    LoanState = "REPAID"
+	LoanStateDefaulted LoanState = "DEFAULTED"
 )
 
+// ParseLoanState validates a state name received from a client
+func ParseLoanState(s string) (LoanState, error) {
+	switch state := LoanState(s); state {
+	case LoanStateProposed, LoanStateApproved, LoanStateInvested, LoanStateDisbursed,
+		LoanStateRejected, LoanStateCancelled, LoanStateRepaid, LoanStateDefaulted:
+		return state, nil
+	default:
+		return "", ValidationError("unknown loan state %q", s)
+	}
+}
+
 type Loan struct {
 	ID                 string    `json:"id"`
 	BorrowerID         string    `json:"borrower_id"`
-	PrincipalAmount    float64   `json:"principal_amount"`
+	PrincipalAmount    Money     `json:"principal_amount"`
 	Rate               float64   `json:"rate"`
 	ROI                float64   `json:"roi"`
 	State              LoanState `json:"state"`
 	AgreementLetterURL string    `json:"agreement_letter_url,omitempty"`
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
+	Version            int       `json:"version"` // bumped on every save, used for optimistic locking
 
 	Approval     *Approval     `json:"approval,omitempty"`
 	Investments  []*Investment `json:"investments,omitempty"`
 	Disbursement *Disbursement `json:"disbursement,omitempty"`
+	Rejection    *Rejection    `json:"rejection,omitempty"`
+	Cancellation *Cancellation `json:"cancellation,omitempty"`
+
+	events []*LoanEvent // raised since the loan was loaded
 }
 
-func NewLoan(borrowerID string, principalAmount, rate, roi float64) *Loan {
-	now := time.Now()
-	return &Loan{
-		ID:              GenerateID(),
+func NewLoan(borrowerID string, principalAmount Money, rate, roi float64) *Loan {
+	loan := &Loan{ID: GenerateID()}
+	loan.raise(&LoanCreated{
 		BorrowerID:      borrowerID,
 		PrincipalAmount: principalAmount,
 		Rate:            rate,
 		ROI:             roi,
-		State:           LoanStateProposed,
-		CreatedAt:       now,
-		UpdatedAt:       now,
-		Investments:     []*Investment{},
-	}
+	})
+	return loan
+}
+
+// Currency is the ISO-4217 currency the loan is denominated in; every
+// investment in the loan must use it.
+func (l *Loan) Currency() string {
+	return l.PrincipalAmount.Currency
 }
 
 func (l *Loan) CanApprove() error {
 	if l.State != LoanStateProposed {
-		return errors.New("loan must be in PROPOSED state to be approved")
+		return InvalidStateError("loan must be in PROPOSED state to be approved")
 	}
 	return nil
 }
@@ -58,20 +79,26 @@ func (l *Loan) Approve(approval *Approval) error {
 		return err
 	}
 
-	l.State = LoanStateApproved
-	l.Approval = approval
-	l.UpdatedAt = time.Now()
+	l.raise(&LoanApproved{Approval: approval})
 	return nil
 }
 
-func (l *Loan) CanAddInvestment(amount float64) error {
+func (l *Loan) CanAddInvestment(amount Money) error {
 	if l.State != LoanStateApproved {
-		return errors.New("loan must be in APPROVED state to add investments")
+		return InvalidStateError("loan must be in APPROVED state to add investments")
+	}
+
+	if amount.Currency != l.Currency() {
+		return ValidationError("investment currency %s does not match loan currency %s", amount.Currency, l.Currency())
+	}
+
+	newTotal, err := l.TotalInvestedAmount().Add(amount)
+	if err != nil {
+		return err
 	}
 
-	currentTotal := l.TotalInvestedAmount()
-	if currentTotal+amount > l.PrincipalAmount {
-		return errors.New("investment would exceed loan principal amount")
+	if cmp, _ := newTotal.Cmp(l.PrincipalAmount); cmp > 0 {
+		return ValidationError("investment would exceed loan principal amount")
 	}
 
 	return nil
@@ -82,27 +109,30 @@ func (l *Loan) AddInvestment(investment *Investment) error {
 		return err
 	}
 
-	l.Investments = append(l.Investments, investment)
-	l.UpdatedAt = time.Now()
+	l.raise(&InvestmentAdded{Investment: investment})
 
-	if l.TotalInvestedAmount() == l.PrincipalAmount {
-		l.State = LoanStateInvested
+	if total := l.TotalInvestedAmount(); total == l.PrincipalAmount {
+		l.raise(&LoanInvested{TotalInvested: total})
 	}
 
 	return nil
 }
 
-func (l *Loan) TotalInvestedAmount() float64 {
-	var total float64
+// TotalInvestedAmount sums the loan's active investments in the loan's
+// currency. CanAddInvestment guarantees every investment shares that currency.
+func (l *Loan) TotalInvestedAmount() Money {
+	total := ZeroMoney(l.PrincipalAmount.Currency)
 	for _, investment := range l.Investments {
-		total += investment.Amount
+		if investment.Status == InvestmentStatusActive {
+			total.Amount += investment.Amount.Amount
+		}
 	}
 	return total
 }
 
 func (l *Loan) CanDisburse() error {
 	if l.State != LoanStateInvested {
-		return errors.New("loan must be in INVESTED state to be disbursed")
+		return InvalidStateError("loan must be in INVESTED state to be disbursed")
 	}
 	return nil
 }
@@ -112,12 +142,81 @@ func (l *Loan) Disburse(disbursement *Disbursement) error {
 		return err
 	}
 
-	l.State = LoanStateDisbursed
-	l.Disbursement = disbursement
-	l.UpdatedAt = time.Now()
+	l.raise(&LoanDisbursed{Disbursement: disbursement})
+	return nil
+}
+
+func (l *Loan) CanReject() error {
+	if l.State != LoanStateProposed {
+		return InvalidStateError("loan must be in PROPOSED state to be rejected")
+	}
+	return nil
+}
+
+func (l *Loan) Reject(rejection *Rejection) error {
+	if err := l.CanReject(); err != nil {
+		return err
+	}
+
+	l.raise(&LoanRejected{Rejection: rejection})
+	return nil
+}
+
+func (l *Loan) CanCancel() error {
+	if l.State != LoanStateProposed && l.State != LoanStateApproved {
+		return InvalidStateError("loan must be in PROPOSED or APPROVED state to be cancelled")
+	}
+	return nil
+}
+
+// Cancel moves the loan to CANCELLED and refunds every investment made so
+// far, since the money will never be disbursed.
+func (l *Loan) Cancel(cancellation *Cancellation) error {
+	if err := l.CanCancel(); err != nil {
+		return err
+	}
+
+	l.raise(&LoanCancelled{Cancellation: cancellation})
+	return nil
+}
+
+func (l *Loan) CanRecordRepayment(amount Money) error {
+	if l.State != LoanStateDisbursed {
+		return InvalidStateError("loan must be in DISBURSED state to record repayments")
+	}
+
+	if amount.Currency != l.Currency() {
+		return ValidationError("repayment currency %s does not match loan currency %s", amount.Currency, l.Currency())
+	}
+
 	return nil
 }
 
+// SettleRepayments moves a DISBURSED loan to REPAID once status shows nothing
+// outstanding, or to DEFAULTED once it is past the policy's default
+// threshold. It reports whether the state changed.
+func (l *Loan) SettleRepayments(status *RepaymentStatus, policy DelinquencyPolicy) bool {
+	if l.State != LoanStateDisbursed {
+		return false
+	}
+
+	switch {
+	case status.IsRepaid():
+		l.raise(&LoanRepaid{TotalPaid: status.TotalPaid})
+	case status.DaysPastDue >= policy.DefaultAfterDays:
+		l.raise(&LoanDefaulted{DaysPastDue: status.DaysPastDue})
+	default:
+		return false
+	}
+
+	return true
+}
+
+// AttachAgreementLetter records the URL of the loan's agreement letter
+func (l *Loan) AttachAgreementLetter(url string) {
+	l.raise(&AgreementLetterAttached{URL: url})
+}
+
 func GenerateID() string {
 	return "loan_" + util.GenerateUUID()
 }
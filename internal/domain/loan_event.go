@@ -0,0 +1,276 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loan/util"
+	"time"
+)
+
+// LoanEventType names a change recorded in a loan's history
+type LoanEventType string
+
+const (
+	LoanEventCreated                 LoanEventType = "LOAN_CREATED"
+	LoanEventApproved                LoanEventType = "LOAN_APPROVED"
+	LoanEventInvestmentAdded         LoanEventType = "INVESTMENT_ADDED"
+	LoanEventInvested                LoanEventType = "LOAN_INVESTED"
+	LoanEventAgreementLetterAttached LoanEventType = "AGREEMENT_LETTER_ATTACHED"
+	LoanEventDisbursed               LoanEventType = "LOAN_DISBURSED"
+	LoanEventRejected                LoanEventType = "LOAN_REJECTED"
+	LoanEventCancelled               LoanEventType = "LOAN_CANCELLED"
+	LoanEventRepaid                  LoanEventType = "LOAN_REPAID"
+	LoanEventDefaulted               LoanEventType = "LOAN_DEFAULTED"
+)
+
+// LoanEventData is the typed payload of a LoanEvent
+type LoanEventData interface {
+	LoanEventType() LoanEventType
+}
+
+type LoanCreated struct {
+	BorrowerID      string  `json:"borrower_id"`
+	PrincipalAmount Money   `json:"principal_amount"`
+	Rate            float64 `json:"rate"`
+	ROI             float64 `json:"roi"`
+}
+
+type LoanApproved struct {
+	Approval *Approval `json:"approval"`
+}
+
+type InvestmentAdded struct {
+	Investment *Investment `json:"investment"`
+}
+
+// LoanInvested follows the InvestmentAdded event that fully funded the loan
+type LoanInvested struct {
+	TotalInvested Money `json:"total_invested"`
+}
+
+type AgreementLetterAttached struct {
+	URL string `json:"url"`
+}
+
+type LoanDisbursed struct {
+	Disbursement *Disbursement `json:"disbursement"`
+}
+
+type LoanRejected struct {
+	Rejection *Rejection `json:"rejection"`
+}
+
+// LoanCancelled also refunds every active investment
+type LoanCancelled struct {
+	Cancellation *Cancellation `json:"cancellation"`
+}
+
+type LoanRepaid struct {
+	TotalPaid Money `json:"total_paid"`
+}
+
+type LoanDefaulted struct {
+	DaysPastDue int `json:"days_past_due"`
+}
+
+func (*LoanCreated) LoanEventType() LoanEventType {
+	return LoanEventCreated
+}
+
+func (*LoanApproved) LoanEventType() LoanEventType {
+	return LoanEventApproved
+}
+
+func (*InvestmentAdded) LoanEventType() LoanEventType {
+	return LoanEventInvestmentAdded
+}
+
+func (*LoanInvested) LoanEventType() LoanEventType {
+	return LoanEventInvested
+}
+
+func (*AgreementLetterAttached) LoanEventType() LoanEventType {
+	return LoanEventAgreementLetterAttached
+}
+
+func (*LoanDisbursed) LoanEventType() LoanEventType {
+	return LoanEventDisbursed
+}
+
+func (*LoanRejected) LoanEventType() LoanEventType {
+	return LoanEventRejected
+}
+
+func (*LoanCancelled) LoanEventType() LoanEventType {
+	return LoanEventCancelled
+}
+
+func (*LoanRepaid) LoanEventType() LoanEventType {
+	return LoanEventRepaid
+}
+
+func (*LoanDefaulted) LoanEventType() LoanEventType {
+	return LoanEventDefaulted
+}
+
+// LoanEvent is one entry in a loan's append-only history. Replaying a loan's
+// events in sequence order rebuilds the loan.
+type LoanEvent struct {
+	ID         string        `json:"id"`
+	LoanID     string        `json:"loan_id"`
+	Sequence   int           `json:"sequence"` // 1 for LOAN_CREATED, assigned by the event store
+	Type       LoanEventType `json:"type"`
+	Data       LoanEventData `json:"data"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
+
+func NewLoanEvent(loanID string, data LoanEventData) *LoanEvent {
+	return &LoanEvent{
+		ID:         "lev_" + util.GenerateUUID(),
+		LoanID:     loanID,
+		Type:       data.LoanEventType(),
+		Data:       data,
+		OccurredAt: time.Now(),
+	}
+}
+
+// DecodeLoanEventData parses the JSON payload of an event of type eventType
+func DecodeLoanEventData(eventType LoanEventType, payload []byte) (LoanEventData, error) {
+	var data LoanEventData
+	switch eventType {
+	case LoanEventCreated:
+		data = &LoanCreated{}
+	case LoanEventApproved:
+		data = &LoanApproved{}
+	case LoanEventInvestmentAdded:
+		data = &InvestmentAdded{}
+	case LoanEventInvested:
+		data = &LoanInvested{}
+	case LoanEventAgreementLetterAttached:
+		data = &AgreementLetterAttached{}
+	case LoanEventDisbursed:
+		data = &LoanDisbursed{}
+	case LoanEventRejected:
+		data = &LoanRejected{}
+	case LoanEventCancelled:
+		data = &LoanCancelled{}
+	case LoanEventRepaid:
+		data = &LoanRepaid{}
+	case LoanEventDefaulted:
+		data = &LoanDefaulted{}
+	default:
+		return nil, fmt.Errorf("unknown loan event %q", eventType)
+	}
+
+	if err := json.Unmarshal(payload, data); err != nil {
+		return nil, fmt.Errorf("decode %s event: %w", eventType, err)
+	}
+	return data, nil
+}
+
+func (e *LoanEvent) UnmarshalJSON(b []byte) error {
+	type plain LoanEvent
+	var raw struct {
+		plain
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	data, err := DecodeLoanEventData(raw.Type, raw.Data)
+	if err != nil {
+		return err
+	}
+
+	*e = LoanEvent(raw.plain)
+	e.Data = data
+	return nil
+}
+
+// ReplayLoan rebuilds a loan from its complete history, which must start
+// with LOAN_CREATED and be in sequence order. Events are facts, so they are
+// applied without re-checking the rules that allowed them. The rebuilt loan
+// has no version, since that belongs to the stored row.
+func ReplayLoan(events []*LoanEvent) (*Loan, error) {
+	if len(events) == 0 {
+		return nil, errors.New("loan has no recorded events")
+	}
+	if events[0].Type != LoanEventCreated {
+		return nil, fmt.Errorf("loan history starts with %s, not %s", events[0].Type, LoanEventCreated)
+	}
+
+	loan := &Loan{ID: events[0].LoanID}
+	for i, event := range events {
+		if event.LoanID != loan.ID {
+			return nil, fmt.Errorf("event %s belongs to loan %s, not %s", event.ID, event.LoanID, loan.ID)
+		}
+		if event.Sequence != i+1 {
+			return nil, fmt.Errorf("event %s has sequence %d, expected %d", event.ID, event.Sequence, i+1)
+		}
+		loan.apply(event)
+	}
+
+	return loan, nil
+}
+
+// raise applies a new event to the loan and holds it until the loan is saved
+func (l *Loan) raise(data LoanEventData) {
+	event := NewLoanEvent(l.ID, data)
+	l.apply(event)
+	l.events = append(l.events, event)
+}
+
+// apply is the only place loan state changes, so live transitions and
+// replayed history always agree
+func (l *Loan) apply(event *LoanEvent) {
+	switch data := event.Data.(type) {
+	case *LoanCreated:
+		l.ID = event.LoanID
+		l.BorrowerID = data.BorrowerID
+		l.PrincipalAmount = data.PrincipalAmount
+		l.Rate = data.Rate
+		l.ROI = data.ROI
+		l.State = LoanStateProposed
+		l.CreatedAt = event.OccurredAt
+		l.Investments = []*Investment{}
+	case *LoanApproved:
+		l.State = LoanStateApproved
+		l.Approval = data.Approval
+	case *InvestmentAdded:
+		l.Investments = append(l.Investments, data.Investment)
+	case *LoanInvested:
+		l.State = LoanStateInvested
+	case *AgreementLetterAttached:
+		l.AgreementLetterURL = data.URL
+	case *LoanDisbursed:
+		l.State = LoanStateDisbursed
+		l.Disbursement = data.Disbursement
+	case *LoanRejected:
+		l.State = LoanStateRejected
+		l.Rejection = data.Rejection
+	case *LoanCancelled:
+		for _, investment := range l.Investments {
+			investment.Refund(data.Cancellation.CancelledAt)
+		}
+		l.State = LoanStateCancelled
+		l.Cancellation = data.Cancellation
+	case *LoanRepaid:
+		l.State = LoanStateRepaid
+	case *LoanDefaulted:
+		l.State = LoanStateDefaulted
+	}
+
+	l.UpdatedAt = event.OccurredAt
+}
+
+// PendingEvents returns the events raised since the loan was loaded, which
+// are appended to the event store when the loan is saved
+func (l *Loan) PendingEvents() []*LoanEvent {
+	return l.events
+}
+
+func (l *Loan) ClearPendingEvents() {
+	l.events = nil
+}
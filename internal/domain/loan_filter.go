@@ -0,0 +1,282 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// LoanFilter narrows a loan listing. Zero-valued fields do not filter.
+type LoanFilter struct {
+	States     []LoanState
+	BorrowerID string
+
+	// A principal bound also restricts the listing to loans in its currency,
+	// since amounts in different currencies cannot be compared
+	MinPrincipal *Money // inclusive
+	MaxPrincipal *Money // inclusive
+
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+
+	// Funding is the percentage of the principal covered by active
+	// investments, from 0 to 100
+	MinFundedPercent *float64 // inclusive
+	MaxFundedPercent *float64 // inclusive
+}
+
+// Matches reports whether loan satisfies every criterion of the filter
+func (f LoanFilter) Matches(loan *Loan) bool {
+	if len(f.States) > 0 {
+		matched := false
+		for _, state := range f.States {
+			if loan.State == state {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if f.BorrowerID != "" && loan.BorrowerID != f.BorrowerID {
+		return false
+	}
+
+	if f.MinPrincipal != nil {
+		if cmp, err := loan.PrincipalAmount.Cmp(*f.MinPrincipal); err != nil || cmp < 0 {
+			return false
+		}
+	}
+
+	if f.MaxPrincipal != nil {
+		if cmp, err := loan.PrincipalAmount.Cmp(*f.MaxPrincipal); err != nil || cmp > 0 {
+			return false
+		}
+	}
+
+	if !f.CreatedFrom.IsZero() && loan.CreatedAt.Before(f.CreatedFrom) {
+		return false
+	}
+
+	if !f.CreatedTo.IsZero() && !loan.CreatedAt.Before(f.CreatedTo) {
+		return false
+	}
+
+	// Compared as invested * 100 against percent * principal, as the SQL
+	// backend does, so both agree at the boundaries
+	invested := float64(loan.TotalInvestedAmount().Amount) * 100
+	principal := float64(loan.PrincipalAmount.Amount)
+	if f.MinFundedPercent != nil && invested < *f.MinFundedPercent*principal {
+		return false
+	}
+
+	if f.MaxFundedPercent != nil && invested > *f.MaxFundedPercent*principal {
+		return false
+	}
+
+	return true
+}
+
+// Validate rejects bounds that no loan could satisfy because they are
+// inconsistent
+func (f LoanFilter) Validate() error {
+	if f.MinPrincipal != nil && f.MaxPrincipal != nil {
+		cmp, err := f.MinPrincipal.Cmp(*f.MaxPrincipal)
+		if err != nil {
+			return ValidationError("principal bounds: %v", err)
+		}
+		if cmp > 0 {
+			return ValidationError("minimum principal cannot exceed maximum principal")
+		}
+	}
+
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && !f.CreatedFrom.Before(f.CreatedTo) {
+		return ValidationError("created from must be before created to")
+	}
+
+	for _, percent := range []*float64{f.MinFundedPercent, f.MaxFundedPercent} {
+		// NaN fails every comparison, so it is rejected before the range check
+		if percent != nil && (math.IsNaN(*percent) || *percent < 0 || *percent > 100) {
+			return ValidationError("funded percentage must be between 0 and 100")
+		}
+	}
+	if f.MinFundedPercent != nil && f.MaxFundedPercent != nil && *f.MinFundedPercent > *f.MaxFundedPercent {
+		return ValidationError("minimum funded percentage cannot exceed maximum funded percentage")
+	}
+
+	return nil
+}
+
+// LoanSortField names the value a loan listing is ordered by
+type LoanSortField string
+
+const (
+	LoanSortCreatedAt       LoanSortField = "created_at"
+	LoanSortPrincipalAmount LoanSortField = "principal_amount"
+	LoanSortFundedPercent   LoanSortField = "funded_percent"
+)
+
+// LoanSort orders a loan listing. The zero value lists the oldest loans
+// first. Ties are broken by creation time, then ID, in the same direction,
+// so that pages are stable.
+type LoanSort struct {
+	Field      LoanSortField
+	Descending bool
+}
+
+// ParseLoanSort reads a sort field with an optional "-" prefix for
+// descending order, e.g. "-principal_amount". An empty string is the
+// default order.
+func ParseLoanSort(s string) (LoanSort, error) {
+	var sort LoanSort
+	if strings.HasPrefix(s, "-") {
+		sort.Descending = true
+		s = s[1:]
+	}
+
+	switch field := LoanSortField(s); field {
+	case "", LoanSortCreatedAt, LoanSortPrincipalAmount, LoanSortFundedPercent:
+		sort.Field = field
+		return sort, nil
+	default:
+		return LoanSort{}, ValidationError("unknown sort field %q", s)
+	}
+}
+
+// Less reports whether a is listed before b. Principal amounts are ordered
+// by their minor units, whatever their currency.
+func (s LoanSort) Less(a, b *Loan) bool {
+	return s.before(s.positionOf(a), s.positionOf(b))
+}
+
+// positionOf returns the values that place loan in the order
+func (s LoanSort) positionOf(loan *Loan) *LoanCursor {
+	position := &LoanCursor{Sort: s, CreatedAt: loan.CreatedAt, ID: loan.ID}
+	switch s.Field {
+	case LoanSortPrincipalAmount:
+		position.PrincipalMinor = loan.PrincipalAmount.Amount
+	case LoanSortFundedPercent:
+		position.InvestedMinor = loan.TotalInvestedAmount().Amount
+		position.PrincipalMinor = loan.PrincipalAmount.Amount
+	}
+	return position
+}
+
+func (s LoanSort) before(a, b *LoanCursor) bool {
+	if s.Descending {
+		a, b = b, a
+	}
+
+	switch s.Field {
+	case LoanSortPrincipalAmount:
+		if a.PrincipalMinor != b.PrincipalMinor {
+			return a.PrincipalMinor < b.PrincipalMinor
+		}
+	case LoanSortFundedPercent:
+		if cmp := compareShares(a.InvestedMinor, a.PrincipalMinor, b.InvestedMinor, b.PrincipalMinor); cmp != 0 {
+			return cmp < 0
+		}
+	}
+
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// LoanCursor marks the last loan of a page by its position in the listing
+// order. The next page starts after that position, so loans created or
+// removed in between never shift it. Clients receive cursors as opaque
+// strings.
+type LoanCursor struct {
+	Sort           LoanSort
+	PrincipalMinor int64 // set when sorting by principal or funding
+	InvestedMinor  int64 // set when sorting by funding, as of the page it ends
+	CreatedAt      time.Time
+	ID             string
+}
+
+// NewLoanCursor returns the cursor after last in the given order
+func NewLoanCursor(order LoanSort, last *Loan) *LoanCursor {
+	return order.positionOf(last)
+}
+
+// Admits reports whether loan is listed after the cursor
+func (c *LoanCursor) Admits(loan *Loan) bool {
+	return c.Sort.before(c, c.Sort.positionOf(loan))
+}
+
+type loanCursorJSON struct {
+	Field          LoanSortField `json:"s,omitempty"`
+	Descending     bool          `json:"d,omitempty"`
+	PrincipalMinor int64         `json:"p,omitempty"`
+	InvestedMinor  int64         `json:"n,omitempty"`
+	CreatedAt      time.Time     `json:"c"`
+	ID             string        `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *LoanCursor) Encode() string {
+	b, _ := json.Marshal(loanCursorJSON{
+		Field:          c.Sort.Field,
+		Descending:     c.Sort.Descending,
+		PrincipalMinor: c.PrincipalMinor,
+		InvestedMinor:  c.InvestedMinor,
+		CreatedAt:      c.CreatedAt.UTC(),
+		ID:             c.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeLoanCursor parses a cursor made by Encode. A cursor continues the
+// listing it came from, so it is rejected if order has changed since.
+func DecodeLoanCursor(s string, order LoanSort) (*LoanCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ValidationError("invalid cursor")
+	}
+
+	var wire loanCursorJSON
+	if err := json.Unmarshal(b, &wire); err != nil || wire.ID == "" {
+		return nil, ValidationError("invalid cursor")
+	}
+
+	// The default order and an explicit created_at sort are the same
+	cursorSort := LoanSort{Field: wire.Field, Descending: wire.Descending}
+	if cursorSort.normalized() != order.normalized() {
+		return nil, ValidationError("cursor belongs to a listing with a different sort")
+	}
+	if order.Field == LoanSortFundedPercent && wire.PrincipalMinor <= 0 {
+		return nil, ValidationError("invalid cursor")
+	}
+
+	return &LoanCursor{
+		Sort:           order,
+		PrincipalMinor: wire.PrincipalMinor,
+		InvestedMinor:  wire.InvestedMinor,
+		CreatedAt:      wire.CreatedAt,
+		ID:             wire.ID,
+	}, nil
+}
+
+// compareShares compares the funded shares aInvested/aPrincipal and
+// bInvested/bPrincipal by cross-multiplying, as the SQL backend does, so
+// that equal shares compare equal whatever their principals. It returns -1,
+// 0 or +1.
+func compareShares(aInvested, aPrincipal, bInvested, bPrincipal int64) int {
+	left := new(big.Int).Mul(big.NewInt(aInvested), big.NewInt(bPrincipal))
+	right := new(big.Int).Mul(big.NewInt(bInvested), big.NewInt(aPrincipal))
+	return left.Cmp(right)
+}
+
+func (s LoanSort) normalized() LoanSort {
+	if s.Field == "" {
+		s.Field = LoanSortCreatedAt
+	}
+	return s
+}
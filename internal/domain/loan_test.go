@@ -0,0 +1,276 @@
+package domain_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"loan/internal/domain"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoanInvestedWithFractionalAmounts(t *testing.T) {
+	principal, _ := domain.ParseMoney("0.30", "USD")
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	loan.State = domain.LoanStateApproved
+
+	for _, amount := range []string{"0.10", "0.20"} {
+		money, _ := domain.ParseMoney(amount, "USD")
+		investment, _ := domain.NewInvestment(loan.ID, "investor123", money)
+		if err := loan.AddInvestment(investment); err != nil {
+			t.Fatalf("Expected no error adding %s, got %v", amount, err)
+		}
+	}
+
+	if loan.State != domain.LoanStateInvested {
+		t.Errorf("Expected 0.10 + 0.20 to fully fund 0.30, got state %s", loan.State)
+	}
+}
+
+func TestLoanRejectsInvestmentInOtherCurrency(t *testing.T) {
+	principal, _ := domain.ParseMoney("1000", "IDR")
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	loan.State = domain.LoanStateApproved
+
+	usd, _ := domain.ParseMoney("100", "USD")
+	if err := loan.CanAddInvestment(usd); err == nil {
+		t.Error("Expected USD investment in an IDR loan to be rejected, got nil")
+	}
+}
+
+func TestLoanTransitionsRaiseEvents(t *testing.T) {
+	// Arrange
+	principal, _ := domain.ParseMoney("1000", "IDR")
+	half, _ := domain.ParseMoney("500", "IDR")
+	now := time.Now()
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", now)
+	first, _ := domain.NewInvestment(loan.ID, "investor1", half)
+	second, _ := domain.NewInvestment(loan.ID, "investor2", half)
+	disbursement, _ := domain.NewDisbursement(loan.ID, "agreement.pdf", "officer123", now)
+
+	// Act
+	_ = loan.Approve(approval)
+	_ = loan.AddInvestment(first)
+	_ = loan.AddInvestment(second)
+	loan.AttachAgreementLetter("https://example.com/letter.pdf")
+	_ = loan.Disburse(disbursement)
+
+	// Assert
+	want := []domain.LoanEventType{
+		domain.LoanEventCreated,
+		domain.LoanEventApproved,
+		domain.LoanEventInvestmentAdded,
+		domain.LoanEventInvestmentAdded,
+		domain.LoanEventInvested,
+		domain.LoanEventAgreementLetterAttached,
+		domain.LoanEventDisbursed,
+	}
+	events := loan.PendingEvents()
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Type != want[i] || event.LoanID != loan.ID {
+			t.Errorf("Expected event %d to be %s for %s, got %s for %s", i, want[i], loan.ID, event.Type, event.LoanID)
+		}
+	}
+
+	loan.ClearPendingEvents()
+	if len(loan.PendingEvents()) != 0 {
+		t.Error("Expected no pending events after clearing")
+	}
+}
+
+func TestReplayLoanRebuildsState(t *testing.T) {
+	// Arrange
+	principal, _ := domain.ParseMoney("1000", "IDR")
+	now := time.Now()
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", now)
+	investment, _ := domain.NewInvestment(loan.ID, "investor1", principal)
+	_ = loan.Approve(approval)
+	_ = loan.AddInvestment(investment)
+
+	// events are replayed as they come back from storage
+	var history []*domain.LoanEvent
+	for i, event := range loan.PendingEvents() {
+		event.Sequence = i + 1
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("Expected event to encode, got %v", err)
+		}
+		var decoded domain.LoanEvent
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("Expected event to decode, got %v", err)
+		}
+		history = append(history, &decoded)
+	}
+
+	// Act
+	rebuilt, err := domain.ReplayLoan(history)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error replaying loan, got %v", err)
+	}
+	if rebuilt.ID != loan.ID || rebuilt.State != domain.LoanStateInvested || rebuilt.BorrowerID != "borrower123" || rebuilt.PrincipalAmount != principal {
+		t.Errorf("Expected replayed loan to match, got %+v", rebuilt)
+	}
+	if rebuilt.Approval == nil || rebuilt.Approval.ProofPictureURL != "proof.jpg" {
+		t.Errorf("Expected replayed approval, got %+v", rebuilt.Approval)
+	}
+	if len(rebuilt.Investments) != 1 || rebuilt.Investments[0].ID != investment.ID {
+		t.Errorf("Expected replayed investment %s, got %+v", investment.ID, rebuilt.Investments)
+	}
+	if !rebuilt.CreatedAt.Equal(loan.CreatedAt) || !rebuilt.UpdatedAt.Equal(loan.UpdatedAt) {
+		t.Errorf("Expected replayed timestamps %v/%v, got %v/%v", loan.CreatedAt, loan.UpdatedAt, rebuilt.CreatedAt, rebuilt.UpdatedAt)
+	}
+	if len(rebuilt.PendingEvents()) != 0 {
+		t.Error("Expected replayed history not to be pending")
+	}
+}
+
+func TestReplayLoanRejectsBrokenHistory(t *testing.T) {
+	principal, _ := domain.ParseMoney("1000", "IDR")
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", time.Now())
+	_ = loan.Approve(approval)
+	created, approved := loan.PendingEvents()[0], loan.PendingEvents()[1]
+	created.Sequence, approved.Sequence = 1, 2
+
+	for name, history := range map[string][]*domain.LoanEvent{
+		"empty":           nil,
+		"missing created": {approved},
+		"out of sequence": {created, {ID: "x", LoanID: loan.ID, Sequence: 3, Type: domain.LoanEventApproved, Data: approved.Data}},
+		"other loan":      {created, {ID: "x", LoanID: "loan_other", Sequence: 2, Type: domain.LoanEventApproved, Data: approved.Data}},
+	} {
+		if _, err := domain.ReplayLoan(history); err == nil {
+			t.Errorf("Expected error replaying %s history, got nil", name)
+		}
+	}
+}
+
+func TestParseLoanSort(t *testing.T) {
+	tests := map[string]struct {
+		want    domain.LoanSort
+		wantErr bool
+	}{
+		"":                  {domain.LoanSort{}, false},
+		"created_at":        {domain.LoanSort{Field: domain.LoanSortCreatedAt}, false},
+		"-principal_amount": {domain.LoanSort{Field: domain.LoanSortPrincipalAmount, Descending: true}, false},
+		"-funded_percent":   {domain.LoanSort{Field: domain.LoanSortFundedPercent, Descending: true}, false},
+		"rate":              {domain.LoanSort{}, true},
+	}
+
+	for input, tt := range tests {
+		got, err := domain.ParseLoanSort(input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLoanSort(%q): expected %+v (error %v), got %+v (%v)", input, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestLoanFilterValidate(t *testing.T) {
+	small, _ := domain.ParseMoney("100", "IDR")
+	large, _ := domain.ParseMoney("1000", "IDR")
+	dollars, _ := domain.ParseMoney("500", "USD")
+	now := time.Now()
+	low, high, over, notANumber := 10.0, 90.0, 150.0, math.NaN()
+
+	tests := map[string]struct {
+		filter  domain.LoanFilter
+		wantErr bool
+	}{
+		"empty":                  {domain.LoanFilter{}, false},
+		"principal range":        {domain.LoanFilter{MinPrincipal: &small, MaxPrincipal: &large}, false},
+		"inverted principal":     {domain.LoanFilter{MinPrincipal: &large, MaxPrincipal: &small}, true},
+		"mixed currencies":       {domain.LoanFilter{MinPrincipal: &small, MaxPrincipal: &dollars}, true},
+		"inverted created range": {domain.LoanFilter{CreatedFrom: now, CreatedTo: now.Add(-time.Hour)}, true},
+		"funded range":           {domain.LoanFilter{MinFundedPercent: &low, MaxFundedPercent: &high}, false},
+		"inverted funded range":  {domain.LoanFilter{MinFundedPercent: &high, MaxFundedPercent: &low}, true},
+		"funded above 100":       {domain.LoanFilter{MaxFundedPercent: &over}, true},
+		"funded NaN":             {domain.LoanFilter{MinFundedPercent: &notANumber}, true},
+	}
+
+	for name, tt := range tests {
+		if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoanCursor(t *testing.T) {
+	principal, _ := domain.ParseMoney("1000", "IDR")
+	loan := domain.NewLoan("borrower123", principal, 0.1, 0.08)
+	largestFirst := domain.LoanSort{Field: domain.LoanSortPrincipalAmount, Descending: true}
+
+	cursor, err := domain.DecodeLoanCursor(domain.NewLoanCursor(largestFirst, loan).Encode(), largestFirst)
+	if err != nil {
+		t.Fatalf("Expected the cursor to decode, got %v", err)
+	}
+	if cursor.ID != loan.ID || cursor.PrincipalMinor != loan.PrincipalAmount.Amount || !cursor.CreatedAt.Equal(loan.CreatedAt) {
+		t.Errorf("Expected the cursor to mark loan %s, got %+v", loan.ID, cursor)
+	}
+
+	smaller, _ := domain.ParseMoney("999", "IDR")
+	if !cursor.Admits(domain.NewLoan("borrower123", smaller, 0.1, 0.08)) || cursor.Admits(loan) {
+		t.Error("Expected only smaller loans after a largest-first cursor")
+	}
+
+	if _, err := domain.DecodeLoanCursor(domain.NewLoanCursor(domain.LoanSort{}, loan).Encode(), domain.LoanSort{Field: domain.LoanSortCreatedAt}); err != nil {
+		t.Errorf("Expected the default order to accept a created_at cursor, got %v", err)
+	}
+
+	for name, encoded := range map[string]string{
+		"other sort":                 domain.NewLoanCursor(domain.LoanSort{}, loan).Encode(),
+		"not base64":                 "!!!",
+		"not JSON":                   "bm90IGpzb24",
+		"funded without a principal": base64.RawURLEncoding.EncodeToString([]byte(`{"s":"funded_percent","c":"2024-01-01T00:00:00Z","i":"x"}`)),
+	} {
+		order := largestFirst
+		if strings.HasPrefix(name, "funded") {
+			order = domain.LoanSort{Field: domain.LoanSortFundedPercent}
+		}
+		if _, err := domain.DecodeLoanCursor(encoded, order); err == nil {
+			t.Errorf("Expected %s cursor to be rejected", name)
+		}
+	}
+}
+
+func TestLoanCursorComparesFundedSharesExactly(t *testing.T) {
+	// Arrange: a third of 750 and a third of 1500 are the same share
+	mostFunded := domain.LoanSort{Field: domain.LoanSortFundedPercent, Descending: true}
+	third := fundedLoan(t, "750", "250")
+	sameShare := fundedLoan(t, "1500", "500")
+	sameShare.CreatedAt = third.CreatedAt.Add(-time.Second) // ties list newest first
+	slightlyLess := fundedLoan(t, "1500", "499")
+
+	// Act
+	cursor, err := domain.DecodeLoanCursor(domain.NewLoanCursor(mostFunded, third).Encode(), mostFunded)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected the cursor to decode, got %v", err)
+	}
+	if cursor.InvestedMinor != 25000 || cursor.PrincipalMinor != 75000 {
+		t.Errorf("Expected the cursor to carry the invested and principal amounts, got %+v", cursor)
+	}
+	if !cursor.Admits(sameShare) || !cursor.Admits(slightlyLess) || cursor.Admits(third) {
+		t.Error("Expected only older loans with the same share, and less funded loans, after the cursor")
+	}
+}
+
+func fundedLoan(t *testing.T, principal, invested string) *domain.Loan {
+	t.Helper()
+	principalAmount, _ := domain.ParseMoney(principal, "IDR")
+	loan := domain.NewLoan("borrower123", principalAmount, 0.1, 0.08)
+	amount, _ := domain.ParseMoney(invested, "IDR")
+	investment, err := domain.NewInvestment(loan.ID, "investor123", amount)
+	if err != nil {
+		t.Fatalf("Expected a valid investment, got %v", err)
+	}
+	loan.Investments = append(loan.Investments, investment)
+	return loan
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currencyExponents lists the supported ISO-4217 currencies and the number
+// of minor-unit digits each one allows
+var currencyExponents = map[string]int{
+	"AUD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"IDR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MYR": 2,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// IsSupportedCurrency reports whether amounts can be held in currency
+func IsSupportedCurrency(currency string) bool {
+	_, ok := currencyExponents[currency]
+	return ok
+}
+
+// Money is an exact monetary amount stored as an integer number of the
+// currency's minor units (e.g. cents), so sums never drift the way float64 does.
+type Money struct {
+	Amount   int64  // minor units
+	Currency string // ISO-4217 code
+}
+
+// NewMoney builds a Money from an amount already expressed in minor units
+func NewMoney(minorUnits int64, currency string) (Money, error) {
+	if _, ok := currencyExponents[currency]; !ok {
+		return Money{}, ValidationError("unsupported currency %q", currency)
+	}
+
+	return Money{Amount: minorUnits, Currency: currency}, nil
+}
+
+// ZeroMoney returns a zero amount in the given currency
+func ZeroMoney(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// ParseMoney parses a plain decimal string such as "1000.50" in the given
+// currency. Signs other than a leading minus, exponents, separators and more
+// decimal places than the currency allows are rejected.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, ValidationError("unsupported currency %q", currency)
+	}
+
+	digits := amount
+	negative := strings.HasPrefix(digits, "-")
+	if negative {
+		digits = digits[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return Money{}, ValidationError("invalid amount %q: must be a decimal number", amount)
+	}
+
+	if len(fraction) > exponent {
+		return Money{}, ValidationError("invalid amount %q: %s allows at most %d decimal places", amount, currency, exponent)
+	}
+
+	fraction += strings.Repeat("0", exponent-len(fraction))
+	minorUnits, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ValidationError("invalid amount %q: out of range", amount)
+	}
+
+	if negative {
+		minorUnits = -minorUnits
+	}
+
+	return Money{Amount: minorUnits, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns m + other. Both amounts must share a currency.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ValidationError("amount overflow")
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both amounts must share a currency.
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than other.
+// Both amounts must share a currency.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return ValidationError("currency mismatch: %s and %s", m.Currency, other.Currency)
+	}
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Decimal formats the amount as a decimal string with exactly as many
+// decimal places as the currency uses, e.g. "1000.50"
+func (m Money) Decimal() string {
+	exponent := currencyExponents[m.Currency]
+
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-m.Amount)
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount": "1000.50", "currency": "IDR"}; the
+// amount is a string so no JSON client rounds it through a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.New("money must be an object with string amount and currency")
+	}
+
+	parsed, err := ParseMoney(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
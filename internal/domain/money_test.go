@@ -0,0 +1,111 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"loan/internal/domain"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{amount: "1000", currency: "IDR", want: 100000},
+		{amount: "1000.5", currency: "IDR", want: 100050},
+		{amount: "0.01", currency: "USD", want: 1},
+		{amount: "-12.34", currency: "USD", want: -1234},
+		{amount: "500", currency: "JPY", want: 500},
+		{amount: "1.234", currency: "KWD", want: 1234},
+		{amount: "0.001", currency: "USD", wantErr: true},
+		{amount: "10.000", currency: "IDR", wantErr: true},
+		{amount: "1.5", currency: "JPY", wantErr: true},
+		{amount: "1e3", currency: "USD", wantErr: true},
+		{amount: "+1", currency: "USD", wantErr: true},
+		{amount: "1,000", currency: "USD", wantErr: true},
+		{amount: " 1", currency: "USD", wantErr: true},
+		{amount: "1.", currency: "USD", wantErr: true},
+		{amount: ".5", currency: "USD", wantErr: true},
+		{amount: "", currency: "USD", wantErr: true},
+		{amount: "99999999999999999999", currency: "USD", wantErr: true},
+		{amount: "1", currency: "XXX", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := domain.ParseMoney(tt.amount, tt.currency)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q, %s): expected error, got %v", tt.amount, tt.currency, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s): expected no error, got %v", tt.amount, tt.currency, err)
+			continue
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("ParseMoney(%q, %s): expected %d, got %d %s", tt.amount, tt.currency, tt.want, got.Amount, got.Currency)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money domain.Money
+		want  string
+	}{
+		{domain.Money{Amount: 100050, Currency: "IDR"}, "1000.50"},
+		{domain.Money{Amount: 5, Currency: "USD"}, "0.05"},
+		{domain.Money{Amount: -5, Currency: "USD"}, "-0.05"},
+		{domain.Money{Amount: 0, Currency: "USD"}, "0.00"},
+		{domain.Money{Amount: 1234, Currency: "KWD"}, "1.234"},
+		{domain.Money{Amount: 500, Currency: "JPY"}, "500"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("Expected %d %s to format as %s, got %s", tt.money.Amount, tt.money.Currency, tt.want, got)
+		}
+	}
+}
+
+func TestMoneyArithmeticRequiresSameCurrency(t *testing.T) {
+	idr, _ := domain.ParseMoney("1", "IDR")
+	usd, _ := domain.ParseMoney("1", "USD")
+
+	if _, err := idr.Add(usd); err == nil {
+		t.Error("Expected error adding different currencies, got nil")
+	}
+	if _, err := idr.Cmp(usd); err == nil {
+		t.Error("Expected error comparing different currencies, got nil")
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	money, _ := domain.ParseMoney("1000.50", "IDR")
+
+	data, err := json.Marshal(money)
+	if err != nil {
+		t.Fatalf("Expected no error marshalling money, got %v", err)
+	}
+	if string(data) != `{"amount":"1000.50","currency":"IDR"}` {
+		t.Errorf("Unexpected money JSON %s", data)
+	}
+
+	var decoded domain.Money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error unmarshalling money, got %v", err)
+	}
+	if decoded != money {
+		t.Errorf("Expected %s after round trip, got %s", money, decoded)
+	}
+
+	for _, bad := range []string{`{"amount":1000.5,"currency":"IDR"}`, `{"amount":"1000.505","currency":"IDR"}`, `"1000"`} {
+		if err := json.Unmarshal([]byte(bad), &decoded); err == nil {
+			t.Errorf("Expected error unmarshalling %s, got nil", bad)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NotificationEvent is a step in the loan lifecycle that people are told
+// about
+type NotificationEvent string
+
+const (
+	NotificationLoanApproved       NotificationEvent = "LOAN_APPROVED"
+	NotificationInvestmentReceived NotificationEvent = "INVESTMENT_RECEIVED"
+	NotificationLoanInvested       NotificationEvent = "LOAN_INVESTED"
+	NotificationLoanDisbursed      NotificationEvent = "LOAN_DISBURSED"
+	NotificationRepaymentDue       NotificationEvent = "REPAYMENT_DUE"
+)
+
+// NotificationEvents lists every event, e.g. for loading a template per event
+var NotificationEvents = []NotificationEvent{
+	NotificationLoanApproved,
+	NotificationInvestmentReceived,
+	NotificationLoanInvested,
+	NotificationLoanDisbursed,
+	NotificationRepaymentDue,
+}
+
+type RecipientRole string
+
+const (
+	RecipientBorrower RecipientRole = "BORROWER"
+	RecipientInvestor RecipientRole = "INVESTOR"
+	RecipientStaff    RecipientRole = "STAFF"
+)
+
+func ParseRecipientRole(s string) (RecipientRole, error) {
+	switch role := RecipientRole(s); role {
+	case RecipientBorrower, RecipientInvestor, RecipientStaff:
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown recipient role %q", s)
+	}
+}
+
+// NotificationChannel is a medium a notification can be delivered over
+type NotificationChannel string
+
+const (
+	ChannelEmail   NotificationChannel = "EMAIL"
+	ChannelSMS     NotificationChannel = "SMS"
+	ChannelWebhook NotificationChannel = "WEBHOOK"
+)
+
+func ParseNotificationChannel(s string) (NotificationChannel, error) {
+	switch channel := NotificationChannel(s); channel {
+	case ChannelEmail, ChannelSMS, ChannelWebhook:
+		return channel, nil
+	default:
+		return "", fmt.Errorf("unknown notification channel %q", s)
+	}
+}
+
+// Notification tells a recipient about a lifecycle event on a loan. Which
+// of the optional fields are set depends on the event.
+type Notification struct {
+	Event              NotificationEvent `json:"event"`
+	RecipientRole      RecipientRole     `json:"recipient_role"`
+	RecipientID        string            `json:"recipient_id,omitempty"` // empty addresses every contact with the role
+	LoanID             string            `json:"loan_id"`
+	Amount             *Money            `json:"amount,omitempty"` // principal, investment or installment, by event
+	AgreementLetterURL string            `json:"agreement_letter_url,omitempty"`
+	InstallmentNumber  int               `json:"installment_number,omitempty"`
+	DueDate            *time.Time        `json:"due_date,omitempty"`
+	OccurredAt         time.Time         `json:"occurred_at"`
+}
+
+func NewNotification(event NotificationEvent, role RecipientRole, recipientID, loanID string) *Notification {
+	return &Notification{
+		Event:         event,
+		RecipientRole: role,
+		RecipientID:   recipientID,
+		LoanID:        loanID,
+		OccurredAt:    time.Now(),
+	}
+}
+
+// WithAmount sets the amount the notification is about and returns it
+func (n *Notification) WithAmount(amount Money) *Notification {
+	n.Amount = &amount
+	return n
+}
+
+// Contact is how to reach a borrower, investor or member of staff, and over
+// which channels they prefer to be notified
+type Contact struct {
+	ID         string                `json:"id"`
+	Role       RecipientRole         `json:"role"`
+	Name       string                `json:"name"`
+	Email      string                `json:"email,omitempty"`
+	Phone      string                `json:"phone,omitempty"`
+	WebhookURL string                `json:"webhook_url,omitempty"`
+	Channels   []NotificationChannel `json:"channels,omitempty"` // EMAIL when empty
+}
+
+// PreferredChannels returns the channels to notify the contact on
+func (c *Contact) PreferredChannels() []NotificationChannel {
+	if len(c.Channels) == 0 {
+		return []NotificationChannel{ChannelEmail}
+	}
+	return c.Channels
+}
+
+// Validate checks that the contact can be reached on every channel it
+// prefers
+func (c *Contact) Validate() error {
+	if c.ID == "" {
+		return errors.New("contact ID cannot be empty")
+	}
+
+	if _, err := ParseRecipientRole(string(c.Role)); err != nil {
+		return err
+	}
+
+	for _, channel := range c.PreferredChannels() {
+		if _, err := ParseNotificationChannel(string(channel)); err != nil {
+			return err
+		}
+
+		switch {
+		case channel == ChannelEmail && c.Email == "":
+			return fmt.Errorf("contact %s prefers EMAIL but has no email address", c.ID)
+		case channel == ChannelSMS && c.Phone == "":
+			return fmt.Errorf("contact %s prefers SMS but has no phone number", c.ID)
+		case channel == ChannelWebhook && c.WebhookURL == "":
+			return fmt.Errorf("contact %s prefers WEBHOOK but has no webhook URL", c.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loan/util"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "PENDING"
+	OutboxStatusDelivered OutboxStatus = "DELIVERED"
+	OutboxStatusDead      OutboxStatus = "DEAD" // gave up after too many failed attempts
+)
+
+// ParseOutboxStatus validates a status name received from a client
+func ParseOutboxStatus(s string) (OutboxStatus, error) {
+	switch status := OutboxStatus(s); status {
+	case OutboxStatusPending, OutboxStatusDelivered, OutboxStatusDead:
+		return status, nil
+	default:
+		return "", ValidationError("unknown outbox status %q", s)
+	}
+}
+
+const (
+	TopicNotification = "notification" // payload is a Notification
+	TopicWebhook      = "webhook"      // payload is a WebhookDispatch
+)
+
+// WebhookDispatch is an event due to be delivered to one subscription
+type WebhookDispatch struct {
+	SubscriptionID string        `json:"subscription_id"`
+	Event          *WebhookEvent `json:"event"`
+}
+
+// OutboxMessage is a side effect, such as a notification, recorded in the
+// same unit of work as the state change that caused it and delivered
+// afterwards by a dispatcher, so it is neither lost when delivery fails nor
+// sent for a change that was rolled back.
+type OutboxMessage struct {
+	ID            string          `json:"id"`
+	Topic         string          `json:"topic"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        OutboxStatus    `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func NewOutboxMessage(topic string, payload interface{}) (*OutboxMessage, error) {
+	if topic == "" {
+		return nil, errors.New("topic cannot be empty")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", topic, err)
+	}
+
+	now := time.Now()
+	return &OutboxMessage{
+		ID:            "msg_" + util.GenerateUUID(),
+		Topic:         topic,
+		Payload:       data,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// MarkDelivered records a successful delivery
+func (m *OutboxMessage) MarkDelivered(at time.Time) {
+	m.Attempts++
+	m.Status = OutboxStatusDelivered
+	m.LastError = ""
+	m.DeliveredAt = &at
+	m.UpdatedAt = at
+}
+
+// MarkFailed records a failed delivery. The message is retried at retryAt,
+// or marked DEAD when retryAt is nil.
+func (m *OutboxMessage) MarkFailed(cause error, at time.Time, retryAt *time.Time) {
+	m.Attempts++
+	m.LastError = cause.Error()
+	m.UpdatedAt = at
+
+	if retryAt == nil {
+		m.Status = OutboxStatusDead
+		return
+	}
+	m.NextAttemptAt = *retryAt
+}
+
+// Replay returns a DEAD message to PENDING for immediate redelivery with a
+// fresh set of attempts
+func (m *OutboxMessage) Replay(at time.Time) error {
+	if m.Status != OutboxStatusDead {
+		return InvalidStateError("only DEAD messages can be replayed")
+	}
+
+	m.Status = OutboxStatusPending
+	m.Attempts = 0
+	m.NextAttemptAt = at
+	m.UpdatedAt = at
+	return nil
+}
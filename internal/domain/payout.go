@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"errors"
+	"loan/util"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Payout is a ledger entry crediting an investor with their share of a
+// borrower repayment
+type Payout struct {
+	ID           string    `json:"id"`
+	LoanID       string    `json:"loan_id"`
+	RepaymentID  string    `json:"repayment_id"`
+	InvestmentID string    `json:"investment_id"`
+	InvestorID   string    `json:"investor_id"`
+	Amount       Money     `json:"amount"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PayoutDistribution splits one repayment between the investors, the platform
+// and any overpayment owed back to the borrower. The three parts always sum
+// to the repayment amount, and the payouts always sum to InvestorShare.
+type PayoutDistribution struct {
+	RepaymentID   string    `json:"repayment_id"`
+	Repayment     Money     `json:"repayment"`
+	InvestorShare Money     `json:"investor_share"`
+	PlatformShare Money     `json:"platform_share"`
+	Unapplied     Money     `json:"unapplied"` // overpayment, not distributed
+	Payouts       []*Payout `json:"payouts"`
+}
+
+// InvestorEntitlement is what the loan's investors are owed over its life:
+// the amount invested plus ROI on it, capped at what the borrower repays.
+func (l *Loan) InvestorEntitlement(totalDue Money) Money {
+	invested := l.TotalInvestedAmount()
+	entitlement := int64(math.Round(float64(invested.Amount) * (1 + l.ROI)))
+	if entitlement > totalDue.Amount {
+		entitlement = totalDue.Amount
+	}
+	return Money{Amount: entitlement, Currency: invested.Currency}
+}
+
+// DistributeRepayment allocates repayment across the loan's active
+// investments. paidBefore is the sum of the loan's earlier repayments and
+// totalDue the schedule total.
+//
+// The investors' share of everything repaid so far is the applied amount
+// scaled by entitlement/totalDue, rounded down; this repayment's share is the
+// growth of that figure, so rounding never accumulates and investors receive
+// exactly their entitlement once the loan is repaid. The share is split
+// pro-rata by investment amount using the largest-remainder method: each
+// investment gets its rounded-down share, then leftover minor units go one at
+// a time to the largest fractional remainders, ties broken by investment ID.
+func DistributeRepayment(loan *Loan, totalDue, paidBefore Money, repayment *Repayment) (*PayoutDistribution, error) {
+	currency := loan.Currency()
+	if totalDue.Currency != currency || paidBefore.Currency != currency || repayment.Amount.Currency != currency {
+		return nil, ValidationError("repayment, schedule and loan currencies must match")
+	}
+
+	if !totalDue.IsPositive() {
+		return nil, errors.New("schedule total must be greater than zero")
+	}
+
+	appliedBefore := minInt64(paidBefore.Amount, totalDue.Amount)
+	appliedAfter := minInt64(paidBefore.Amount+repayment.Amount.Amount, totalDue.Amount)
+
+	entitlement := loan.InvestorEntitlement(totalDue).Amount
+	sharedBefore, _ := mulDiv(appliedBefore, entitlement, totalDue.Amount)
+	sharedAfter, _ := mulDiv(appliedAfter, entitlement, totalDue.Amount)
+	investorShare := sharedAfter - sharedBefore
+
+	distribution := &PayoutDistribution{
+		RepaymentID:   repayment.ID,
+		Repayment:     repayment.Amount,
+		InvestorShare: Money{Amount: investorShare, Currency: currency},
+		PlatformShare: Money{Amount: appliedAfter - appliedBefore - investorShare, Currency: currency},
+		Unapplied:     Money{Amount: repayment.Amount.Amount - (appliedAfter - appliedBefore), Currency: currency},
+		Payouts:       []*Payout{},
+	}
+
+	var active []*Investment
+	for _, investment := range loan.Investments {
+		if investment.Status == InvestmentStatusActive {
+			active = append(active, investment)
+		}
+	}
+	totalInvested := loan.TotalInvestedAmount().Amount
+	if investorShare == 0 || totalInvested == 0 {
+		return distribution, nil
+	}
+
+	type share struct {
+		investment *Investment
+		amount     int64
+		remainder  int64
+	}
+	shares := make([]*share, len(active))
+	allocated := int64(0)
+	for i, investment := range active {
+		amount, remainder := mulDiv(investorShare, investment.Amount.Amount, totalInvested)
+		shares[i] = &share{investment: investment, amount: amount, remainder: remainder}
+		allocated += amount
+	}
+
+	byRemainder := make([]*share, len(shares))
+	copy(byRemainder, shares)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		if byRemainder[i].remainder != byRemainder[j].remainder {
+			return byRemainder[i].remainder > byRemainder[j].remainder
+		}
+		return byRemainder[i].investment.ID < byRemainder[j].investment.ID
+	})
+	for i := int64(0); i < investorShare-allocated; i++ {
+		byRemainder[i].amount++
+	}
+
+	now := time.Now()
+	for _, s := range shares {
+		if s.amount == 0 {
+			continue
+		}
+		distribution.Payouts = append(distribution.Payouts, &Payout{
+			ID:           "pay_" + util.GenerateUUID(),
+			LoanID:       loan.ID,
+			RepaymentID:  repayment.ID,
+			InvestmentID: s.investment.ID,
+			InvestorID:   s.investment.InvestorID,
+			Amount:       Money{Amount: s.amount, Currency: currency},
+			CreatedAt:    now,
+		})
+	}
+
+	return distribution, nil
+}
+
+// InvestorPayoutTotal is the sum of payouts credited to one investor
+type InvestorPayoutTotal struct {
+	InvestorID string `json:"investor_id"`
+	Total      Money  `json:"total"`
+}
+
+// PayoutLedger lists a loan's payout entries with per-investor totals
+type PayoutLedger struct {
+	LoanID         string                 `json:"loan_id"`
+	TotalPaidOut   Money                  `json:"total_paid_out"`
+	InvestorTotals []*InvestorPayoutTotal `json:"investor_totals"`
+	Entries        []*Payout              `json:"entries"`
+}
+
+// NewPayoutLedger totals entries per investor, ordered by investor ID
+func NewPayoutLedger(loan *Loan, entries []*Payout) *PayoutLedger {
+	ledger := &PayoutLedger{
+		LoanID:         loan.ID,
+		TotalPaidOut:   ZeroMoney(loan.Currency()),
+		InvestorTotals: []*InvestorPayoutTotal{},
+		Entries:        entries,
+	}
+
+	byInvestor := make(map[string]*InvestorPayoutTotal)
+	for _, entry := range entries {
+		total, exists := byInvestor[entry.InvestorID]
+		if !exists {
+			total = &InvestorPayoutTotal{InvestorID: entry.InvestorID, Total: ZeroMoney(loan.Currency())}
+			byInvestor[entry.InvestorID] = total
+			ledger.InvestorTotals = append(ledger.InvestorTotals, total)
+		}
+		total.Total.Amount += entry.Amount.Amount
+		ledger.TotalPaidOut.Amount += entry.Amount.Amount
+	}
+
+	sort.Slice(ledger.InvestorTotals, func(i, j int) bool {
+		return ledger.InvestorTotals[i].InvestorID < ledger.InvestorTotals[j].InvestorID
+	})
+
+	return ledger
+}
+
+// mulDiv returns a*b/c rounded down and its remainder, without overflowing
+// on the intermediate product
+func mulDiv(a, b, c int64) (int64, int64) {
+	product := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	quotient, remainder := new(big.Int).QuoRem(product, big.NewInt(c), new(big.Int))
+	return quotient.Int64(), remainder.Int64()
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,109 @@
+package domain_test
+
+import (
+	"loan/internal/domain"
+	"testing"
+	"time"
+)
+
+// payoutFixture returns an IDR 100.00 loan with 8% ROI funded by three
+// investments, and a schedule total of 110.00
+func payoutFixture(t *testing.T) (*domain.Loan, domain.Money) {
+	t.Helper()
+
+	principal, _ := domain.ParseMoney("100.00", "IDR")
+	loan := domain.NewLoan("borrower1", principal, 0.1, 0.08)
+	loan.State = domain.LoanStateApproved
+
+	for _, inv := range []struct{ id, amount string }{{"inv_c", "33.34"}, {"inv_b", "33.33"}, {"inv_a", "33.33"}} {
+		amount, _ := domain.ParseMoney(inv.amount, "IDR")
+		investment, _ := domain.NewInvestment(loan.ID, "investor_"+inv.id, amount)
+		investment.ID = inv.id
+		if err := loan.AddInvestment(investment); err != nil {
+			t.Fatalf("Expected no error adding investment, got %v", err)
+		}
+	}
+	loan.State = domain.LoanStateDisbursed
+
+	totalDue, _ := domain.ParseMoney("110.00", "IDR")
+	return loan, totalDue
+}
+
+func TestDistributeRepaymentLargestRemainder(t *testing.T) {
+	// Arrange
+	loan, totalDue := payoutFixture(t)
+	repayment := repayment(t, loan.ID, "10.00", time.Now())
+
+	// Act
+	distribution, err := domain.DistributeRepayment(loan, totalDue, domain.ZeroMoney("IDR"), repayment)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error distributing repayment, got %v", err)
+	}
+
+	// 10.00 * 108/110 = 9.818..., rounded down to 9.81 for investors
+	if distribution.InvestorShare.Decimal() != "9.81" || distribution.PlatformShare.Decimal() != "0.19" {
+		t.Errorf("Expected 9.81 to investors and 0.19 to platform, got %s and %s",
+			distribution.InvestorShare.Decimal(), distribution.PlatformShare.Decimal())
+	}
+
+	// inv_c's exact share is 3.2706..., inv_a's and inv_b's 3.2697...; the two
+	// leftover cents go to the larger remainders, inv_a before inv_b by ID
+	want := map[string]string{"inv_a": "3.27", "inv_b": "3.27", "inv_c": "3.27"}
+	if len(distribution.Payouts) != 3 {
+		t.Fatalf("Expected 3 payouts, got %d", len(distribution.Payouts))
+	}
+	for _, payout := range distribution.Payouts {
+		if payout.Amount.Decimal() != want[payout.InvestmentID] {
+			t.Errorf("Expected %s for %s, got %s", want[payout.InvestmentID], payout.InvestmentID, payout.Amount.Decimal())
+		}
+	}
+}
+
+func TestDistributeRepaymentsReconcileToEntitlement(t *testing.T) {
+	// Arrange
+	loan, totalDue := payoutFixture(t)
+	// 120.00 in total: 110.00 repays the loan, 10.00 is overpaid
+	amounts := []string{"7.77", "13.01", "0.03", "33.33", "19.99", "40.87", "5.00"}
+
+	// Act
+	paid := domain.ZeroMoney("IDR")
+	var investors, platform, unapplied int64
+	perInvestment := make(map[string]int64)
+	for _, amount := range amounts {
+		repayment := repayment(t, loan.ID, amount, time.Now())
+		distribution, err := domain.DistributeRepayment(loan, totalDue, paid, repayment)
+		if err != nil {
+			t.Fatalf("Expected no error distributing repayment, got %v", err)
+		}
+
+		var payouts int64
+		for _, payout := range distribution.Payouts {
+			payouts += payout.Amount.Amount
+			perInvestment[payout.InvestmentID] += payout.Amount.Amount
+		}
+		if payouts != distribution.InvestorShare.Amount {
+			t.Errorf("Expected payouts to sum to investor share %d, got %d", distribution.InvestorShare.Amount, payouts)
+		}
+		if distribution.InvestorShare.Amount+distribution.PlatformShare.Amount+distribution.Unapplied.Amount != repayment.Amount.Amount {
+			t.Errorf("Expected distribution of %s to reconcile, got %+v", repayment.Amount, distribution)
+		}
+
+		investors += distribution.InvestorShare.Amount
+		platform += distribution.PlatformShare.Amount
+		unapplied += distribution.Unapplied.Amount
+		paid, _ = paid.Add(repayment.Amount)
+	}
+
+	// Assert
+	if investors != 10800 || platform != 200 || unapplied != 1000 {
+		t.Errorf("Expected 108.00 / 2.00 / 10.00 split, got %d / %d / %d", investors, platform, unapplied)
+	}
+
+	for id, total := range perInvestment {
+		if total < 3598 || total > 3602 {
+			t.Errorf("Expected %s to receive about 36.00, got %d", id, total)
+		}
+	}
+}
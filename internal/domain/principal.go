@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SystemActor is recorded for changes made outside a request, where no
+// caller is known
+const SystemActor = "system"
+
+// Role grants a principal access to a set of lifecycle actions
+type Role string
+
+const (
+	RoleBorrower       Role = "borrower"
+	RoleInvestor       Role = "investor"
+	RoleFieldValidator Role = "field_validator"
+	RoleFieldOfficer   Role = "field_officer"
+	RoleAdmin          Role = "admin" // may perform every action, on behalf of anyone
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string `json:"subject"`
+	Roles   []Role `json:"roles,omitempty"`
+}
+
+func (p *Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	requestIDKey
+	loggerKey
+)
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext returns the caller recorded by ContextWithPrincipal
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// ActorFromContext names the caller for the audit trail: the principal's
+// subject, or SystemActor when there is none
+func ActorFromContext(ctx context.Context) string {
+	if principal, ok := PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return principal.Subject
+	}
+	return SystemActor
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID, or "" outside a request
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// ContextWithLogger records the logger for work done on behalf of a request,
+// usually tagged with its request ID
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// LoggerFromContext returns the logger recorded by ContextWithLogger, or the
+// default logger outside a request
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
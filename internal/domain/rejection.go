@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type Rejection struct {
+	LoanID     string    `json:"loan_id"`
+	Reason     string    `json:"reason"`
+	ReviewerID string    `json:"reviewer_id"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+func NewRejection(loanID, reason, reviewerID string, rejectedAt time.Time) (*Rejection, error) {
+	if loanID == "" {
+		return nil, ValidationError("loan ID cannot be empty")
+	}
+
+	if reason == "" {
+		return nil, ValidationError("rejection reason cannot be empty")
+	}
+
+	if reviewerID == "" {
+		return nil, ValidationError("reviewer ID cannot be empty")
+	}
+
+	if rejectedAt.IsZero() {
+		return nil, ValidationError("rejection date cannot be empty")
+	}
+
+	return &Rejection{
+		LoanID:     loanID,
+		Reason:     reason,
+		ReviewerID: reviewerID,
+		RejectedAt: rejectedAt,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"loan/util"
+	"sort"
+	"time"
+)
+
+// Repayment is a payment received from the borrower of a disbursed loan
+type Repayment struct {
+	ID         string    `json:"id"`
+	LoanID     string    `json:"loan_id"`
+	Amount     Money     `json:"amount"`
+	PaidAt     time.Time `json:"paid_at"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func NewRepayment(loanID string, amount Money, paidAt time.Time) (*Repayment, error) {
+	if loanID == "" {
+		return nil, ValidationError("loan ID cannot be empty")
+	}
+
+	if !amount.IsPositive() {
+		return nil, ValidationError("repayment amount must be greater than zero")
+	}
+
+	if paidAt.IsZero() {
+		return nil, ValidationError("payment date cannot be empty")
+	}
+
+	if paidAt.After(time.Now()) {
+		return nil, ValidationError("payment date cannot be in the future")
+	}
+
+	return &Repayment{
+		ID:         "rep_" + util.GenerateUUID(),
+		LoanID:     loanID,
+		Amount:     amount,
+		PaidAt:     paidAt,
+		RecordedAt: time.Now(),
+	}, nil
+}
+
+// DelinquencyPolicy holds the thresholds, in days past due, at which a loan
+// is reported delinquent and at which it is moved to DEFAULTED
+type DelinquencyPolicy struct {
+	GraceDays        int `json:"grace_days"`
+	DefaultAfterDays int `json:"default_after_days"`
+}
+
+func (p DelinquencyPolicy) Validate() error {
+	if p.GraceDays < 0 {
+		return ValidationError("grace days cannot be negative")
+	}
+
+	if p.DefaultAfterDays <= p.GraceDays {
+		return ValidationError("default threshold must be greater than grace days")
+	}
+
+	return nil
+}
+
+type InstallmentState string
+
+const (
+	InstallmentStatePending InstallmentState = "PENDING"
+	InstallmentStatePartial InstallmentState = "PARTIAL"
+	InstallmentStatePaid    InstallmentState = "PAID"
+	InstallmentStateOverdue InstallmentState = "OVERDUE"
+)
+
+// InstallmentStatus is a scheduled installment together with what has been
+// paid towards it
+type InstallmentStatus struct {
+	*Installment
+	Paid        Money            `json:"paid"`
+	Outstanding Money            `json:"outstanding"`
+	State       InstallmentState `json:"state"`
+	PaidAt      *time.Time       `json:"paid_at,omitempty"` // when the installment was fully paid
+}
+
+// RepaymentStatus is the position of a loan's repayments against its
+// schedule at a point in time
+type RepaymentStatus struct {
+	LoanID       string               `json:"loan_id"`
+	AsOf         time.Time            `json:"as_of"`
+	TotalDue     Money                `json:"total_due"`
+	TotalPaid    Money                `json:"total_paid"`
+	Outstanding  Money                `json:"outstanding"`
+	Overpaid     Money                `json:"overpaid"` // paid beyond the schedule total, owed back to the borrower
+	DaysPastDue  int                  `json:"days_past_due"`
+	Delinquent   bool                 `json:"delinquent"`
+	Installments []*InstallmentStatus `json:"installments"`
+	Repayments   []*Repayment         `json:"repayments"`
+}
+
+// NewRepaymentStatus applies repayments made up to asOf to the schedule in
+// payment-date order. Each payment settles the earliest installment with an
+// outstanding balance first, so partial payments leave an installment PARTIAL
+// and early or oversized payments roll forward onto later installments.
+// Anything left once every installment is paid is reported as Overpaid.
+func NewRepaymentStatus(schedule *RepaymentSchedule, repayments []*Repayment, policy DelinquencyPolicy, asOf time.Time) (*RepaymentStatus, error) {
+	currency := schedule.TotalAmount.Currency
+
+	paid := make([]*Repayment, 0, len(repayments))
+	for _, repayment := range repayments {
+		if repayment.Amount.Currency != currency {
+			return nil, ValidationError("repayment currency %s does not match schedule currency %s", repayment.Amount.Currency, currency)
+		}
+		if !repayment.PaidAt.After(asOf) {
+			paid = append(paid, repayment)
+		}
+	}
+	sort.SliceStable(paid, func(i, j int) bool {
+		return paid[i].PaidAt.Before(paid[j].PaidAt)
+	})
+
+	status := &RepaymentStatus{
+		LoanID:       schedule.LoanID,
+		AsOf:         asOf,
+		TotalDue:     schedule.TotalAmount,
+		TotalPaid:    ZeroMoney(currency),
+		Outstanding:  ZeroMoney(currency),
+		Overpaid:     ZeroMoney(currency),
+		Installments: make([]*InstallmentStatus, len(schedule.Installments)),
+		Repayments:   paid,
+	}
+
+	for i, installment := range schedule.Installments {
+		status.Installments[i] = &InstallmentStatus{
+			Installment: installment,
+			Paid:        ZeroMoney(currency),
+			Outstanding: installment.Amount,
+		}
+	}
+
+	next := 0
+	for _, repayment := range paid {
+		status.TotalPaid.Amount += repayment.Amount.Amount
+
+		remaining := repayment.Amount.Amount
+		for remaining > 0 && next < len(status.Installments) {
+			installment := status.Installments[next]
+
+			applied := remaining
+			if applied > installment.Outstanding.Amount {
+				applied = installment.Outstanding.Amount
+			}
+			installment.Paid.Amount += applied
+			installment.Outstanding.Amount -= applied
+			remaining -= applied
+
+			if installment.Outstanding.IsZero() {
+				paidAt := repayment.PaidAt
+				installment.PaidAt = &paidAt
+				next++
+			}
+		}
+		status.Overpaid.Amount += remaining
+	}
+
+	today := civilDate(asOf)
+	for _, installment := range status.Installments {
+		status.Outstanding.Amount += installment.Outstanding.Amount
+
+		switch {
+		case installment.Outstanding.IsZero():
+			installment.State = InstallmentStatePaid
+		case today.After(civilDate(installment.DueDate)):
+			installment.State = InstallmentStateOverdue
+			if status.DaysPastDue == 0 {
+				status.DaysPastDue = daysBetween(installment.DueDate, asOf)
+			}
+		case installment.Paid.IsPositive():
+			installment.State = InstallmentStatePartial
+		default:
+			installment.State = InstallmentStatePending
+		}
+	}
+	status.Delinquent = status.DaysPastDue > policy.GraceDays
+
+	return status, nil
+}
+
+// IsRepaid reports whether every installment has been paid in full
+func (s *RepaymentStatus) IsRepaid() bool {
+	return s.Outstanding.IsZero()
+}
+
+// civilDate strips the time of day so day counts are calendar days
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(civilDate(to).Sub(civilDate(from)).Hours() / 24)
+}
@@ -0,0 +1,163 @@
+package domain_test
+
+import (
+	"errors"
+	"loan/internal/domain"
+	"testing"
+	"time"
+)
+
+var testPolicy = domain.DelinquencyPolicy{GraceDays: 3, DefaultAfterDays: 90}
+
+// repaymentFixture returns a disbursed IDR 300.00 loan with no interest and
+// three monthly installments of 100.00 due on the 1st of Feb, Mar and Apr 2024
+func repaymentFixture(t *testing.T) (*domain.Loan, *domain.RepaymentSchedule) {
+	t.Helper()
+
+	principal, _ := domain.ParseMoney("300.00", "IDR")
+	loan := domain.NewLoan("borrower1", principal, 0, 0)
+	loan.State = domain.LoanStateDisbursed
+
+	terms := domain.ScheduleTerms{Tenor: 3, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+	schedule, err := domain.NewRepaymentSchedule(loan, terms, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error generating schedule, got %v", err)
+	}
+
+	return loan, schedule
+}
+
+func repayment(t *testing.T, loanID, amount string, paidAt time.Time) *domain.Repayment {
+	t.Helper()
+
+	money, _ := domain.ParseMoney(amount, "IDR")
+	repayment, err := domain.NewRepayment(loanID, money, paidAt)
+	if err != nil {
+		t.Fatalf("Expected no error creating repayment, got %v", err)
+	}
+	return repayment
+}
+
+func TestRepaymentStatusPartialAndEarlyPayments(t *testing.T) {
+	// Arrange
+	loan, schedule := repaymentFixture(t)
+	repayments := []*domain.Repayment{
+		repayment(t, loan.ID, "150.00", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)),
+		repayment(t, loan.ID, "20.00", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)),
+	}
+
+	// Act
+	status, err := domain.NewRepaymentStatus(schedule, repayments, testPolicy, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error computing status, got %v", err)
+	}
+
+	wantStates := []domain.InstallmentState{domain.InstallmentStatePaid, domain.InstallmentStatePartial, domain.InstallmentStatePending}
+	for i, installment := range status.Installments {
+		if installment.State != wantStates[i] {
+			t.Errorf("Expected installment %d to be %s, got %s", installment.Number, wantStates[i], installment.State)
+		}
+	}
+
+	if status.Installments[1].Paid.Decimal() != "70.00" {
+		t.Errorf("Expected 70.00 paid towards installment 2, got %s", status.Installments[1].Paid.Decimal())
+	}
+	if status.Outstanding.Decimal() != "130.00" {
+		t.Errorf("Expected 130.00 outstanding, got %s", status.Outstanding.Decimal())
+	}
+	if status.DaysPastDue != 0 || status.Delinquent {
+		t.Errorf("Expected loan to be current, got %d days past due", status.DaysPastDue)
+	}
+}
+
+func TestRepaymentStatusDaysPastDue(t *testing.T) {
+	loan, schedule := repaymentFixture(t)
+	repayments := []*domain.Repayment{
+		repayment(t, loan.ID, "50.00", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	status, err := domain.NewRepaymentStatus(schedule, repayments, testPolicy, time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error computing status, got %v", err)
+	}
+
+	// the oldest unpaid installment was due on 1 February
+	if status.DaysPastDue != 33 {
+		t.Errorf("Expected 33 days past due, got %d", status.DaysPastDue)
+	}
+	if !status.Delinquent {
+		t.Error("Expected loan past the grace period to be delinquent")
+	}
+	if status.Installments[0].State != domain.InstallmentStateOverdue || status.Installments[1].State != domain.InstallmentStateOverdue {
+		t.Errorf("Expected first two installments to be overdue, got %s and %s",
+			status.Installments[0].State, status.Installments[1].State)
+	}
+}
+
+func TestRepaymentStatusOverpayment(t *testing.T) {
+	loan, schedule := repaymentFixture(t)
+	repayments := []*domain.Repayment{
+		repayment(t, loan.ID, "250.00", time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)),
+		repayment(t, loan.ID, "75.50", time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)),
+	}
+
+	status, err := domain.NewRepaymentStatus(schedule, repayments, testPolicy, time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error computing status, got %v", err)
+	}
+
+	if !status.IsRepaid() {
+		t.Errorf("Expected loan to be repaid, got %s outstanding", status.Outstanding)
+	}
+	if status.Overpaid.Decimal() != "25.50" {
+		t.Errorf("Expected 25.50 overpaid, got %s", status.Overpaid.Decimal())
+	}
+	if status.TotalPaid.Decimal() != "325.50" {
+		t.Errorf("Expected 325.50 paid, got %s", status.TotalPaid.Decimal())
+	}
+}
+
+func TestSettleRepayments(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     string
+		asOf       time.Time
+		wantState  domain.LoanState
+		wantChange bool
+	}{
+		{name: "repaid", amount: "300.00", asOf: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), wantState: domain.LoanStateRepaid, wantChange: true},
+		{name: "delinquent", amount: "100.00", asOf: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), wantState: domain.LoanStateDisbursed},
+		{name: "defaulted", amount: "100.00", asOf: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), wantState: domain.LoanStateDefaulted, wantChange: true},
+	}
+
+	for _, tt := range tests {
+		loan, schedule := repaymentFixture(t)
+		repayments := []*domain.Repayment{repayment(t, loan.ID, tt.amount, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC))}
+
+		status, _ := domain.NewRepaymentStatus(schedule, repayments, testPolicy, tt.asOf)
+		changed := loan.SettleRepayments(status, testPolicy)
+
+		if loan.State != tt.wantState || changed != tt.wantChange {
+			t.Errorf("%s: expected state %s (changed %v), got %s (changed %v)", tt.name, tt.wantState, tt.wantChange, loan.State, changed)
+		}
+	}
+}
+
+func TestDelinquencyPolicyValidate(t *testing.T) {
+	invalid := []domain.DelinquencyPolicy{
+		{GraceDays: -1, DefaultAfterDays: 90},
+		{GraceDays: 30, DefaultAfterDays: 30},
+	}
+
+	for _, policy := range invalid {
+		if err := policy.Validate(); !errors.Is(err, domain.ErrValidation) {
+			t.Errorf("Expected ErrValidation validating %+v, got %v", policy, err)
+		}
+	}
+
+	if err := testPolicy.Validate(); err != nil {
+		t.Errorf("Expected %+v to be valid, got %v", testPolicy, err)
+	}
+}
@@ -1,9 +1,16 @@
 package domain
 
+import (
+	"errors"
+	"net/http"
+)
+
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int          `json:"code"`
+	ErrorCode string       `json:"error_code,omitempty"` // set on errors, see ErrorCodeOf
+	Message   string       `json:"message"`
+	Errors    []FieldError `json:"errors,omitempty"` // every invalid field of the request
+	Data      interface{}  `json:"data,omitempty"`
 }
 
 func NewSuccessResponse(code int, message string, data interface{}) *Response {
@@ -14,18 +21,61 @@ func NewSuccessResponse(code int, message string, data interface{}) *Response {
 	}
 }
 
+// NewErrorResponse reports an error with the code usual for its HTTP
+// status. Use NewErrorResponseFor when the error's kind is known.
 func NewErrorResponse(code int, message string) *Response {
 	return &Response{
-		Code:    code,
-		Message: message,
+		Code:      code,
+		ErrorCode: errorCodeForStatus(code),
+		Message:   message,
+	}
+}
+
+// NewErrorResponseFor reports err with the code for its kind, listing its
+// fields when it is a FieldErrors
+func NewErrorResponseFor(code int, err error) *Response {
+	response := &Response{
+		Code:      code,
+		ErrorCode: ErrorCodeOf(err),
+		Message:   err.Error(),
+	}
+
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		response.Message = "Request has invalid fields"
+		response.Errors = fieldErrs
+	}
+	return response
+}
+
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	default:
+		return CodeInternal
 	}
 }
 
 type PaginatedResponse struct {
-	Items    interface{} `json:"items"`
-	Total    int         `json:"total"`
-	Page     int         `json:"page"`
-	PageSize int         `json:"page_size"`
+	Items      interface{} `json:"items"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page,omitempty"` // absent when paging by cursor
+	PageSize   int         `json:"page_size"`
+	NextCursor string      `json:"next_cursor,omitempty"` // absent on the last page
+	Totals     interface{} `json:"totals,omitempty"`
 }
 
 func NewPaginatedResponse(items interface{}, total, page, pageSize int) *PaginatedResponse {
@@ -37,16 +87,30 @@ func NewPaginatedResponse(items interface{}, total, page, pageSize int) *Paginat
 	}
 }
 
+// InvestmentSummary reports a single loan's investments. A loan and all of
+// its investments share one currency, so the totals are in that currency.
 type InvestmentSummary struct {
 	Investments     interface{} `json:"investments"`
-	TotalInvested   float64     `json:"total_invested"`
-	PrincipalAmount float64     `json:"principal_amount"`
+	Currency        string      `json:"currency"`
+	TotalInvested   Money       `json:"total_invested"`
+	PrincipalAmount Money       `json:"principal_amount"`
 }
 
-func NewInvestmentSummary(investments interface{}, totalInvested, principalAmount float64) *InvestmentSummary {
+func NewInvestmentSummary(investments interface{}, totalInvested, principalAmount Money) *InvestmentSummary {
 	return &InvestmentSummary{
 		Investments:     investments,
+		Currency:        principalAmount.Currency,
 		TotalInvested:   totalInvested,
 		PrincipalAmount: principalAmount,
 	}
 }
+
+// RepaymentReceipt is returned after recording a repayment: the repayment,
+// how it was distributed to investors, the loan's resulting state and its
+// position against the schedule
+type RepaymentReceipt struct {
+	Repayment    *Repayment          `json:"repayment"`
+	Distribution *PayoutDistribution `json:"distribution"`
+	LoanState    LoanState           `json:"loan_state"`
+	Status       *RepaymentStatus    `json:"status"`
+}
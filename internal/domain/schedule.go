@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+type RepaymentFrequency string
+
+const (
+	RepaymentFrequencyWeekly  RepaymentFrequency = "WEEKLY"
+	RepaymentFrequencyMonthly RepaymentFrequency = "MONTHLY"
+)
+
+// InterestMethod decides how Loan.Rate turns into interest. Rate is the total
+// interest over the tenor, so each period carries Rate/Tenor:
+//   - FLAT charges that periodic rate on the original principal every period,
+//     so total interest is exactly principal * Rate.
+//   - ANNUITY (effective interest) charges it on the outstanding balance and
+//     keeps every installment equal, so total interest is lower than FLAT.
+type InterestMethod string
+
+const (
+	InterestMethodFlat    InterestMethod = "FLAT"
+	InterestMethodAnnuity InterestMethod = "ANNUITY"
+)
+
+// ScheduleTerms configures how a repayment schedule is generated
+type ScheduleTerms struct {
+	Tenor     int                `json:"tenor"` // number of installments
+	Frequency RepaymentFrequency `json:"frequency"`
+	Method    InterestMethod     `json:"interest_method"`
+}
+
+func (t ScheduleTerms) Validate() error {
+	if t.Tenor <= 0 {
+		return ValidationError("tenor must be greater than zero")
+	}
+
+	if t.Frequency != RepaymentFrequencyWeekly && t.Frequency != RepaymentFrequencyMonthly {
+		return ValidationError("unsupported repayment frequency %q", t.Frequency)
+	}
+
+	if t.Method != InterestMethodFlat && t.Method != InterestMethodAnnuity {
+		return ValidationError("unsupported interest method %q", t.Method)
+	}
+
+	return nil
+}
+
+// dueDate returns the due date of the n-th installment (1-based) counted from
+// start. Monthly dates are clamped to the end of shorter months.
+func (t ScheduleTerms) dueDate(start time.Time, n int) time.Time {
+	if t.Frequency == RepaymentFrequencyWeekly {
+		return start.AddDate(0, 0, 7*n)
+	}
+
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, start.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+}
+
+type Installment struct {
+	Number    int       `json:"number"`
+	DueDate   time.Time `json:"due_date"`
+	Principal Money     `json:"principal"`
+	Interest  Money     `json:"interest"`
+	Amount    Money     `json:"amount"` // Principal + Interest
+}
+
+type RepaymentSchedule struct {
+	LoanID         string         `json:"loan_id"`
+	Terms          ScheduleTerms  `json:"terms"`
+	TotalPrincipal Money          `json:"total_principal"`
+	TotalInterest  Money          `json:"total_interest"`
+	TotalAmount    Money          `json:"total_amount"`
+	Installments   []*Installment `json:"installments"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// NewRepaymentSchedule splits the loan's principal and interest into
+// installments due from start. All arithmetic is in minor units and any
+// rounding remainder lands on the final installment, so the installments
+// always sum exactly to the schedule totals.
+func NewRepaymentSchedule(loan *Loan, terms ScheduleTerms, start time.Time) (*RepaymentSchedule, error) {
+	if err := terms.Validate(); err != nil {
+		return nil, err
+	}
+
+	if start.IsZero() {
+		return nil, errors.New("schedule start date cannot be empty")
+	}
+
+	var amounts [][2]int64
+	switch terms.Method {
+	case InterestMethodFlat:
+		amounts = flatInstallments(loan.PrincipalAmount.Amount, loan.Rate, terms.Tenor)
+	case InterestMethodAnnuity:
+		amounts = annuityInstallments(loan.PrincipalAmount.Amount, loan.Rate, terms.Tenor)
+	}
+
+	currency := loan.Currency()
+	schedule := &RepaymentSchedule{
+		LoanID:         loan.ID,
+		Terms:          terms,
+		TotalPrincipal: ZeroMoney(currency),
+		TotalInterest:  ZeroMoney(currency),
+		TotalAmount:    ZeroMoney(currency),
+		Installments:   make([]*Installment, 0, terms.Tenor),
+		CreatedAt:      time.Now(),
+	}
+
+	for i, amount := range amounts {
+		installment := &Installment{
+			Number:    i + 1,
+			DueDate:   terms.dueDate(start, i+1),
+			Principal: Money{Amount: amount[0], Currency: currency},
+			Interest:  Money{Amount: amount[1], Currency: currency},
+			Amount:    Money{Amount: amount[0] + amount[1], Currency: currency},
+		}
+		schedule.Installments = append(schedule.Installments, installment)
+		schedule.addToTotals(installment)
+	}
+
+	return schedule, nil
+}
+
+// RecalculateTotals rebuilds the schedule totals from its installments, e.g.
+// after loading them from storage.
+func (s *RepaymentSchedule) RecalculateTotals(currency string) {
+	s.TotalPrincipal = ZeroMoney(currency)
+	s.TotalInterest = ZeroMoney(currency)
+	s.TotalAmount = ZeroMoney(currency)
+	for _, installment := range s.Installments {
+		s.addToTotals(installment)
+	}
+}
+
+func (s *RepaymentSchedule) addToTotals(installment *Installment) {
+	s.TotalPrincipal.Amount += installment.Principal.Amount
+	s.TotalInterest.Amount += installment.Interest.Amount
+	s.TotalAmount.Amount += installment.Amount.Amount
+}
+
+// flatInstallments returns [principal, interest] pairs in minor units
+func flatInstallments(principal int64, rate float64, tenor int) [][2]int64 {
+	totalInterest := int64(math.Round(float64(principal) * rate))
+	n := int64(tenor)
+
+	amounts := make([][2]int64, tenor)
+	for i := range amounts {
+		amounts[i] = [2]int64{principal / n, totalInterest / n}
+	}
+
+	last := &amounts[tenor-1]
+	last[0] += principal % n
+	last[1] += totalInterest % n
+
+	return amounts
+}
+
+// annuityInstallments returns [principal, interest] pairs in minor units for
+// equal installments with interest charged on the declining balance
+func annuityInstallments(principal int64, rate float64, tenor int) [][2]int64 {
+	periodicRate := rate / float64(tenor)
+
+	var payment int64
+	if periodicRate == 0 {
+		payment = int64(math.Round(float64(principal) / float64(tenor)))
+	} else {
+		factor := math.Pow(1+periodicRate, float64(tenor))
+		payment = int64(math.Round(float64(principal) * periodicRate * factor / (factor - 1)))
+	}
+
+	amounts := make([][2]int64, tenor)
+	balance := principal
+	for i := range amounts {
+		interest := int64(math.Round(float64(balance) * periodicRate))
+
+		principalPart := payment - interest
+		if i == tenor-1 || principalPart > balance {
+			principalPart = balance
+		}
+
+		amounts[i] = [2]int64{principalPart, interest}
+		balance -= principalPart
+	}
+
+	return amounts
+}
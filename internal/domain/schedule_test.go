@@ -0,0 +1,116 @@
+package domain_test
+
+import (
+	"errors"
+	"loan/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestFlatScheduleSumsExactly(t *testing.T) {
+	// Arrange
+	principal, _ := domain.ParseMoney("1000.00", "IDR")
+	loan := domain.NewLoan("borrower1", principal, 0.1, 0.08)
+	terms := domain.ScheduleTerms{Tenor: 3, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+
+	// Act
+	schedule, err := domain.NewRepaymentSchedule(loan, terms, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error generating schedule, got %v", err)
+	}
+
+	wantPrincipal := []int64{33333, 33333, 33334}
+	wantInterest := []int64{3333, 3333, 3334}
+	for i, installment := range schedule.Installments {
+		if installment.Principal.Amount != wantPrincipal[i] || installment.Interest.Amount != wantInterest[i] {
+			t.Errorf("Expected installment %d to be %d + %d, got %d + %d", installment.Number,
+				wantPrincipal[i], wantInterest[i], installment.Principal.Amount, installment.Interest.Amount)
+		}
+	}
+
+	if schedule.TotalPrincipal != principal {
+		t.Errorf("Expected total principal %s, got %s", principal, schedule.TotalPrincipal)
+	}
+	if schedule.TotalInterest.Decimal() != "100.00" {
+		t.Errorf("Expected total interest 100.00, got %s", schedule.TotalInterest.Decimal())
+	}
+	if schedule.TotalAmount.Decimal() != "1100.00" {
+		t.Errorf("Expected total amount 1100.00, got %s", schedule.TotalAmount.Decimal())
+	}
+}
+
+func TestAnnuityScheduleSumsExactly(t *testing.T) {
+	// Arrange
+	principal, _ := domain.ParseMoney("1000.01", "USD")
+	loan := domain.NewLoan("borrower1", principal, 0.12, 0.08)
+	terms := domain.ScheduleTerms{Tenor: 12, Frequency: domain.RepaymentFrequencyWeekly, Method: domain.InterestMethodAnnuity}
+
+	// Act
+	schedule, err := domain.NewRepaymentSchedule(loan, terms, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error generating schedule, got %v", err)
+	}
+
+	var principalSum, amountSum int64
+	for _, installment := range schedule.Installments[:len(schedule.Installments)-1] {
+		if installment.Amount != schedule.Installments[0].Amount {
+			t.Errorf("Expected equal installments, got %s and %s", schedule.Installments[0].Amount, installment.Amount)
+		}
+	}
+	for _, installment := range schedule.Installments {
+		principalSum += installment.Principal.Amount
+		amountSum += installment.Amount.Amount
+	}
+
+	if principalSum != principal.Amount {
+		t.Errorf("Expected principal parts to sum to %d, got %d", principal.Amount, principalSum)
+	}
+	if amountSum != schedule.TotalAmount.Amount || schedule.TotalAmount.Amount != schedule.TotalPrincipal.Amount+schedule.TotalInterest.Amount {
+		t.Errorf("Expected installments to sum to total %s, got %d", schedule.TotalAmount, amountSum)
+	}
+
+	flatInterest := int64(float64(principal.Amount) * 0.12)
+	if schedule.TotalInterest.Amount >= flatInterest {
+		t.Errorf("Expected annuity interest below flat interest %d, got %d", flatInterest, schedule.TotalInterest.Amount)
+	}
+
+	if got := schedule.Installments[1].DueDate; !got.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected second weekly installment due 2024-01-15, got %s", got)
+	}
+}
+
+func TestMonthlyDueDatesClampToMonthEnd(t *testing.T) {
+	principal, _ := domain.ParseMoney("300.00", "IDR")
+	loan := domain.NewLoan("borrower1", principal, 0, 0)
+	terms := domain.ScheduleTerms{Tenor: 3, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+
+	schedule, err := domain.NewRepaymentSchedule(loan, terms, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error generating schedule, got %v", err)
+	}
+
+	want := []string{"2024-02-29", "2024-03-31", "2024-04-30"}
+	for i, installment := range schedule.Installments {
+		if got := installment.DueDate.Format("2006-01-02"); got != want[i] {
+			t.Errorf("Expected installment %d due %s, got %s", installment.Number, want[i], got)
+		}
+	}
+}
+
+func TestScheduleTermsValidate(t *testing.T) {
+	invalid := []domain.ScheduleTerms{
+		{Tenor: 0, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat},
+		{Tenor: 12, Frequency: "DAILY", Method: domain.InterestMethodFlat},
+		{Tenor: 12, Frequency: domain.RepaymentFrequencyMonthly, Method: "BALLOON"},
+	}
+
+	for _, terms := range invalid {
+		if err := terms.Validate(); !errors.Is(err, domain.ErrValidation) {
+			t.Errorf("Expected ErrValidation validating %+v, got %v", terms, err)
+		}
+	}
+}
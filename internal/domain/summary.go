@@ -0,0 +1,45 @@
+package domain
+
+import "sort"
+
+// CurrencyTotal aggregates the loans that share one currency. Totals are only
+// ever accumulated within a currency; amounts in different currencies are
+// never added together.
+type CurrencyTotal struct {
+	Currency        string `json:"currency"`
+	LoanCount       int    `json:"loan_count"`
+	PrincipalAmount Money  `json:"principal_amount"`
+	TotalInvested   Money  `json:"total_invested"`
+}
+
+// SummarizeByCurrency groups loans by currency and returns one total per
+// currency, ordered by currency code.
+func SummarizeByCurrency(loans []*Loan) []*CurrencyTotal {
+	byCurrency := make(map[string]*CurrencyTotal)
+	for _, loan := range loans {
+		total, exists := byCurrency[loan.Currency()]
+		if !exists {
+			total = &CurrencyTotal{
+				Currency:        loan.Currency(),
+				PrincipalAmount: ZeroMoney(loan.Currency()),
+				TotalInvested:   ZeroMoney(loan.Currency()),
+			}
+			byCurrency[loan.Currency()] = total
+		}
+
+		total.LoanCount++
+		total.PrincipalAmount.Amount += loan.PrincipalAmount.Amount
+		total.TotalInvested.Amount += loan.TotalInvestedAmount().Amount
+	}
+
+	totals := make([]*CurrencyTotal, 0, len(byCurrency))
+	for _, total := range byCurrency {
+		totals = append(totals, total)
+	}
+
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Currency < totals[j].Currency
+	})
+
+	return totals
+}
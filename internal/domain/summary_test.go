@@ -0,0 +1,28 @@
+package domain_test
+
+import (
+	"loan/internal/domain"
+	"testing"
+)
+
+func TestSummarizeByCurrency(t *testing.T) {
+	idr, _ := domain.ParseMoney("1000", "IDR")
+	usd, _ := domain.ParseMoney("1000", "USD")
+	loans := []*domain.Loan{
+		domain.NewLoan("borrower1", idr, 0.1, 0.08),
+		domain.NewLoan("borrower2", usd, 0.1, 0.08),
+		domain.NewLoan("borrower3", idr, 0.1, 0.08),
+	}
+
+	totals := domain.SummarizeByCurrency(loans)
+
+	if len(totals) != 2 {
+		t.Fatalf("Expected one total per currency, got %d", len(totals))
+	}
+	if totals[0].Currency != "IDR" || totals[0].LoanCount != 2 || totals[0].PrincipalAmount.Decimal() != "2000.00" {
+		t.Errorf("Unexpected IDR total %+v", totals[0])
+	}
+	if totals[1].Currency != "USD" || totals[1].LoanCount != 1 || totals[1].PrincipalAmount.Decimal() != "1000.00" {
+		t.Errorf("Unexpected USD total %+v", totals[1])
+	}
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"loan/util"
+	"net/url"
+	"time"
+)
+
+// WebhookEventType names a loan lifecycle change that subscribers can be
+// told about
+type WebhookEventType string
+
+const (
+	WebhookLoanApproved    WebhookEventType = "loan.approved"
+	WebhookInvestmentAdded WebhookEventType = "loan.investment_added"
+	WebhookLoanInvested    WebhookEventType = "loan.invested" // the investment that completed funding
+	WebhookLoanDisbursed   WebhookEventType = "loan.disbursed"
+)
+
+func ParseWebhookEventType(s string) (WebhookEventType, error) {
+	switch eventType := WebhookEventType(s); eventType {
+	case WebhookLoanApproved, WebhookInvestmentAdded, WebhookLoanInvested, WebhookLoanDisbursed:
+		return eventType, nil
+	default:
+		return "", ValidationError("unknown webhook event %q", s)
+	}
+}
+
+// WebhookEvent is the JSON body POSTed to subscribers
+type WebhookEvent struct {
+	ID         string           `json:"id"` // the same for every retry, so receivers can discard duplicates
+	Type       WebhookEventType `json:"type"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       WebhookEventData `json:"data"`
+}
+
+type WebhookEventData struct {
+	Loan       *Loan       `json:"loan"`
+	Investment *Investment `json:"investment,omitempty"`
+}
+
+func NewWebhookEvent(eventType WebhookEventType, loan *Loan, investment *Investment) *WebhookEvent {
+	return &WebhookEvent{
+		ID:         "evt_" + util.GenerateUUID(),
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Data: WebhookEventData{
+			Loan:       loan,
+			Investment: investment,
+		},
+	}
+}
+
+// WebhookSubscription registers a URL to receive webhook events, signed with
+// the subscription's secret
+type WebhookSubscription struct {
+	ID        string             `json:"id"`
+	URL       string             `json:"url"`
+	Secret    string             `json:"secret,omitempty"` // only shown when the subscription is created
+	Events    []WebhookEventType `json:"events"`           // empty subscribes to every event
+	Active    bool               `json:"active"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+func NewWebhookSubscription(rawURL string, events []WebhookEventType) (*WebhookSubscription, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ValidationError("webhook URL must be an absolute http or https URL")
+	}
+
+	for _, event := range events {
+		if _, err := ParseWebhookEventType(string(event)); err != nil {
+			return nil, err
+		}
+	}
+	if events == nil {
+		events = []WebhookEventType{}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate webhook secret: %w", err)
+	}
+
+	now := time.Now()
+	return &WebhookSubscription{
+		ID:        "whs_" + util.GenerateUUID(),
+		URL:       rawURL,
+		Secret:    "whsec_" + hex.EncodeToString(secret),
+		Events:    events,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Subscribes reports whether the subscription should receive eventType
+func (s *WebhookSubscription) Subscribes(eventType WebhookEventType) bool {
+	if !s.Active {
+		return false
+	}
+
+	if len(s.Events) == 0 {
+		return true
+	}
+
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Deactivate stops further deliveries to the subscription
+func (s *WebhookSubscription) Deactivate(at time.Time) {
+	s.Active = false
+	s.UpdatedAt = at
+}
+
+// Redacted returns a copy without the secret, for display
+func (s *WebhookSubscription) Redacted() *WebhookSubscription {
+	clone := *s
+	clone.Secret = ""
+	return &clone
+}
+
+// WebhookDelivery records one attempt to deliver an event to a subscription
+type WebhookDelivery struct {
+	ID             string           `json:"id"`
+	SubscriptionID string           `json:"subscription_id"`
+	EventID        string           `json:"event_id"`
+	EventType      WebhookEventType `json:"event_type"`
+	URL            string           `json:"url"`
+	Attempt        int              `json:"attempt"`
+	StatusCode     int              `json:"status_code,omitempty"` // 0 when no response was received
+	Error          string           `json:"error,omitempty"`
+	Succeeded      bool             `json:"succeeded"`
+	DurationMillis int64            `json:"duration_ms"`
+	AttemptedAt    time.Time        `json:"attempted_at"`
+}
+
+func NewWebhookDelivery(subscription *WebhookSubscription, event *WebhookEvent, attempt int, attemptedAt time.Time) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:             "whd_" + util.GenerateUUID(),
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		URL:            subscription.URL,
+		Attempt:        attempt,
+		AttemptedAt:    attemptedAt,
+	}
+}
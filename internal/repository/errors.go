@@ -0,0 +1,17 @@
+package repository
+
+import "loan/internal/domain"
+
+// Errors returned when a record does not exist. Each matches
+// domain.ErrNotFound.
+var (
+	ErrLoanNotFound                = domain.NotFoundError("loan not found")
+	ErrScheduleNotFound            = domain.NotFoundError("repayment schedule not found")
+	ErrOutboxMessageNotFound       = domain.NotFoundError("outbox message not found")
+	ErrWebhookSubscriptionNotFound = domain.NotFoundError("webhook subscription not found")
+)
+
+// ErrConflict is returned by SaveLoan when the stored loan's version no longer
+// matches the version the caller read, meaning another writer got there first.
+// It matches domain.ErrConflict.
+var ErrConflict = domain.ConflictError("loan was modified concurrently, please retry")
@@ -0,0 +1,38 @@
+CREATE TABLE loans (
+    id                   TEXT PRIMARY KEY,
+    borrower_id          TEXT NOT NULL,
+    principal_amount     DOUBLE PRECISION NOT NULL,
+    rate                 DOUBLE PRECISION NOT NULL,
+    roi                  DOUBLE PRECISION NOT NULL,
+    state                TEXT NOT NULL,
+    agreement_letter_url TEXT NOT NULL DEFAULT '',
+    created_at           TIMESTAMP NOT NULL,
+    updated_at           TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_loans_borrower_id ON loans (borrower_id);
+CREATE INDEX idx_loans_state ON loans (state);
+
+CREATE TABLE approvals (
+    loan_id            TEXT PRIMARY KEY REFERENCES loans (id),
+    proof_picture_url  TEXT NOT NULL,
+    field_validator_id TEXT NOT NULL,
+    approval_date      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE investments (
+    id          TEXT PRIMARY KEY,
+    loan_id     TEXT NOT NULL REFERENCES loans (id),
+    investor_id TEXT NOT NULL,
+    amount      DOUBLE PRECISION NOT NULL,
+    invested_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_investments_loan_id ON investments (loan_id);
+
+CREATE TABLE disbursements (
+    loan_id                TEXT PRIMARY KEY REFERENCES loans (id),
+    agreement_document_url TEXT NOT NULL,
+    field_officer_id       TEXT NOT NULL,
+    disbursement_date      TIMESTAMP NOT NULL
+);
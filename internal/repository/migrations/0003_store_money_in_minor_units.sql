@@ -0,0 +1,11 @@
+-- Amounts move from floating point to integer minor units plus an ISO-4217
+-- currency. Existing rows predate currencies and are all IDR (2 decimals).
+ALTER TABLE loans ADD COLUMN principal_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+UPDATE loans SET principal_minor = CAST(ROUND(principal_amount * 100) AS BIGINT);
+ALTER TABLE loans DROP COLUMN principal_amount;
+
+ALTER TABLE investments ADD COLUMN amount_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE investments ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+UPDATE investments SET amount_minor = CAST(ROUND(amount * 100) AS BIGINT);
+ALTER TABLE investments DROP COLUMN amount;
@@ -0,0 +1,16 @@
+CREATE TABLE rejections (
+    loan_id     TEXT PRIMARY KEY REFERENCES loans (id),
+    reason      TEXT NOT NULL,
+    reviewer_id TEXT NOT NULL,
+    rejected_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE cancellations (
+    loan_id      TEXT PRIMARY KEY REFERENCES loans (id),
+    reason       TEXT NOT NULL,
+    cancelled_by TEXT NOT NULL,
+    cancelled_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE investments ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE';
+ALTER TABLE investments ADD COLUMN refunded_at TIMESTAMP;
@@ -0,0 +1,17 @@
+CREATE TABLE repayment_schedules (
+    loan_id         TEXT PRIMARY KEY REFERENCES loans (id),
+    tenor           INTEGER NOT NULL,
+    frequency       TEXT NOT NULL,
+    interest_method TEXT NOT NULL,
+    currency        TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE repayment_installments (
+    loan_id         TEXT NOT NULL REFERENCES repayment_schedules (loan_id),
+    number          INTEGER NOT NULL,
+    due_date        TIMESTAMP NOT NULL,
+    principal_minor BIGINT NOT NULL,
+    interest_minor  BIGINT NOT NULL,
+    PRIMARY KEY (loan_id, number)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE repayments (
+    id           TEXT PRIMARY KEY,
+    loan_id      TEXT NOT NULL REFERENCES loans (id),
+    amount_minor BIGINT NOT NULL,
+    currency     TEXT NOT NULL,
+    paid_at      TIMESTAMP NOT NULL,
+    recorded_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_repayments_loan_id ON repayments (loan_id);
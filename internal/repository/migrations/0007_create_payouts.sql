@@ -0,0 +1,12 @@
+CREATE TABLE payouts (
+    id            TEXT PRIMARY KEY,
+    loan_id       TEXT NOT NULL REFERENCES loans (id),
+    repayment_id  TEXT NOT NULL REFERENCES repayments (id),
+    investment_id TEXT NOT NULL REFERENCES investments (id),
+    investor_id   TEXT NOT NULL,
+    amount_minor  BIGINT NOT NULL,
+    currency      TEXT NOT NULL,
+    created_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_payouts_loan_id ON payouts (loan_id);
@@ -0,0 +1,14 @@
+CREATE TABLE outbox_messages (
+    id              TEXT PRIMARY KEY,
+    topic           TEXT NOT NULL,
+    payload         TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL,
+    created_at      TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP NOT NULL,
+    delivered_at    TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_messages_status_next_attempt ON outbox_messages (status, next_attempt_at);
@@ -0,0 +1,25 @@
+CREATE TABLE webhook_subscriptions (
+    id         TEXT PRIMARY KEY,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     TEXT NOT NULL DEFAULT '',
+    active     BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions (id),
+    event_id        TEXT NOT NULL,
+    event_type      TEXT NOT NULL,
+    url             TEXT NOT NULL,
+    attempt         INTEGER NOT NULL,
+    status_code     INTEGER NOT NULL DEFAULT 0,
+    error           TEXT NOT NULL DEFAULT '',
+    succeeded       BOOLEAN NOT NULL,
+    duration_ms     BIGINT NOT NULL,
+    attempted_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, attempted_at);
@@ -0,0 +1,11 @@
+-- Append-only history of loan state changes. Loans created before this
+-- migration have no history.
+CREATE TABLE loan_events (
+    id          TEXT PRIMARY KEY,
+    loan_id     TEXT NOT NULL REFERENCES loans (id),
+    sequence    INTEGER NOT NULL,
+    type        TEXT NOT NULL,
+    data        TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    UNIQUE (loan_id, sequence)
+);
@@ -0,0 +1,19 @@
+-- Hash-chained audit trail. Entries are only ever inserted, and loan_id has
+-- no foreign key so the trail can outlive the records it describes.
+CREATE TABLE audit_entries (
+    id           TEXT PRIMARY KEY,
+    sequence     BIGINT NOT NULL UNIQUE,
+    actor        TEXT NOT NULL,
+    action       TEXT NOT NULL,
+    loan_id      TEXT NOT NULL DEFAULT '',
+    request_id   TEXT NOT NULL DEFAULT '',
+    before_state TEXT NOT NULL DEFAULT '',
+    after_state  TEXT NOT NULL DEFAULT '',
+    occurred_at  TIMESTAMP NOT NULL,
+    prev_hash    TEXT NOT NULL,
+    hash         TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_entries_loan_id ON audit_entries (loan_id, sequence);
+CREATE INDEX idx_audit_entries_actor ON audit_entries (actor, sequence);
+CREATE INDEX idx_audit_entries_occurred_at ON audit_entries (occurred_at);
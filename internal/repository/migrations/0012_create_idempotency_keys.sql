@@ -0,0 +1,13 @@
+-- Responses to requests made with an Idempotency-Key, replayed to retries.
+-- status_code is 0 while the first request is in progress.
+CREATE TABLE idempotency_keys (
+    actor           TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash    TEXT NOT NULL,
+    status_code     INTEGER NOT NULL DEFAULT 0,
+    body            TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+    PRIMARY KEY (actor, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,10 @@
+-- The sequence of the last audit entry. Reading it through an UPDATE locks
+-- the row until the transaction ends, so concurrent appends take turns
+-- instead of racing for the same sequence.
+CREATE TABLE audit_chain_head (
+    id       INTEGER PRIMARY KEY CHECK (id = 1),
+    sequence BIGINT NOT NULL
+);
+
+INSERT INTO audit_chain_head (id, sequence)
+SELECT 1, COALESCE(MAX(sequence), 0) FROM audit_entries;
@@ -2,18 +2,36 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
+	"fmt"
 	"loan/internal/domain"
+	"sort"
 	"sync"
+	"time"
 )
 
-// MockLoanRepository is an in-memory implementation of LoanRepository
+// MockLoanRepository is an in-memory implementation of LoanRepository.
+// Loans are copied on the way in and out, so callers never share state with
+// the store or with each other.
 type MockLoanRepository struct {
 	loans         map[string]*domain.Loan
 	approvals     map[string]*domain.Approval
 	investments   map[string]*domain.Investment
 	disbursements map[string]*domain.Disbursement
+	rejections    map[string]*domain.Rejection
+	cancellations map[string]*domain.Cancellation
+	schedules     map[string]*domain.RepaymentSchedule
+	repayments    map[string][]*domain.Repayment
+	payouts       map[string][]*domain.Payout
+	outbox        map[string]*domain.OutboxMessage
+	webhooks      map[string]*domain.WebhookSubscription
+	deliveries    map[string][]*domain.WebhookDelivery
+	events        map[string][]json.RawMessage // encoded, so stored history cannot be changed through shared pointers
+	audit         []*domain.AuditEntry
+	idempotency   map[idempotencyKey]*domain.IdempotencyRecord
 	mutex         sync.RWMutex
+	inTx          bool
+	undo          *undoLog // of the unit of work, nil outside one
 }
 
 func NewMockLoanRepository() *MockLoanRepository {
@@ -22,14 +40,42 @@ func NewMockLoanRepository() *MockLoanRepository {
 		approvals:     make(map[string]*domain.Approval),
 		investments:   make(map[string]*domain.Investment),
 		disbursements: make(map[string]*domain.Disbursement),
+		rejections:    make(map[string]*domain.Rejection),
+		cancellations: make(map[string]*domain.Cancellation),
+		schedules:     make(map[string]*domain.RepaymentSchedule),
+		repayments:    make(map[string][]*domain.Repayment),
+		payouts:       make(map[string][]*domain.Payout),
+		outbox:        make(map[string]*domain.OutboxMessage),
+		webhooks:      make(map[string]*domain.WebhookSubscription),
+		deliveries:    make(map[string][]*domain.WebhookDelivery),
+		events:        make(map[string][]json.RawMessage),
+		idempotency:   make(map[idempotencyKey]*domain.IdempotencyRecord),
 	}
 }
 
+// idempotencyKey scopes an idempotency key to its actor
+type idempotencyKey struct {
+	actor, key string
+}
+
+// SaveLoan stores a copy of loan if its version matches the stored version
+// (0 for a new loan), returning ErrConflict otherwise.
 func (r *MockLoanRepository) SaveLoan(ctx context.Context, loan *domain.Loan) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.loans[loan.ID] = loan
+	storedVersion := 0
+	if existing, exists := r.loans[loan.ID]; exists {
+		storedVersion = existing.Version
+	}
+
+	if loan.Version != storedVersion {
+		return ErrConflict
+	}
+
+	remember(r.undo, "loans", r.loans, loan.ID, cloneLoan)
+	loan.Version++
+	r.loans[loan.ID] = cloneLoan(loan)
 	return nil
 }
 
@@ -39,20 +85,32 @@ func (r *MockLoanRepository) GetLoanByID(ctx context.Context, id string) (*domai
 
 	loan, exists := r.loans[id]
 	if !exists {
-		return nil, errors.New("loan not found")
+		return nil, ErrLoanNotFound
 	}
 
-	return loan, nil
+	return cloneLoan(loan), nil
 }
 
-func (r *MockLoanRepository) ListLoans(ctx context.Context, page, pageSize int) ([]*domain.Loan, int, error) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-
+// listLoans returns copies of the loans matching filter, in order. The
+// caller must hold the lock.
+func (r *MockLoanRepository) listLoans(filter domain.LoanFilter, order domain.LoanSort) []*domain.Loan {
 	var result []*domain.Loan
 	for _, loan := range r.loans {
-		result = append(result, loan)
+		if filter.Matches(loan) {
+			result = append(result, cloneLoan(loan))
+		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return order.Less(result[i], result[j])
+	})
+	return result
+}
+
+func (r *MockLoanRepository) ListLoans(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, page, pageSize int) ([]*domain.Loan, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := r.listLoans(filter, order)
 
 	total := len(result)
 
@@ -78,17 +136,46 @@ func (r *MockLoanRepository) ListLoans(ctx context.Context, page, pageSize int)
 	return result, total, nil
 }
 
+func (r *MockLoanRepository) ListLoansAfter(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, after *domain.LoanCursor, limit int) ([]*domain.Loan, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	matching := r.listLoans(filter, order)
+
+	result := []*domain.Loan{}
+	for _, loan := range matching {
+		if len(result) == limit {
+			break
+		}
+		if after == nil || after.Admits(loan) {
+			result = append(result, loan)
+		}
+	}
+
+	return result, len(matching), nil
+}
+
+func (r *MockLoanRepository) SummarizeLoans(ctx context.Context, filter domain.LoanFilter) ([]*domain.CurrencyTotal, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return domain.SummarizeByCurrency(r.listLoans(filter, domain.LoanSort{})), nil
+}
+
 func (r *MockLoanRepository) SaveApproval(ctx context.Context, approval *domain.Approval) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	loan, exists := r.loans[approval.LoanID]
 	if !exists {
-		return errors.New("loan not found")
+		return ErrLoanNotFound
 	}
 
-	r.approvals[approval.LoanID] = approval
-	loan.Approval = approval
+	remember(r.undo, "loans", r.loans, approval.LoanID, cloneLoan)
+	remember(r.undo, "approvals", r.approvals, approval.LoanID, cloneRecord[domain.Approval])
+	stored := *approval
+	r.approvals[approval.LoanID] = &stored
+	loan.Approval = &stored
 
 	return nil
 }
@@ -99,22 +186,25 @@ func (r *MockLoanRepository) SaveInvestment(ctx context.Context, investment *dom
 
 	loan, exists := r.loans[investment.LoanID]
 	if !exists {
-		return errors.New("loan not found")
+		return ErrLoanNotFound
 	}
 
-	r.investments[investment.ID] = investment
+	remember(r.undo, "loans", r.loans, investment.LoanID, cloneLoan)
+	remember(r.undo, "investments", r.investments, investment.ID, cloneRecord[domain.Investment])
+	stored := *investment
+	r.investments[investment.ID] = &stored
 
 	found := false
 	for i, inv := range loan.Investments {
 		if inv.ID == investment.ID {
-			loan.Investments[i] = investment
+			loan.Investments[i] = &stored
 			found = true
 			break
 		}
 	}
 
 	if !found {
-		loan.Investments = append(loan.Investments, investment)
+		loan.Investments = append(loan.Investments, &stored)
 	}
 
 	return nil
@@ -126,10 +216,10 @@ func (r *MockLoanRepository) GetLoanInvestments(ctx context.Context, loanID stri
 
 	loan, exists := r.loans[loanID]
 	if !exists {
-		return nil, errors.New("loan not found")
+		return nil, ErrLoanNotFound
 	}
 
-	return loan.Investments, nil
+	return cloneLoan(loan).Investments, nil
 }
 
 func (r *MockLoanRepository) SaveDisbursement(ctx context.Context, disbursement *domain.Disbursement) error {
@@ -138,11 +228,667 @@ func (r *MockLoanRepository) SaveDisbursement(ctx context.Context, disbursement
 
 	loan, exists := r.loans[disbursement.LoanID]
 	if !exists {
-		return errors.New("loan not found")
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "loans", r.loans, disbursement.LoanID, cloneLoan)
+	remember(r.undo, "disbursements", r.disbursements, disbursement.LoanID, cloneRecord[domain.Disbursement])
+	stored := *disbursement
+	r.disbursements[disbursement.LoanID] = &stored
+	loan.Disbursement = &stored
+
+	return nil
+}
+
+func (r *MockLoanRepository) SaveRepaymentSchedule(ctx context.Context, schedule *domain.RepaymentSchedule) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.loans[schedule.LoanID]; !exists {
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "schedules", r.schedules, schedule.LoanID, cloneSchedule)
+	r.schedules[schedule.LoanID] = cloneSchedule(schedule)
+
+	return nil
+}
+
+func (r *MockLoanRepository) GetRepaymentSchedule(ctx context.Context, loanID string) (*domain.RepaymentSchedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schedule, exists := r.schedules[loanID]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	return cloneSchedule(schedule), nil
+}
+
+func (r *MockLoanRepository) SaveRepayment(ctx context.Context, repayment *domain.Repayment) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.loans[repayment.LoanID]; !exists {
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "repayments", r.repayments, repayment.LoanID, cloneRepayments)
+	stored := *repayment
+	repayments := r.repayments[repayment.LoanID]
+	for i, existing := range repayments {
+		if existing.ID == repayment.ID {
+			repayments[i] = &stored
+			return nil
+		}
+	}
+	r.repayments[repayment.LoanID] = append(repayments, &stored)
+
+	return nil
+}
+
+func (r *MockLoanRepository) GetLoanRepayments(ctx context.Context, loanID string) ([]*domain.Repayment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.loans[loanID]; !exists {
+		return nil, ErrLoanNotFound
+	}
+
+	repayments := cloneRepayments(r.repayments[loanID])
+	sort.SliceStable(repayments, func(i, j int) bool {
+		return repayments[i].PaidAt.Before(repayments[j].PaidAt)
+	})
+
+	return repayments, nil
+}
+
+func (r *MockLoanRepository) SavePayout(ctx context.Context, payout *domain.Payout) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.loans[payout.LoanID]; !exists {
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "payouts", r.payouts, payout.LoanID, clonePayouts)
+	stored := *payout
+	payouts := r.payouts[payout.LoanID]
+	for i, existing := range payouts {
+		if existing.ID == payout.ID {
+			payouts[i] = &stored
+			return nil
+		}
+	}
+	r.payouts[payout.LoanID] = append(payouts, &stored)
+
+	return nil
+}
+
+func (r *MockLoanRepository) GetLoanPayouts(ctx context.Context, loanID string) ([]*domain.Payout, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.loans[loanID]; !exists {
+		return nil, ErrLoanNotFound
+	}
+
+	return clonePayouts(r.payouts[loanID]), nil
+}
+
+func (r *MockLoanRepository) SaveRejection(ctx context.Context, rejection *domain.Rejection) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	loan, exists := r.loans[rejection.LoanID]
+	if !exists {
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "loans", r.loans, rejection.LoanID, cloneLoan)
+	remember(r.undo, "rejections", r.rejections, rejection.LoanID, cloneRecord[domain.Rejection])
+	stored := *rejection
+	r.rejections[rejection.LoanID] = &stored
+	loan.Rejection = &stored
+
+	return nil
+}
+
+func (r *MockLoanRepository) SaveCancellation(ctx context.Context, cancellation *domain.Cancellation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	loan, exists := r.loans[cancellation.LoanID]
+	if !exists {
+		return ErrLoanNotFound
+	}
+
+	remember(r.undo, "loans", r.loans, cancellation.LoanID, cloneLoan)
+	remember(r.undo, "cancellations", r.cancellations, cancellation.LoanID, cloneRecord[domain.Cancellation])
+	stored := *cancellation
+	r.cancellations[cancellation.LoanID] = &stored
+	loan.Cancellation = &stored
+
+	return nil
+}
+
+func (r *MockLoanRepository) SaveOutboxMessage(ctx context.Context, message *domain.OutboxMessage) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remember(r.undo, "outbox", r.outbox, message.ID, cloneOutboxMessage)
+	r.outbox[message.ID] = cloneOutboxMessage(message)
+
+	return nil
+}
+
+func (r *MockLoanRepository) GetOutboxMessage(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	message, exists := r.outbox[id]
+	if !exists {
+		return nil, ErrOutboxMessageNotFound
+	}
+
+	return cloneOutboxMessage(message), nil
+}
+
+func (r *MockLoanRepository) ListOutboxMessages(ctx context.Context, status domain.OutboxStatus, page, pageSize int) ([]*domain.OutboxMessage, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := []*domain.OutboxMessage{}
+	for _, message := range r.outbox {
+		if status == "" || message.Status == status {
+			result = append(result, cloneOutboxMessage(message))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	total := len(result)
+	if page > 0 && pageSize > 0 {
+		start := (page - 1) * pageSize
+		if start >= total {
+			return []*domain.OutboxMessage{}, total, nil
+		}
+
+		end := start + pageSize
+		if end > total {
+			end = total
+		}
+		result = result[start:end]
+	}
+
+	return result, total, nil
+}
+
+func (r *MockLoanRepository) ListDueOutboxMessages(ctx context.Context, asOf time.Time, limit int) ([]*domain.OutboxMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	due := []*domain.OutboxMessage{}
+	for _, message := range r.outbox {
+		if message.Status == domain.OutboxStatusPending && !message.NextAttemptAt.After(asOf) {
+			due = append(due, cloneOutboxMessage(message))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+func (r *MockLoanRepository) SaveWebhookSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remember(r.undo, "webhooks", r.webhooks, subscription.ID, cloneWebhookSubscription)
+	r.webhooks[subscription.ID] = cloneWebhookSubscription(subscription)
+
+	return nil
+}
+
+func (r *MockLoanRepository) GetWebhookSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	subscription, exists := r.webhooks[id]
+	if !exists {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+
+	return cloneWebhookSubscription(subscription), nil
+}
+
+func (r *MockLoanRepository) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := []*domain.WebhookSubscription{}
+	for _, subscription := range r.webhooks {
+		result = append(result, cloneWebhookSubscription(subscription))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (r *MockLoanRepository) SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.webhooks[delivery.SubscriptionID]; !exists {
+		return ErrWebhookSubscriptionNotFound
+	}
+
+	remember(r.undo, "deliveries", r.deliveries, delivery.SubscriptionID, cloneWebhookDeliveries)
+	clone := *delivery
+	r.deliveries[delivery.SubscriptionID] = append(r.deliveries[delivery.SubscriptionID], &clone)
+
+	return nil
+}
+
+func (r *MockLoanRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, page, pageSize int) ([]*domain.WebhookDelivery, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.webhooks[subscriptionID]; !exists {
+		return nil, 0, ErrWebhookSubscriptionNotFound
+	}
+
+	result := cloneWebhookDeliveries(r.deliveries[subscriptionID])
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].AttemptedAt.Equal(result[j].AttemptedAt) {
+			return result[i].AttemptedAt.After(result[j].AttemptedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	total := len(result)
+	if page > 0 && pageSize > 0 {
+		start := (page - 1) * pageSize
+		if start >= total {
+			return []*domain.WebhookDelivery{}, total, nil
+		}
+
+		end := start + pageSize
+		if end > total {
+			end = total
+		}
+		result = result[start:end]
 	}
 
-	r.disbursements[disbursement.LoanID] = disbursement
-	loan.Disbursement = disbursement
+	return result, total, nil
+}
+
+func (r *MockLoanRepository) AppendLoanEvents(ctx context.Context, events ...*domain.LoanEvent) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	histories := make(map[string][]json.RawMessage)
+	for _, event := range events {
+		if _, exists := r.loans[event.LoanID]; !exists {
+			return ErrLoanNotFound
+		}
+
+		history, exists := histories[event.LoanID]
+		if !exists {
+			history = r.events[event.LoanID]
+		}
+
+		event.Sequence = len(history) + 1
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		histories[event.LoanID] = append(history, encoded)
+	}
+
+	for loanID, history := range histories {
+		remember(r.undo, "events", r.events, loanID, cloneEvents)
+		r.events[loanID] = history
+	}
 
 	return nil
 }
+
+func (r *MockLoanRepository) GetLoanEvents(ctx context.Context, loanID string) ([]*domain.LoanEvent, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.loans[loanID]; !exists {
+		return nil, ErrLoanNotFound
+	}
+
+	events := make([]*domain.LoanEvent, len(r.events[loanID]))
+	for i, encoded := range r.events[loanID] {
+		events[i] = &domain.LoanEvent{}
+		if err := json.Unmarshal(encoded, events[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+func (r *MockLoanRepository) AppendAuditEntry(ctx context.Context, entry *domain.AuditEntry) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if entry.Sequence != int64(len(r.audit)+1) {
+		return fmt.Errorf("audit entry %d is out of sequence", entry.Sequence)
+	}
+
+	r.audit = append(r.audit, cloneAuditEntry(entry))
+	return nil
+}
+
+func (r *MockLoanRepository) LastAuditEntry(ctx context.Context) (*domain.AuditEntry, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if len(r.audit) == 0 {
+		return nil, nil
+	}
+	return cloneAuditEntry(r.audit[len(r.audit)-1]), nil
+}
+
+func (r *MockLoanRepository) ListAuditEntries(ctx context.Context, filter domain.AuditFilter, page, pageSize int) ([]*domain.AuditEntry, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := []*domain.AuditEntry{}
+	for _, entry := range r.audit {
+		if filter.Matches(entry) {
+			result = append(result, cloneAuditEntry(entry))
+		}
+	}
+
+	total := len(result)
+	if page > 0 && pageSize > 0 {
+		start := (page - 1) * pageSize
+		if start >= total {
+			return []*domain.AuditEntry{}, total, nil
+		}
+
+		end := start + pageSize
+		if end > total {
+			end = total
+		}
+		result = result[start:end]
+	}
+
+	return result, total, nil
+}
+
+func (r *MockLoanRepository) ReserveIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := idempotencyKey{record.Actor, record.Key}
+	if existing, exists := r.idempotency[key]; exists && !existing.Expired(record.CreatedAt) {
+		return cloneIdempotencyRecord(existing), nil
+	}
+
+	remember(r.undo, "idempotency", r.idempotency, key, cloneIdempotencyRecord)
+	r.idempotency[key] = cloneIdempotencyRecord(record)
+	return nil, nil
+}
+
+func (r *MockLoanRepository) SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := idempotencyKey{record.Actor, record.Key}
+	remember(r.undo, "idempotency", r.idempotency, key, cloneIdempotencyRecord)
+	r.idempotency[key] = cloneIdempotencyRecord(record)
+	return nil
+}
+
+func (r *MockLoanRepository) DeleteIdempotencyRecord(ctx context.Context, actor, key string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remember(r.undo, "idempotency", r.idempotency, idempotencyKey{actor, key}, cloneIdempotencyRecord)
+	delete(r.idempotency, idempotencyKey{actor, key})
+	return nil
+}
+
+func (r *MockLoanRepository) PurgeExpiredIdempotencyRecords(ctx context.Context, asOf time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	purged := 0
+	for key, record := range r.idempotency {
+		if record.Expired(asOf) {
+			remember(r.undo, "idempotency", r.idempotency, key, cloneIdempotencyRecord)
+			delete(r.idempotency, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// WithinTx runs fn against the repository state directly, recording the
+// records it changes in an undo log that restores them if fn fails. The
+// write lock is held for the duration, so transactions are serialised
+// against each other and against other writes, and no one sees a change
+// that is later undone.
+func (r *MockLoanRepository) WithinTx(ctx context.Context, fn func(repo LoanRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tx := &MockLoanRepository{
+		loans:         r.loans,
+		approvals:     r.approvals,
+		investments:   r.investments,
+		disbursements: r.disbursements,
+		rejections:    r.rejections,
+		cancellations: r.cancellations,
+		schedules:     r.schedules,
+		repayments:    r.repayments,
+		payouts:       r.payouts,
+		outbox:        r.outbox,
+		webhooks:      r.webhooks,
+		deliveries:    r.deliveries,
+		events:        r.events,
+		audit:         r.audit,
+		idempotency:   r.idempotency,
+		inTx:          true,
+		undo:          &undoLog{touched: make(map[undoKey]bool)},
+	}
+	if err := fn(tx); err != nil {
+		tx.undo.rollback()
+		return err
+	}
+
+	// The trail is only ever appended to, so entries appended by a failed
+	// unit of work are dropped by keeping the old length
+	r.audit = tx.audit
+	return nil
+}
+
+// undoLog restores the records a unit of work changed. Each record is copied
+// the first time it is changed, rather than copying the whole store up front.
+type undoLog struct {
+	touched  map[undoKey]bool
+	restores []func()
+}
+
+// undoKey identifies a record by the map holding it and its key there
+type undoKey struct {
+	table string
+	key   interface{}
+}
+
+// remember records how to restore m[key], or remove it if it does not
+// exist yet, unless the unit of work has already changed it. Outside a unit
+// of work undo is nil and nothing is recorded.
+func remember[K comparable, V any](undo *undoLog, table string, m map[K]V, key K, clone func(V) V) {
+	if undo == nil || undo.touched[undoKey{table, key}] {
+		return
+	}
+	undo.touched[undoKey{table, key}] = true
+
+	previous, existed := m[key]
+	if existed {
+		previous = clone(previous)
+	}
+	undo.restores = append(undo.restores, func() {
+		if existed {
+			m[key] = previous
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+func (u *undoLog) rollback() {
+	for i := len(u.restores) - 1; i >= 0; i-- {
+		u.restores[i]()
+	}
+}
+
+// cloneLoan copies the loan's state but not its pending events, which are
+// only appended to the history by whoever raised them
+func cloneLoan(loan *domain.Loan) *domain.Loan {
+	clone := *loan
+	clone.ClearPendingEvents()
+
+	if loan.Approval != nil {
+		approval := *loan.Approval
+		clone.Approval = &approval
+	}
+
+	clone.Investments = make([]*domain.Investment, len(loan.Investments))
+	for i, inv := range loan.Investments {
+		investment := *inv
+		if inv.RefundedAt != nil {
+			refundedAt := *inv.RefundedAt
+			investment.RefundedAt = &refundedAt
+		}
+		clone.Investments[i] = &investment
+	}
+
+	if loan.Disbursement != nil {
+		disbursement := *loan.Disbursement
+		clone.Disbursement = &disbursement
+	}
+
+	if loan.Rejection != nil {
+		rejection := *loan.Rejection
+		clone.Rejection = &rejection
+	}
+
+	if loan.Cancellation != nil {
+		cancellation := *loan.Cancellation
+		clone.Cancellation = &cancellation
+	}
+
+	return &clone
+}
+
+func cloneSchedule(schedule *domain.RepaymentSchedule) *domain.RepaymentSchedule {
+	clone := *schedule
+
+	clone.Installments = make([]*domain.Installment, len(schedule.Installments))
+	for i, inst := range schedule.Installments {
+		installment := *inst
+		clone.Installments[i] = &installment
+	}
+
+	return &clone
+}
+
+func cloneRepayments(repayments []*domain.Repayment) []*domain.Repayment {
+	clones := make([]*domain.Repayment, len(repayments))
+	for i, rep := range repayments {
+		repayment := *rep
+		clones[i] = &repayment
+	}
+	return clones
+}
+
+func clonePayouts(payouts []*domain.Payout) []*domain.Payout {
+	clones := make([]*domain.Payout, len(payouts))
+	for i, p := range payouts {
+		payout := *p
+		clones[i] = &payout
+	}
+	return clones
+}
+
+func cloneOutboxMessage(message *domain.OutboxMessage) *domain.OutboxMessage {
+	clone := *message
+	clone.Payload = append([]byte(nil), message.Payload...)
+
+	if message.DeliveredAt != nil {
+		deliveredAt := *message.DeliveredAt
+		clone.DeliveredAt = &deliveredAt
+	}
+
+	return &clone
+}
+
+func cloneWebhookSubscription(subscription *domain.WebhookSubscription) *domain.WebhookSubscription {
+	clone := *subscription
+	clone.Events = append([]domain.WebhookEventType{}, subscription.Events...)
+	return &clone
+}
+
+func cloneWebhookDeliveries(deliveries []*domain.WebhookDelivery) []*domain.WebhookDelivery {
+	clones := make([]*domain.WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		delivery := *d
+		clones[i] = &delivery
+	}
+	return clones
+}
+
+func cloneAuditEntry(entry *domain.AuditEntry) *domain.AuditEntry {
+	clone := *entry
+	clone.Before = append(json.RawMessage(nil), entry.Before...)
+	clone.After = append(json.RawMessage(nil), entry.After...)
+	return &clone
+}
+
+// cloneRecord copies a record shallowly, which is enough for records that are
+// replaced rather than changed in place
+func cloneRecord[T any](record *T) *T {
+	clone := *record
+	return &clone
+}
+
+func cloneEvents(events []json.RawMessage) []json.RawMessage {
+	return append([]json.RawMessage(nil), events...)
+}
+
+func cloneIdempotencyRecord(record *domain.IdempotencyRecord) *domain.IdempotencyRecord {
+	clone := *record
+	clone.Body = append([]byte(nil), record.Body...)
+	return &clone
+}
@@ -3,13 +3,20 @@ package repository
 import (
 	"context"
 	"loan/internal/domain"
+	"time"
 )
 
 // LoanRepository defines the interface for loan data operations
 type LoanRepository interface {
 	SaveLoan(ctx context.Context, loan *domain.Loan) error
 	GetLoanByID(ctx context.Context, id string) (*domain.Loan, error)
-	ListLoans(ctx context.Context, page, pageSize int) ([]*domain.Loan, int, error)
+	ListLoans(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, page, pageSize int) ([]*domain.Loan, int, error)
+	// ListLoansAfter returns up to limit loans listed after the cursor, or
+	// from the first loan when after is nil, and how many match the filter
+	ListLoansAfter(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, after *domain.LoanCursor, limit int) ([]*domain.Loan, int, error)
+	// SummarizeLoans returns principal and invested totals per currency of
+	// the loans matching the filter
+	SummarizeLoans(ctx context.Context, filter domain.LoanFilter) ([]*domain.CurrencyTotal, error)
 
 	SaveApproval(ctx context.Context, approval *domain.Approval) error
 
@@ -17,4 +24,71 @@ type LoanRepository interface {
 	GetLoanInvestments(ctx context.Context, loanID string) ([]*domain.Investment, error)
 
 	SaveDisbursement(ctx context.Context, disbursement *domain.Disbursement) error
+
+	// SaveRepaymentSchedule stores schedule, replacing any previous schedule
+	// for the same loan
+	SaveRepaymentSchedule(ctx context.Context, schedule *domain.RepaymentSchedule) error
+	GetRepaymentSchedule(ctx context.Context, loanID string) (*domain.RepaymentSchedule, error)
+
+	SaveRepayment(ctx context.Context, repayment *domain.Repayment) error
+	// GetLoanRepayments returns the loan's repayments ordered by payment date
+	GetLoanRepayments(ctx context.Context, loanID string) ([]*domain.Repayment, error)
+
+	SavePayout(ctx context.Context, payout *domain.Payout) error
+	// GetLoanPayouts returns the loan's payout ledger in the order it was written
+	GetLoanPayouts(ctx context.Context, loanID string) ([]*domain.Payout, error)
+
+	SaveRejection(ctx context.Context, rejection *domain.Rejection) error
+	SaveCancellation(ctx context.Context, cancellation *domain.Cancellation) error
+
+	SaveOutboxMessage(ctx context.Context, message *domain.OutboxMessage) error
+	GetOutboxMessage(ctx context.Context, id string) (*domain.OutboxMessage, error)
+	// ListOutboxMessages pages through messages oldest first, optionally
+	// restricted to one status ("" for all)
+	ListOutboxMessages(ctx context.Context, status domain.OutboxStatus, page, pageSize int) ([]*domain.OutboxMessage, int, error)
+	// ListDueOutboxMessages returns up to limit PENDING messages whose next
+	// attempt is due at asOf, earliest first
+	ListDueOutboxMessages(ctx context.Context, asOf time.Time, limit int) ([]*domain.OutboxMessage, error)
+
+	SaveWebhookSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	// ListWebhookSubscriptions returns every subscription, oldest first
+	ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// ListWebhookDeliveries pages through a subscription's delivery attempts,
+	// most recent first
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, page, pageSize int) ([]*domain.WebhookDelivery, int, error)
+
+	// AppendLoanEvents adds events to the end of their loans' histories,
+	// setting each event's Sequence. Events are never updated or removed.
+	AppendLoanEvents(ctx context.Context, events ...*domain.LoanEvent) error
+	// GetLoanEvents returns the loan's history in sequence order
+	GetLoanEvents(ctx context.Context, loanID string) ([]*domain.LoanEvent, error)
+
+	// AppendAuditEntry stores an entry already chained to LastAuditEntry.
+	// Entries are never updated or removed.
+	AppendAuditEntry(ctx context.Context, entry *domain.AuditEntry) error
+	// LastAuditEntry returns the most recent entry, or nil when the trail is
+	// empty. Within WithinTx it also holds the trail until the unit of work
+	// ends, so concurrent units of work append their entries in turn.
+	LastAuditEntry(ctx context.Context) (*domain.AuditEntry, error)
+	// ListAuditEntries pages through the trail in sequence order
+	ListAuditEntries(ctx context.Context, filter domain.AuditFilter, page, pageSize int) ([]*domain.AuditEntry, int, error)
+
+	// ReserveIdempotencyKey stores record unless its actor already holds a
+	// record with the same key, in which case the stored record is returned
+	// and nothing is written. Expired records are replaced.
+	ReserveIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	// SaveIdempotencyRecord stores the response of a reserved record
+	SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error
+	// DeleteIdempotencyRecord releases a key so that it can be used again
+	DeleteIdempotencyRecord(ctx context.Context, actor, key string) error
+	// PurgeExpiredIdempotencyRecords deletes the records that have expired as
+	// of asOf, returning how many were deleted
+	PurgeExpiredIdempotencyRecords(ctx context.Context, asOf time.Time) (int, error)
+
+	// WithinTx runs fn as a single unit of work: every write made through the
+	// repo passed to fn is committed together when fn returns nil, or discarded
+	// when it returns an error. fn must only use the repo it is given.
+	WithinTx(ctx context.Context, fn func(repo LoanRepository) error) error
 }
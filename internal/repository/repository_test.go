@@ -0,0 +1,1204 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// mustMoney parses an IDR amount, panicking on bad input
+func mustMoney(amount string) domain.Money {
+	money, err := domain.ParseMoney(amount, "IDR")
+	if err != nil {
+		panic(err)
+	}
+	return money
+}
+
+// forEachRepository runs the same behavioural test against every
+// LoanRepository implementation so the backends stay interchangeable.
+func forEachRepository(t *testing.T, test func(t *testing.T, repo repository.LoanRepository)) {
+	t.Run("mock", func(t *testing.T) {
+		test(t, repository.NewMockLoanRepository())
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := repository.OpenSQLDatabase(context.Background(), "sqlite", "file::memory:?_pragma=foreign_keys(1)")
+		if err != nil {
+			t.Fatalf("Expected to open sqlite database, got %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		test(t, repository.NewSQLLoanRepository(db))
+	})
+}
+
+func TestSaveAndGetLoan(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+
+		if err := repo.SaveLoan(ctx, loan); err != nil {
+			t.Fatalf("Expected no error saving loan, got %v", err)
+		}
+
+		saved, err := repo.GetLoanByID(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected to retrieve loan, got %v", err)
+		}
+
+		if saved.BorrowerID != "borrower123" || saved.PrincipalAmount != mustMoney("1000.00") || saved.Rate != 0.1 || saved.ROI != 0.08 {
+			t.Errorf("Expected saved loan fields to round-trip, got %+v", saved)
+		}
+		if saved.State != domain.LoanStateProposed {
+			t.Errorf("Expected state PROPOSED, got %s", saved.State)
+		}
+		if !saved.CreatedAt.Equal(loan.CreatedAt) {
+			t.Errorf("Expected CreatedAt %v, got %v", loan.CreatedAt, saved.CreatedAt)
+		}
+
+		saved.State = domain.LoanStateApproved
+		saved.AgreementLetterURL = "letter.pdf"
+		if err := repo.SaveLoan(ctx, saved); err != nil {
+			t.Fatalf("Expected no error updating loan, got %v", err)
+		}
+
+		updated, _ := repo.GetLoanByID(ctx, loan.ID)
+		if updated.State != domain.LoanStateApproved || updated.AgreementLetterURL != "letter.pdf" {
+			t.Errorf("Expected update to be persisted, got %+v", updated)
+		}
+	})
+}
+
+func TestGetLoanNotFound(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		_, err := repo.GetLoanByID(context.Background(), "missing")
+		if err == nil || err.Error() != "loan not found" {
+			t.Errorf("Expected loan not found error, got %v", err)
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Expected the error to match domain.ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestListLoans(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		for i := 0; i < 5; i++ {
+			if err := repo.SaveLoan(ctx, domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)); err != nil {
+				t.Fatalf("Expected no error saving loan, got %v", err)
+			}
+		}
+
+		all, total, err := repo.ListLoans(ctx, domain.LoanFilter{}, domain.LoanSort{}, 0, 0)
+		if err != nil {
+			t.Fatalf("Expected no error listing loans, got %v", err)
+		}
+		if total != 5 || len(all) != 5 {
+			t.Errorf("Expected 5 loans, got %d (total %d)", len(all), total)
+		}
+
+		page, total, _ := repo.ListLoans(ctx, domain.LoanFilter{}, domain.LoanSort{}, 2, 2)
+		if total != 5 || len(page) != 2 {
+			t.Errorf("Expected 2 loans on page 2 of 5, got %d (total %d)", len(page), total)
+		}
+
+		last, _, _ := repo.ListLoans(ctx, domain.LoanFilter{}, domain.LoanSort{}, 3, 2)
+		if len(last) != 1 {
+			t.Errorf("Expected 1 loan on last page, got %d", len(last))
+		}
+
+		beyond, _, _ := repo.ListLoans(ctx, domain.LoanFilter{}, domain.LoanSort{}, 4, 2)
+		if len(beyond) != 0 {
+			t.Errorf("Expected no loans beyond last page, got %d", len(beyond))
+		}
+	})
+}
+
+func TestSaveApproval(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", time.Now())
+		if err := repo.SaveApproval(ctx, approval); err != nil {
+			t.Fatalf("Expected no error saving approval, got %v", err)
+		}
+
+		saved, _ := repo.GetLoanByID(ctx, loan.ID)
+		if saved.Approval == nil {
+			t.Fatal("Expected approval to be attached to loan, got nil")
+		}
+		if saved.Approval.ProofPictureURL != "proof.jpg" || saved.Approval.FieldValidatorID != "validator123" {
+			t.Errorf("Expected approval fields to round-trip, got %+v", saved.Approval)
+		}
+
+		orphan, _ := domain.NewApproval("missing", "proof.jpg", "validator123", time.Now())
+		if err := repo.SaveApproval(ctx, orphan); err == nil {
+			t.Error("Expected error saving approval for missing loan, got nil")
+		}
+	})
+}
+
+func TestSaveInvestment(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		first, _ := domain.NewInvestment(loan.ID, "investor1", mustMoney("400.00"))
+		second, _ := domain.NewInvestment(loan.ID, "investor2", mustMoney("600.00"))
+		for _, inv := range []*domain.Investment{first, second} {
+			if err := repo.SaveInvestment(ctx, inv); err != nil {
+				t.Fatalf("Expected no error saving investment, got %v", err)
+			}
+		}
+
+		investments, err := repo.GetLoanInvestments(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected no error getting investments, got %v", err)
+		}
+		if len(investments) != 2 {
+			t.Fatalf("Expected 2 investments, got %d", len(investments))
+		}
+
+		total := domain.ZeroMoney("IDR")
+		for _, inv := range investments {
+			total, _ = total.Add(inv.Amount)
+		}
+		if total != mustMoney("1000.00") {
+			t.Errorf("Expected total investment 1000.00, got %s", total)
+		}
+
+		saved, _ := repo.GetLoanByID(ctx, loan.ID)
+		if len(saved.Investments) != 2 {
+			t.Errorf("Expected loan to carry 2 investments, got %d", len(saved.Investments))
+		}
+
+		if _, err := repo.GetLoanInvestments(ctx, "missing"); err == nil {
+			t.Error("Expected error getting investments for missing loan, got nil")
+		}
+	})
+}
+
+func TestSaveDisbursement(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		disbursement, _ := domain.NewDisbursement(loan.ID, "agreement.pdf", "officer123", time.Now())
+		if err := repo.SaveDisbursement(ctx, disbursement); err != nil {
+			t.Fatalf("Expected no error saving disbursement, got %v", err)
+		}
+
+		saved, _ := repo.GetLoanByID(ctx, loan.ID)
+		if saved.Disbursement == nil {
+			t.Fatal("Expected disbursement to be attached to loan, got nil")
+		}
+		if saved.Disbursement.AgreementDocumentURL != "agreement.pdf" || saved.Disbursement.FieldOfficerID != "officer123" {
+			t.Errorf("Expected disbursement fields to round-trip, got %+v", saved.Disbursement)
+		}
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	db, err := repository.OpenSQLDatabase(ctx, "sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("Expected to open sqlite database, got %v", err)
+	}
+	defer db.Close()
+
+	if err := repository.Migrate(ctx, db); err != nil {
+		t.Fatalf("Expected re-running migrations to succeed, got %v", err)
+	}
+
+	var applied int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("Expected to read schema_migrations, got %v", err)
+	}
+	if applied == 0 {
+		t.Error("Expected migrations to be recorded, got none")
+	}
+}
+
+func TestWithinTx(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		// A failing unit of work leaves no trace
+		err := repo.WithinTx(ctx, func(tx repository.LoanRepository) error {
+			approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", time.Now())
+			if err := tx.SaveApproval(ctx, approval); err != nil {
+				return err
+			}
+			if err := tx.SaveLoan(ctx, domain.NewLoan("borrower456", mustMoney("500.00"), 0.1, 0.08)); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		})
+		if err == nil || err.Error() != "boom" {
+			t.Fatalf("Expected WithinTx to return fn error, got %v", err)
+		}
+
+		saved, _ := repo.GetLoanByID(ctx, loan.ID)
+		if saved.Approval != nil {
+			t.Error("Expected approval to be rolled back, got one")
+		}
+		if _, total, _ := repo.ListLoans(ctx, domain.LoanFilter{}, domain.LoanSort{}, 0, 0); total != 1 {
+			t.Errorf("Expected loan insert to be rolled back, got %d loans", total)
+		}
+
+		// A successful unit of work commits every write
+		err = repo.WithinTx(ctx, func(tx repository.LoanRepository) error {
+			txLoan, err := tx.GetLoanByID(ctx, loan.ID)
+			if err != nil {
+				return err
+			}
+			approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator123", time.Now())
+			if err := txLoan.Approve(approval); err != nil {
+				return err
+			}
+			if err := tx.SaveApproval(ctx, approval); err != nil {
+				return err
+			}
+			return tx.SaveLoan(ctx, txLoan)
+		})
+		if err != nil {
+			t.Fatalf("Expected WithinTx to commit, got %v", err)
+		}
+
+		saved, _ = repo.GetLoanByID(ctx, loan.ID)
+		if saved.State != domain.LoanStateApproved || saved.Approval == nil {
+			t.Errorf("Expected approved loan with approval after commit, got %+v", saved)
+		}
+	})
+}
+
+func TestWithinTxRollsBackChangesToExistingRecords(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+		investment, _ := domain.NewInvestment(loan.ID, "investor1", mustMoney("1000.00"))
+		_ = repo.SaveInvestment(ctx, investment)
+		repayment, _ := domain.NewRepayment(loan.ID, mustMoney("100.00"), time.Now())
+		_ = repo.SaveRepayment(ctx, repayment)
+		message, _ := domain.NewOutboxMessage(domain.TopicNotification, domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor1", loan.ID))
+		_ = repo.SaveOutboxMessage(ctx, message)
+		_ = repo.AppendLoanEvents(ctx, loan.PendingEvents()...)
+
+		err := repo.WithinTx(ctx, func(tx repository.LoanRepository) error {
+			refunded := *investment
+			refunded.Refund(time.Now())
+			corrected := *repayment
+			corrected.Amount = mustMoney("150.00")
+			delivered := *message
+			delivered.MarkDelivered(time.Now())
+			entry := domain.NewAuditEntry(ctx, domain.AuditCreateLoan, loan.ID, nil, []byte(`{}`))
+			entry.Chain(nil)
+
+			for _, err := range []error{
+				tx.SaveInvestment(ctx, &refunded),
+				tx.SaveRepayment(ctx, &corrected),
+				tx.SaveOutboxMessage(ctx, &delivered),
+				tx.AppendLoanEvents(ctx, domain.NewLoanEvent(loan.ID, &domain.AgreementLetterAttached{URL: "https://example.com/letter.pdf"})),
+				tx.AppendAuditEntry(ctx, entry),
+			} {
+				if err != nil {
+					return err
+				}
+			}
+			return errors.New("boom")
+		})
+
+		if err == nil || err.Error() != "boom" {
+			t.Fatalf("Expected WithinTx to return fn error, got %v", err)
+		}
+		if investments, _ := repo.GetLoanInvestments(ctx, loan.ID); len(investments) != 1 || investments[0].Status != domain.InvestmentStatusActive {
+			t.Errorf("Expected the refund to be rolled back, got %+v", investments)
+		}
+		if repayments, _ := repo.GetLoanRepayments(ctx, loan.ID); len(repayments) != 1 || repayments[0].Amount != mustMoney("100.00") {
+			t.Errorf("Expected the corrected repayment to be rolled back, got %+v", repayments)
+		}
+		if saved, _ := repo.GetOutboxMessage(ctx, message.ID); saved.Status != domain.OutboxStatusPending {
+			t.Errorf("Expected the delivery to be rolled back, got %s", saved.Status)
+		}
+		if events, _ := repo.GetLoanEvents(ctx, loan.ID); len(events) != 1 {
+			t.Errorf("Expected the appended event to be rolled back, got %d events", len(events))
+		}
+		if last, _ := repo.LastAuditEntry(ctx); last != nil {
+			t.Errorf("Expected the audit entry to be rolled back, got %+v", last)
+		}
+	})
+}
+
+func TestSaveLoanVersionConflict(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)
+		if err := repo.SaveLoan(ctx, loan); err != nil {
+			t.Fatalf("Expected no error saving loan, got %v", err)
+		}
+		if loan.Version != 1 {
+			t.Errorf("Expected version 1 after first save, got %d", loan.Version)
+		}
+
+		first, _ := repo.GetLoanByID(ctx, loan.ID)
+		second, _ := repo.GetLoanByID(ctx, loan.ID)
+
+		// Mutating a returned loan must not leak into the store
+		first.State = domain.LoanStateApproved
+		if reread, _ := repo.GetLoanByID(ctx, loan.ID); reread.State != domain.LoanStateProposed {
+			t.Errorf("Expected stored loan to be unaffected by caller mutation, got %s", reread.State)
+		}
+
+		if err := repo.SaveLoan(ctx, first); err != nil {
+			t.Fatalf("Expected first writer to succeed, got %v", err)
+		}
+
+		second.State = domain.LoanStateApproved
+		if err := repo.SaveLoan(ctx, second); !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("Expected stale writer to get ErrConflict, got %v", err)
+		}
+
+		if err := repo.SaveLoan(ctx, domain.NewLoan("borrower123", mustMoney("1000.00"), 0.1, 0.08)); err != nil {
+			t.Errorf("Expected new loan to save, got %v", err)
+		}
+
+		duplicate := *loan
+		duplicate.Version = 0
+		if err := repo.SaveLoan(ctx, &duplicate); !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("Expected re-inserting an existing loan to conflict, got %v", err)
+		}
+	})
+}
+
+func TestSummarizeLoans(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		usd, _ := domain.ParseMoney("250.00", "USD")
+
+		idrLoan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, idrLoan)
+		_ = repo.SaveLoan(ctx, domain.NewLoan("borrower2", mustMoney("500.00"), 0.1, 0.08))
+		_ = repo.SaveLoan(ctx, domain.NewLoan("borrower3", usd, 0.1, 0.08))
+
+		investment, _ := domain.NewInvestment(idrLoan.ID, "investor1", mustMoney("400.00"))
+		_ = repo.SaveInvestment(ctx, investment)
+
+		totals, err := repo.SummarizeLoans(ctx, domain.LoanFilter{})
+		if err != nil {
+			t.Fatalf("Expected no error summarizing loans, got %v", err)
+		}
+		if len(totals) != 2 {
+			t.Fatalf("Expected totals for 2 currencies, got %d", len(totals))
+		}
+
+		idr := totals[0]
+		if idr.Currency != "IDR" || idr.LoanCount != 2 || idr.PrincipalAmount != mustMoney("1500.00") || idr.TotalInvested != mustMoney("400.00") {
+			t.Errorf("Unexpected IDR totals %+v", idr)
+		}
+
+		usdTotal := totals[1]
+		if usdTotal.Currency != "USD" || usdTotal.LoanCount != 1 || usdTotal.PrincipalAmount != usd || !usdTotal.TotalInvested.IsZero() {
+			t.Errorf("Unexpected USD totals %+v", usdTotal)
+		}
+
+		filtered, err := repo.SummarizeLoans(ctx, domain.LoanFilter{BorrowerID: "borrower1"})
+		if err != nil {
+			t.Fatalf("Expected no error summarizing filtered loans, got %v", err)
+		}
+		if len(filtered) != 1 || filtered[0].LoanCount != 1 || filtered[0].PrincipalAmount != mustMoney("1000.00") || filtered[0].TotalInvested != mustMoney("400.00") {
+			t.Errorf("Expected totals of borrower1's loan only, got %+v", filtered)
+		}
+
+		funded := 30.0
+		filtered, _ = repo.SummarizeLoans(ctx, domain.LoanFilter{MinFundedPercent: &funded})
+		if len(filtered) != 1 || filtered[0].LoanCount != 1 {
+			t.Errorf("Expected totals of the funded loan only, got %+v", filtered)
+		}
+	})
+}
+
+func TestSummarizeLoansIgnoresRefundedInvestments(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		funded := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		cancelled := domain.NewLoan("borrower2", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, funded)
+		_ = repo.SaveLoan(ctx, cancelled)
+
+		active, _ := domain.NewInvestment(funded.ID, "investor1", mustMoney("300.00"))
+		_ = repo.SaveInvestment(ctx, active)
+		refunded, _ := domain.NewInvestment(cancelled.ID, "investor1", mustMoney("400.00"))
+		_ = repo.SaveInvestment(ctx, refunded)
+
+		cancellation, _ := domain.NewCancellation(cancelled.ID, "borrower withdrew", "borrower2", time.Now())
+		_ = repo.SaveCancellation(ctx, cancellation)
+		refunded.Refund(cancellation.CancelledAt)
+		if err := repo.SaveInvestment(ctx, refunded); err != nil {
+			t.Fatalf("Expected no error saving refunded investment, got %v", err)
+		}
+
+		totals, err := repo.SummarizeLoans(ctx, domain.LoanFilter{})
+		if err != nil {
+			t.Fatalf("Expected no error summarizing loans, got %v", err)
+		}
+		if len(totals) != 1 || totals[0].LoanCount != 2 || totals[0].TotalInvested != mustMoney("300.00") {
+			t.Errorf("Expected only the active 300.00 to count as invested, got %+v", totals[0])
+		}
+	})
+}
+
+func TestListLoansFilter(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+
+		proposed := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		rejected := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		rejected.State = domain.LoanStateRejected
+		cancelled := domain.NewLoan("borrower2", mustMoney("1000.00"), 0.1, 0.08)
+		cancelled.State = domain.LoanStateCancelled
+		for _, loan := range []*domain.Loan{proposed, rejected, cancelled} {
+			_ = repo.SaveLoan(ctx, loan)
+		}
+
+		loans, total, err := repo.ListLoans(ctx, domain.LoanFilter{
+			States: []domain.LoanState{domain.LoanStateRejected, domain.LoanStateCancelled},
+		}, domain.LoanSort{}, 0, 0)
+		if err != nil {
+			t.Fatalf("Expected no error listing loans, got %v", err)
+		}
+		if total != 2 || len(loans) != 2 {
+			t.Errorf("Expected 2 rejected or cancelled loans, got %d (total %d)", len(loans), total)
+		}
+
+		loans, total, _ = repo.ListLoans(ctx, domain.LoanFilter{BorrowerID: "borrower1"}, domain.LoanSort{}, 1, 1)
+		if total != 2 || len(loans) != 1 || loans[0].BorrowerID != "borrower1" {
+			t.Errorf("Expected first of 2 loans for borrower1, got %d (total %d)", len(loans), total)
+		}
+
+		loans, total, _ = repo.ListLoans(ctx, domain.LoanFilter{
+			States:     []domain.LoanState{domain.LoanStateCancelled},
+			BorrowerID: "borrower1",
+		}, domain.LoanSort{}, 0, 0)
+		if total != 0 || len(loans) != 0 {
+			t.Errorf("Expected no cancelled loans for borrower1, got %d", total)
+		}
+	})
+}
+
+func TestListLoansFilterRangesAndSort(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+		usd, _ := domain.ParseMoney("1500.00", "USD")
+
+		names := map[string]string{}
+		for _, tt := range []struct {
+			name      string
+			principal domain.Money
+			hours     int // created this many hours after base
+			invested  string
+		}{
+			{"half", mustMoney("1000.00"), 0, "500.00"},
+			{"unfunded", mustMoney("3000.00"), 1, ""},
+			{"full", mustMoney("2000.00"), 2, "2000.00"},
+			{"dollars", usd, 3, ""},
+		} {
+			loan := domain.NewLoan("borrower1", tt.principal, 0.1, 0.08)
+			loan.CreatedAt = base.Add(time.Duration(tt.hours) * time.Hour)
+			if err := repo.SaveLoan(ctx, loan); err != nil {
+				t.Fatalf("Expected no error saving loan, got %v", err)
+			}
+			if tt.invested != "" {
+				investment, _ := domain.NewInvestment(loan.ID, "investor1", mustMoney(tt.invested))
+				_ = repo.SaveInvestment(ctx, investment)
+			}
+			names[loan.ID] = tt.name
+		}
+
+		minIDR, maxIDR := mustMoney("1000.00"), mustMoney("2000.00")
+		fifty, hundred := 50.0, 100.0
+		tests := map[string]struct {
+			filter domain.LoanFilter
+			order  domain.LoanSort
+			want   []string
+		}{
+			"default order":        {domain.LoanFilter{}, domain.LoanSort{}, []string{"half", "unfunded", "full", "dollars"}},
+			"newest first":         {domain.LoanFilter{}, domain.LoanSort{Field: domain.LoanSortCreatedAt, Descending: true}, []string{"dollars", "full", "unfunded", "half"}},
+			"principal range":      {domain.LoanFilter{MinPrincipal: &minIDR, MaxPrincipal: &maxIDR}, domain.LoanSort{}, []string{"half", "full"}},
+			"minimum principal":    {domain.LoanFilter{MinPrincipal: &maxIDR}, domain.LoanSort{Field: domain.LoanSortPrincipalAmount}, []string{"full", "unfunded"}},
+			"created range":        {domain.LoanFilter{CreatedFrom: base.Add(time.Hour), CreatedTo: base.Add(3 * time.Hour)}, domain.LoanSort{}, []string{"unfunded", "full"}},
+			"at least half funded": {domain.LoanFilter{MinFundedPercent: &fifty}, domain.LoanSort{}, []string{"half", "full"}},
+			"not fully funded":     {domain.LoanFilter{MaxFundedPercent: &fifty}, domain.LoanSort{}, []string{"half", "unfunded", "dollars"}},
+			"exactly funded":       {domain.LoanFilter{MinFundedPercent: &hundred}, domain.LoanSort{}, []string{"full"}},
+			"most funded first":    {domain.LoanFilter{}, domain.LoanSort{Field: domain.LoanSortFundedPercent, Descending: true}, []string{"full", "half", "dollars", "unfunded"}},
+			"largest first":        {domain.LoanFilter{}, domain.LoanSort{Field: domain.LoanSortPrincipalAmount, Descending: true}, []string{"unfunded", "full", "dollars", "half"}},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				result, total, err := repo.ListLoans(ctx, tt.filter, tt.order, 0, 0)
+				if err != nil {
+					t.Fatalf("Expected no error listing loans, got %v", err)
+				}
+
+				var got []string
+				for _, loan := range result {
+					got = append(got, names[loan.ID])
+				}
+				if total != len(tt.want) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("Expected %v, got %v (total %d)", tt.want, got, total)
+				}
+			})
+		}
+	})
+}
+
+func TestListLoansAfterWalksEveryLoanOnce(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+		// Several loans share a creation time and a principal, so ties must
+		// be broken by ID. One is a third funded, a share no float holds
+		// exactly.
+		for i, principal := range []string{"1000.00", "2000.00", "1000.00", "750.00", "2000.00", "1000.00", "500.00"} {
+			loan := domain.NewLoan("borrower1", mustMoney(principal), 0.1, 0.08)
+			loan.CreatedAt = base.Add(time.Duration(i/2) * time.Hour)
+			if err := repo.SaveLoan(ctx, loan); err != nil {
+				t.Fatalf("Expected no error saving loan, got %v", err)
+			}
+			if i%3 == 0 {
+				investment, _ := domain.NewInvestment(loan.ID, "investor1", mustMoney("250.00"))
+				_ = repo.SaveInvestment(ctx, investment)
+			}
+		}
+
+		for _, order := range []domain.LoanSort{
+			{},
+			{Field: domain.LoanSortCreatedAt, Descending: true},
+			{Field: domain.LoanSortPrincipalAmount},
+			{Field: domain.LoanSortPrincipalAmount, Descending: true},
+			{Field: domain.LoanSortFundedPercent},
+			{Field: domain.LoanSortFundedPercent, Descending: true},
+		} {
+			t.Run(fmt.Sprintf("%s desc=%v", order.Field, order.Descending), func(t *testing.T) {
+				all, _, _ := repo.ListLoans(ctx, domain.LoanFilter{}, order, 0, 0)
+
+				var (
+					walked []string
+					after  *domain.LoanCursor
+				)
+				for pages := 0; pages < 10; pages++ {
+					page, total, err := repo.ListLoansAfter(ctx, domain.LoanFilter{}, order, after, 2)
+					if err != nil {
+						t.Fatalf("Expected no error listing loans, got %v", err)
+					}
+					if total != len(all) {
+						t.Errorf("Expected total %d, got %d", len(all), total)
+					}
+					if len(page) == 0 {
+						break
+					}
+					for _, loan := range page {
+						walked = append(walked, loan.ID)
+					}
+
+					// Cursors travel as strings between requests
+					after, err = domain.DecodeLoanCursor(domain.NewLoanCursor(order, page[len(page)-1]).Encode(), order)
+					if err != nil {
+						t.Fatalf("Expected cursor to decode, got %v", err)
+					}
+				}
+
+				var want []string
+				for _, loan := range all {
+					want = append(want, loan.ID)
+				}
+				if strings.Join(walked, ",") != strings.Join(want, ",") {
+					t.Errorf("Expected pages to walk %v, got %v", want, walked)
+				}
+			})
+		}
+	})
+}
+
+func TestListLoansAfterIgnoresLoansCreatedMeanwhile(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		base := time.Now().Add(-time.Hour).UTC()
+		var created []string
+		for i := 0; i < 4; i++ {
+			loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+			loan.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			_ = repo.SaveLoan(ctx, loan)
+			created = append(created, loan.ID)
+		}
+
+		newestFirst := domain.LoanSort{Field: domain.LoanSortCreatedAt, Descending: true}
+		first, _, _ := repo.ListLoansAfter(ctx, domain.LoanFilter{}, newestFirst, nil, 2)
+
+		// A loan created between pages would shift an offset page
+		_ = repo.SaveLoan(ctx, domain.NewLoan("borrower2", mustMoney("1000.00"), 0.1, 0.08))
+
+		second, total, _ := repo.ListLoansAfter(ctx, domain.LoanFilter{}, newestFirst, domain.NewLoanCursor(newestFirst, first[1]), 2)
+		if total != 5 || len(second) != 2 || second[0].ID != created[1] || second[1].ID != created[0] {
+			t.Errorf("Expected the two oldest loans on the second page, got %d loans (total %d)", len(second), total)
+		}
+	})
+}
+
+func TestSaveRejectionAndCancellation(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		rejected := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		cancelled := domain.NewLoan("borrower2", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, rejected)
+		_ = repo.SaveLoan(ctx, cancelled)
+
+		rejection, _ := domain.NewRejection(rejected.ID, "incomplete documents", "reviewer123", time.Now())
+		if err := repo.SaveRejection(ctx, rejection); err != nil {
+			t.Fatalf("Expected no error saving rejection, got %v", err)
+		}
+
+		investment, _ := domain.NewInvestment(cancelled.ID, "investor1", mustMoney("400.00"))
+		_ = repo.SaveInvestment(ctx, investment)
+
+		cancellation, _ := domain.NewCancellation(cancelled.ID, "borrower withdrew", "staff123", time.Now())
+		if err := repo.SaveCancellation(ctx, cancellation); err != nil {
+			t.Fatalf("Expected no error saving cancellation, got %v", err)
+		}
+		investment.Refund(cancellation.CancelledAt)
+		if err := repo.SaveInvestment(ctx, investment); err != nil {
+			t.Fatalf("Expected no error saving refunded investment, got %v", err)
+		}
+
+		saved, _ := repo.GetLoanByID(ctx, rejected.ID)
+		if saved.Rejection == nil || saved.Rejection.Reason != "incomplete documents" || saved.Rejection.ReviewerID != "reviewer123" {
+			t.Errorf("Expected rejection to round-trip, got %+v", saved.Rejection)
+		}
+
+		saved, _ = repo.GetLoanByID(ctx, cancelled.ID)
+		if saved.Cancellation == nil || saved.Cancellation.CancelledBy != "staff123" {
+			t.Errorf("Expected cancellation to round-trip, got %+v", saved.Cancellation)
+		}
+		if len(saved.Investments) != 1 || saved.Investments[0].Status != domain.InvestmentStatusRefunded || saved.Investments[0].RefundedAt == nil {
+			t.Errorf("Expected refunded investment to round-trip, got %+v", saved.Investments)
+		}
+	})
+}
+
+func TestSaveRepaymentSchedule(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		if _, err := repo.GetRepaymentSchedule(ctx, loan.ID); err == nil {
+			t.Error("Expected error getting missing schedule, got nil")
+		}
+
+		terms := domain.ScheduleTerms{Tenor: 3, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodAnnuity}
+		schedule, _ := domain.NewRepaymentSchedule(loan, terms, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+		if err := repo.SaveRepaymentSchedule(ctx, schedule); err != nil {
+			t.Fatalf("Expected no error saving schedule, got %v", err)
+		}
+
+		saved, err := repo.GetRepaymentSchedule(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected no error getting schedule, got %v", err)
+		}
+
+		if saved.Terms != terms {
+			t.Errorf("Expected terms %+v, got %+v", terms, saved.Terms)
+		}
+		if len(saved.Installments) != 3 {
+			t.Fatalf("Expected 3 installments, got %d", len(saved.Installments))
+		}
+		for i, installment := range saved.Installments {
+			want := schedule.Installments[i]
+			if installment.Amount != want.Amount || installment.Interest != want.Interest || !installment.DueDate.Equal(want.DueDate) {
+				t.Errorf("Expected installment %+v, got %+v", want, installment)
+			}
+		}
+		if saved.TotalAmount != schedule.TotalAmount || saved.TotalPrincipal != loan.PrincipalAmount {
+			t.Errorf("Expected totals %s / %s, got %s / %s",
+				schedule.TotalAmount, loan.PrincipalAmount, saved.TotalAmount, saved.TotalPrincipal)
+		}
+
+		orphan := *schedule
+		orphan.LoanID = "missing"
+		if err := repo.SaveRepaymentSchedule(ctx, &orphan); err == nil {
+			t.Error("Expected error saving schedule for missing loan, got nil")
+		}
+	})
+}
+
+func TestSaveRepayment(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		later, _ := domain.NewRepayment(loan.ID, mustMoney("200.00"), time.Now().Add(-time.Hour))
+		earlier, _ := domain.NewRepayment(loan.ID, mustMoney("100.00"), time.Now().Add(-48*time.Hour))
+		for _, repayment := range []*domain.Repayment{later, earlier} {
+			if err := repo.SaveRepayment(ctx, repayment); err != nil {
+				t.Fatalf("Expected no error saving repayment, got %v", err)
+			}
+		}
+
+		repayments, err := repo.GetLoanRepayments(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected no error getting repayments, got %v", err)
+		}
+
+		if len(repayments) != 2 || repayments[0].ID != earlier.ID || repayments[1].ID != later.ID {
+			t.Fatalf("Expected repayments ordered by payment date, got %+v", repayments)
+		}
+		if repayments[1].Amount != later.Amount || !repayments[1].PaidAt.Equal(later.PaidAt) {
+			t.Errorf("Expected repayment %+v, got %+v", later, repayments[1])
+		}
+
+		orphan, _ := domain.NewRepayment("missing", mustMoney("1.00"), time.Now())
+		if err := repo.SaveRepayment(ctx, orphan); err == nil {
+			t.Error("Expected error saving repayment for missing loan, got nil")
+		}
+	})
+}
+
+func TestSavePayout(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		_ = repo.SaveLoan(ctx, loan)
+
+		investment, _ := domain.NewInvestment(loan.ID, "investor1", mustMoney("1000.00"))
+		_ = repo.SaveInvestment(ctx, investment)
+		repayment, _ := domain.NewRepayment(loan.ID, mustMoney("100.00"), time.Now())
+		_ = repo.SaveRepayment(ctx, repayment)
+
+		payout := &domain.Payout{
+			ID:           "pay_1",
+			LoanID:       loan.ID,
+			RepaymentID:  repayment.ID,
+			InvestmentID: investment.ID,
+			InvestorID:   investment.InvestorID,
+			Amount:       mustMoney("98.18"),
+			CreatedAt:    time.Now(),
+		}
+		if err := repo.SavePayout(ctx, payout); err != nil {
+			t.Fatalf("Expected no error saving payout, got %v", err)
+		}
+
+		payouts, err := repo.GetLoanPayouts(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected no error getting payouts, got %v", err)
+		}
+
+		if len(payouts) != 1 || payouts[0].Amount != payout.Amount || payouts[0].InvestorID != "investor1" || payouts[0].RepaymentID != repayment.ID {
+			t.Errorf("Expected payout %+v, got %+v", payout, payouts)
+		}
+
+		if _, err := repo.GetLoanPayouts(ctx, "missing"); err == nil {
+			t.Error("Expected error getting payouts for missing loan, got nil")
+		}
+	})
+}
+
+func TestOutboxMessages(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		now := time.Now()
+
+		due, _ := domain.NewOutboxMessage(domain.TopicNotification, domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor1", "loan1"))
+		due.NextAttemptAt = now.Add(-time.Second)
+		later, _ := domain.NewOutboxMessage(domain.TopicNotification, domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor2", "loan1"))
+		later.NextAttemptAt = now.Add(500 * time.Millisecond) // within the same second as now
+		delivered, _ := domain.NewOutboxMessage(domain.TopicNotification, domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor3", "loan1"))
+		delivered.NextAttemptAt = now.Add(-time.Minute)
+		delivered.MarkDelivered(now)
+
+		for _, message := range []*domain.OutboxMessage{due, later, delivered} {
+			if err := repo.SaveOutboxMessage(ctx, message); err != nil {
+				t.Fatalf("Expected no error saving outbox message, got %v", err)
+			}
+		}
+
+		saved, err := repo.GetOutboxMessage(ctx, delivered.ID)
+		if err != nil {
+			t.Fatalf("Expected to retrieve outbox message, got %v", err)
+		}
+		if saved.Status != domain.OutboxStatusDelivered || saved.Attempts != 1 || saved.DeliveredAt == nil || string(saved.Payload) != string(delivered.Payload) {
+			t.Errorf("Expected outbox message fields to round-trip, got %+v", saved)
+		}
+
+		dueMessages, err := repo.ListDueOutboxMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("Expected no error listing due messages, got %v", err)
+		}
+		if len(dueMessages) != 1 || dueMessages[0].ID != due.ID {
+			t.Errorf("Expected only %s to be due, got %+v", due.ID, dueMessages)
+		}
+
+		pending, total, err := repo.ListOutboxMessages(ctx, domain.OutboxStatusPending, 1, 10)
+		if err != nil {
+			t.Fatalf("Expected no error listing pending messages, got %v", err)
+		}
+		if total != 2 || len(pending) != 2 {
+			t.Errorf("Expected 2 pending messages, got %d of %d", len(pending), total)
+		}
+
+		all, total, _ := repo.ListOutboxMessages(ctx, "", 1, 2)
+		if total != 3 || len(all) != 2 {
+			t.Errorf("Expected first page of 2 out of 3 messages, got %d of %d", len(all), total)
+		}
+
+		if _, err := repo.GetOutboxMessage(ctx, "missing"); err == nil {
+			t.Error("Expected error getting missing outbox message, got nil")
+		}
+	})
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+
+		all, _ := domain.NewWebhookSubscription("https://example.com/all", nil)
+		approvals, _ := domain.NewWebhookSubscription("https://example.com/approvals", []domain.WebhookEventType{domain.WebhookLoanApproved})
+		approvals.CreatedAt = all.CreatedAt.Add(time.Second)
+		for _, subscription := range []*domain.WebhookSubscription{all, approvals} {
+			if err := repo.SaveWebhookSubscription(ctx, subscription); err != nil {
+				t.Fatalf("Expected no error saving webhook subscription, got %v", err)
+			}
+		}
+
+		approvals.Deactivate(time.Now())
+		if err := repo.SaveWebhookSubscription(ctx, approvals); err != nil {
+			t.Fatalf("Expected no error updating webhook subscription, got %v", err)
+		}
+
+		saved, err := repo.GetWebhookSubscription(ctx, approvals.ID)
+		if err != nil {
+			t.Fatalf("Expected to retrieve webhook subscription, got %v", err)
+		}
+		if saved.Active || saved.Secret != approvals.Secret || len(saved.Events) != 1 || saved.Events[0] != domain.WebhookLoanApproved {
+			t.Errorf("Expected webhook subscription fields to round-trip, got %+v", saved)
+		}
+
+		subscriptions, err := repo.ListWebhookSubscriptions(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error listing webhook subscriptions, got %v", err)
+		}
+		if len(subscriptions) != 2 || subscriptions[0].ID != all.ID || len(subscriptions[0].Events) != 0 {
+			t.Errorf("Expected both subscriptions oldest first, got %+v", subscriptions)
+		}
+
+		if _, err := repo.GetWebhookSubscription(ctx, "missing"); err == nil {
+			t.Error("Expected error getting missing webhook subscription, got nil")
+		}
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+
+		subscription, _ := domain.NewWebhookSubscription("https://example.com/hooks", nil)
+		if err := repo.SaveWebhookSubscription(ctx, subscription); err != nil {
+			t.Fatalf("Expected no error saving webhook subscription, got %v", err)
+		}
+
+		event := domain.NewWebhookEvent(domain.WebhookLoanApproved, domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08), nil)
+		failed := domain.NewWebhookDelivery(subscription, event, 1, time.Now().Add(-time.Minute))
+		failed.StatusCode = 500
+		failed.Error = "webhook responded 500 Internal Server Error"
+		succeeded := domain.NewWebhookDelivery(subscription, event, 2, time.Now())
+		succeeded.StatusCode = 204
+		succeeded.Succeeded = true
+		for _, delivery := range []*domain.WebhookDelivery{failed, succeeded} {
+			if err := repo.SaveWebhookDelivery(ctx, delivery); err != nil {
+				t.Fatalf("Expected no error saving webhook delivery, got %v", err)
+			}
+		}
+
+		deliveries, total, err := repo.ListWebhookDeliveries(ctx, subscription.ID, 1, 10)
+		if err != nil {
+			t.Fatalf("Expected no error listing webhook deliveries, got %v", err)
+		}
+		if total != 2 || len(deliveries) != 2 || deliveries[0].ID != succeeded.ID {
+			t.Fatalf("Expected 2 deliveries most recent first, got %d of %d", len(deliveries), total)
+		}
+		if last := deliveries[1]; last.Attempt != 1 || last.StatusCode != 500 || last.Error != failed.Error || last.Succeeded || last.EventID != event.ID {
+			t.Errorf("Expected webhook delivery fields to round-trip, got %+v", last)
+		}
+
+		page, total, _ := repo.ListWebhookDeliveries(ctx, subscription.ID, 2, 1)
+		if total != 2 || len(page) != 1 || page[0].ID != failed.ID {
+			t.Errorf("Expected second page to hold the oldest delivery, got %+v", page)
+		}
+
+		if _, _, err := repo.ListWebhookDeliveries(ctx, "missing", 1, 10); err == nil {
+			t.Error("Expected error listing deliveries of a missing subscription, got nil")
+		}
+	})
+}
+
+func TestLoanEvents(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		loan := domain.NewLoan("borrower1", mustMoney("1000.00"), 0.1, 0.08)
+		if err := repo.SaveLoan(ctx, loan); err != nil {
+			t.Fatalf("Expected no error saving loan, got %v", err)
+		}
+		approval, _ := domain.NewApproval(loan.ID, "proof.jpg", "validator1", time.Now())
+		_ = loan.Approve(approval)
+
+		if err := repo.AppendLoanEvents(ctx, loan.PendingEvents()...); err != nil {
+			t.Fatalf("Expected no error appending events, got %v", err)
+		}
+		letter := domain.NewLoanEvent(loan.ID, &domain.AgreementLetterAttached{URL: "https://example.com/letter.pdf"})
+		if err := repo.AppendLoanEvents(ctx, letter); err != nil {
+			t.Fatalf("Expected no error appending a later event, got %v", err)
+		}
+		if letter.Sequence != 3 {
+			t.Errorf("Expected the later event to be numbered 3, got %d", letter.Sequence)
+		}
+
+		events, err := repo.GetLoanEvents(ctx, loan.ID)
+		if err != nil {
+			t.Fatalf("Expected no error getting events, got %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("Expected 3 events, got %d", len(events))
+		}
+		for i, want := range []domain.LoanEventType{domain.LoanEventCreated, domain.LoanEventApproved, domain.LoanEventAgreementLetterAttached} {
+			if events[i].Type != want || events[i].Sequence != i+1 {
+				t.Errorf("Expected event %d to be %s, got %s #%d", i+1, want, events[i].Type, events[i].Sequence)
+			}
+		}
+		if approved, ok := events[1].Data.(*domain.LoanApproved); !ok || approved.Approval.FieldValidatorID != "validator1" {
+			t.Errorf("Expected typed approval data to round-trip, got %+v", events[1].Data)
+		}
+
+		if _, err := repo.GetLoanEvents(ctx, "missing"); err == nil {
+			t.Error("Expected error getting events of a missing loan, got nil")
+		}
+		if err := repo.AppendLoanEvents(ctx, domain.NewLoanEvent("missing", &domain.LoanRepaid{})); err == nil {
+			t.Error("Expected error appending an event to a missing loan, got nil")
+		}
+	})
+}
+
+func TestAuditEntries(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		start := time.Now().UTC()
+
+		if last, err := repo.LastAuditEntry(ctx); err != nil || last != nil {
+			t.Fatalf("Expected an empty trail, got %+v (%v)", last, err)
+		}
+
+		actors := []string{"alice", "bob", "alice"}
+		loans := []string{"loan1", "loan1", "loan2"}
+		for i := range actors {
+			entry := domain.NewAuditEntry(domain.ContextWithPrincipal(ctx, &domain.Principal{Subject: actors[i]}), domain.AuditApproveLoan, loans[i],
+				[]byte(`{"state":"PROPOSED"}`), []byte(`{"state":"APPROVED"}`))
+			entry.OccurredAt = start.Add(time.Duration(i) * time.Minute)
+
+			last, err := repo.LastAuditEntry(ctx)
+			if err != nil {
+				t.Fatalf("Expected no error reading the last entry, got %v", err)
+			}
+			entry.Chain(last)
+			if err := repo.AppendAuditEntry(ctx, entry); err != nil {
+				t.Fatalf("Expected no error appending entry %d, got %v", i+1, err)
+			}
+		}
+
+		all, total, err := repo.ListAuditEntries(ctx, domain.AuditFilter{}, 0, 0)
+		if err != nil || total != 3 {
+			t.Fatalf("Expected 3 entries, got %d (%v)", total, err)
+		}
+		if verification := domain.VerifyAuditTrail(all); !verification.Valid {
+			t.Errorf("Expected the stored trail to verify, got %+v", verification)
+		}
+		if string(all[0].Before) != `{"state":"PROPOSED"}` || all[0].Actor != "alice" || all[0].Action != domain.AuditApproveLoan {
+			t.Errorf("Expected audit entry fields to round-trip, got %+v", all[0])
+		}
+
+		duplicate := domain.NewAuditEntry(ctx, domain.AuditApproveLoan, "loan1", nil, nil)
+		duplicate.Chain(all[1])
+		if err := repo.AppendAuditEntry(ctx, duplicate); err == nil {
+			t.Error("Expected error appending a second entry 3, got nil")
+		}
+
+		for name, tc := range map[string]struct {
+			filter domain.AuditFilter
+			want   []int64
+		}{
+			"loan":       {domain.AuditFilter{LoanID: "loan1"}, []int64{1, 2}},
+			"actor":      {domain.AuditFilter{Actor: "alice"}, []int64{1, 3}},
+			"time range": {domain.AuditFilter{From: start.Add(time.Minute), To: start.Add(2 * time.Minute)}, []int64{2}},
+			"combined":   {domain.AuditFilter{LoanID: "loan1", Actor: "alice", From: start}, []int64{1}},
+		} {
+			entries, total, err := repo.ListAuditEntries(ctx, tc.filter, 1, 10)
+			if err != nil || total != len(tc.want) || len(entries) != len(tc.want) {
+				t.Errorf("Expected %s filter to match %v, got %d entries (%v)", name, tc.want, total, err)
+				continue
+			}
+			for i, entry := range entries {
+				if entry.Sequence != tc.want[i] {
+					t.Errorf("Expected %s filter entry %d to be %d, got %d", name, i, tc.want[i], entry.Sequence)
+				}
+			}
+		}
+
+		page, total, _ := repo.ListAuditEntries(ctx, domain.AuditFilter{}, 2, 2)
+		if total != 3 || len(page) != 1 || page[0].Sequence != 3 {
+			t.Errorf("Expected second page to hold entry 3, got %+v", page)
+		}
+	})
+}
+
+func TestAppendAuditEntriesConcurrently(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		const appenders = 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, appenders)
+		for i := 0; i < appenders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+					last, err := repo.LastAuditEntry(ctx)
+					if err != nil {
+						return err
+					}
+					entry := domain.NewAuditEntry(ctx, domain.AuditCreateLoan, "loan1", nil, []byte(`{}`))
+					entry.Chain(last)
+					return repo.AppendAuditEntry(ctx, entry)
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Expected every append to succeed, got %v", err)
+			}
+		}
+		all, total, err := repo.ListAuditEntries(ctx, domain.AuditFilter{}, 0, 0)
+		if err != nil || total != appenders {
+			t.Fatalf("Expected %d entries, got %d (%v)", appenders, total, err)
+		}
+		if verification := domain.VerifyAuditTrail(all); !verification.Valid {
+			t.Errorf("Expected the trail to verify, got %+v", verification)
+		}
+	})
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		first := domain.NewIdempotencyRecord("alice", "key-1", "POST", "/api/v1/loans", []byte(`{"a":1}`))
+
+		existing, err := repo.ReserveIdempotencyKey(ctx, first)
+		if err != nil || existing != nil {
+			t.Fatalf("Expected the first request to reserve the key, got %+v (%v)", existing, err)
+		}
+
+		retry := domain.NewIdempotencyRecord("alice", "key-1", "POST", "/api/v1/loans", []byte(`{"a":1}`))
+		existing, err = repo.ReserveIdempotencyKey(ctx, retry)
+		if err != nil || existing == nil || existing.Completed() || !existing.Matches(retry) {
+			t.Fatalf("Expected the in-progress record, got %+v (%v)", existing, err)
+		}
+
+		first.Complete(201, []byte(`{"code":201}`))
+		if err := repo.SaveIdempotencyRecord(ctx, first); err != nil {
+			t.Fatalf("Expected no error saving the response, got %v", err)
+		}
+		existing, _ = repo.ReserveIdempotencyKey(ctx, retry)
+		if existing == nil || existing.StatusCode != 201 || string(existing.Body) != `{"code":201}` {
+			t.Errorf("Expected the stored response, got %+v", existing)
+		}
+
+		other := domain.NewIdempotencyRecord("bob", "key-1", "POST", "/api/v1/loans", []byte(`{"a":1}`))
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, other); existing != nil {
+			t.Errorf("Expected keys to be scoped to their actor, got %+v", existing)
+		}
+
+		if err := repo.DeleteIdempotencyRecord(ctx, "alice", "key-1"); err != nil {
+			t.Fatalf("Expected no error deleting the record, got %v", err)
+		}
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, retry); existing != nil {
+			t.Errorf("Expected a released key to be reserved again, got %+v", existing)
+		}
+
+		stale := domain.NewIdempotencyRecord("carol", "key-1", "POST", "/api/v1/loans", []byte(`{}`))
+		stale.CreatedAt = time.Now().UTC().Add(-domain.IdempotencyKeyTTL - time.Minute)
+		repo.ReserveIdempotencyKey(ctx, stale)
+		fresh := domain.NewIdempotencyRecord("carol", "key-1", "POST", "/api/v1/loans", []byte(`{"b":2}`))
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, fresh); existing != nil {
+			t.Errorf("Expected an expired key to be reserved again, got %+v", existing)
+		}
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, fresh); existing == nil || !existing.Matches(fresh) {
+			t.Errorf("Expected the expired record to be replaced, got %+v", existing)
+		}
+	})
+}
+
+func TestPurgeExpiredIdempotencyRecords(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.LoanRepository) {
+		ctx := context.Background()
+		now := time.Now().UTC()
+
+		expired := domain.NewIdempotencyRecord("alice", "old", "POST", "/api/v1/loans", []byte(`{}`))
+		expired.CreatedAt = now.Add(-domain.IdempotencyKeyTTL - time.Minute)
+		expired.Complete(201, []byte(`{"code":201}`))
+		live := domain.NewIdempotencyRecord("alice", "new", "POST", "/api/v1/loans", []byte(`{}`))
+		live.CreatedAt = now.Add(-domain.IdempotencyKeyTTL + time.Minute)
+		for _, record := range []*domain.IdempotencyRecord{expired, live} {
+			repo.ReserveIdempotencyKey(ctx, record)
+			repo.SaveIdempotencyRecord(ctx, record)
+		}
+
+		purged, err := repo.PurgeExpiredIdempotencyRecords(ctx, now)
+		if err != nil || purged != 1 {
+			t.Fatalf("Expected 1 record purged, got %d (%v)", purged, err)
+		}
+
+		// A purged key is free, while a live one still replays its response
+		retry := domain.NewIdempotencyRecord("alice", "old", "POST", "/api/v1/loans", []byte(`{}`))
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, retry); existing != nil {
+			t.Errorf("Expected the purged key to be free, got %+v", existing)
+		}
+		liveRetry := domain.NewIdempotencyRecord("alice", "new", "POST", "/api/v1/loans", []byte(`{}`))
+		if existing, _ := repo.ReserveIdempotencyKey(ctx, liveRetry); existing == nil {
+			t.Error("Expected the live record to survive the purge")
+		}
+
+		if purged, _ := repo.PurgeExpiredIdempotencyRecords(ctx, now); purged != 0 {
+			t.Errorf("Expected nothing left to purge, got %d", purged)
+		}
+	})
+}
@@ -0,0 +1,1525 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"strings"
+	"time"
+)
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by SQLLoanRepository, so the
+// same queries run inside and outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SQLLoanRepository is a LoanRepository backed by a database/sql connection.
+// Queries use $N placeholders and portable DDL so the same implementation
+// runs on SQLite and Postgres.
+type SQLLoanRepository struct {
+	db   *sql.DB
+	conn dbtx
+	inTx bool
+}
+
+func NewSQLLoanRepository(db *sql.DB) *SQLLoanRepository {
+	return &SQLLoanRepository{
+		db:   db,
+		conn: db,
+	}
+}
+
+// OpenSQLDatabase opens a database with the given driver and DSN, verifies the
+// connection and applies pending migrations.
+func OpenSQLDatabase(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if driver == "sqlite" {
+		// SQLite allows a single writer; sharing one connection avoids
+		// SQLITE_BUSY errors and keeps in-memory databases alive.
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// WithinTx runs fn in a database transaction, committing when fn returns nil
+// and rolling back otherwise. Calls made while already inside a transaction
+// join it.
+func (r *SQLLoanRepository) WithinTx(ctx context.Context, fn func(repo LoanRepository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLLoanRepository{db: r.db, conn: tx, inTx: true}); err != nil {
+		domain.LoggerFromContext(ctx).Debug("transaction rolled back", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveLoan inserts a new loan (version 0) or updates an existing one only if
+// its stored version still matches loan.Version, returning ErrConflict
+// otherwise. On success loan.Version is incremented to the stored version.
+func (r *SQLLoanRepository) SaveLoan(ctx context.Context, loan *domain.Loan) error {
+	var (
+		result sql.Result
+		err    error
+	)
+
+	if loan.Version == 0 {
+		result, err = r.conn.ExecContext(ctx, `
+			INSERT INTO loans (id, borrower_id, principal_minor, currency, rate, roi, state, agreement_letter_url, created_at, updated_at, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 1)
+			ON CONFLICT (id) DO NOTHING`,
+			loan.ID,
+			loan.BorrowerID,
+			loan.PrincipalAmount.Amount,
+			loan.PrincipalAmount.Currency,
+			loan.Rate,
+			loan.ROI,
+			string(loan.State),
+			loan.AgreementLetterURL,
+			loan.CreatedAt.UTC(),
+			loan.UpdatedAt.UTC(),
+		)
+	} else {
+		result, err = r.conn.ExecContext(ctx, `
+			UPDATE loans SET
+				borrower_id = $2,
+				principal_minor = $3,
+				currency = $4,
+				rate = $5,
+				roi = $6,
+				state = $7,
+				agreement_letter_url = $8,
+				updated_at = $9,
+				version = version + 1
+			WHERE id = $1 AND version = $10`,
+			loan.ID,
+			loan.BorrowerID,
+			loan.PrincipalAmount.Amount,
+			loan.PrincipalAmount.Currency,
+			loan.Rate,
+			loan.ROI,
+			string(loan.State),
+			loan.AgreementLetterURL,
+			loan.UpdatedAt.UTC(),
+			loan.Version,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		if loan.Version != 0 {
+			if err := r.ensureLoanExists(ctx, loan.ID); err != nil {
+				return err
+			}
+		}
+		return ErrConflict
+	}
+
+	loan.Version++
+	return nil
+}
+
+func (r *SQLLoanRepository) GetLoanByID(ctx context.Context, id string) (*domain.Loan, error) {
+	row := r.conn.QueryRowContext(ctx, `
+		SELECT id, borrower_id, principal_minor, currency, rate, roi, state, agreement_letter_url, created_at, updated_at, version
+		FROM loans WHERE id = $1`, id)
+
+	loan, err := scanLoan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLoanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRelations(ctx, loan); err != nil {
+		return nil, err
+	}
+
+	return loan, nil
+}
+
+// loanListingFrom joins each loan to its active invested total, which the
+// funding filters and sort use
+const loanListingFrom = `
+		FROM loans l
+		LEFT JOIN (
+			SELECT loan_id, SUM(amount_minor) AS invested_minor
+			FROM investments WHERE status = 'ACTIVE' GROUP BY loan_id
+		) i ON i.loan_id = l.id`
+
+const loanListingSelect = `
+		SELECT l.id, l.borrower_id, l.principal_minor, l.currency, l.rate, l.roi, l.state, l.agreement_letter_url, l.created_at, l.updated_at, l.version` +
+	loanListingFrom
+
+func (r *SQLLoanRepository) ListLoans(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, page, pageSize int) ([]*domain.Loan, int, error) {
+	where, args := loanFilterClause(filter)
+
+	var total int
+	if err := r.conn.QueryRowContext(ctx, `SELECT COUNT(*)`+loanListingFrom+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := loanListingSelect + where + loanOrderClause(order)
+	if page > 0 && pageSize > 0 {
+		args = append(args, pageSize, (page-1)*pageSize)
+		query += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	}
+
+	result, err := r.queryLoans(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+func (r *SQLLoanRepository) ListLoansAfter(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, after *domain.LoanCursor, limit int) ([]*domain.Loan, int, error) {
+	where, args := loanFilterClause(filter)
+
+	var total int
+	if err := r.conn.QueryRowContext(ctx, `SELECT COUNT(*)`+loanListingFrom+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	if after != nil {
+		var condition string
+		condition, args = loanAfterCondition(order, after, args)
+		if where == "" {
+			where = " WHERE " + condition
+		} else {
+			where += " AND " + condition
+		}
+	}
+
+	args = append(args, limit)
+	query := loanListingSelect + where + loanOrderClause(order) + fmt.Sprintf(` LIMIT $%d`, len(args))
+
+	result, err := r.queryLoans(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, total, nil
+}
+
+// queryLoans runs a loan listing query and loads each loan's relations
+func (r *SQLLoanRepository) queryLoans(ctx context.Context, query string, args ...interface{}) ([]*domain.Loan, error) {
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*domain.Loan{}
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result = append(result, loan)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Relations are loaded after the cursor is closed so a single-connection
+	// pool is never asked for a second connection.
+	for _, loan := range result {
+		if err := r.loadRelations(ctx, loan); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// SummarizeLoans aggregates in SQL, grouping by currency so that amounts in
+// different currencies are never summed together. Like the listing, it only
+// counts active investments.
+func (r *SQLLoanRepository) SummarizeLoans(ctx context.Context, filter domain.LoanFilter) ([]*domain.CurrencyTotal, error) {
+	where, args := loanFilterClause(filter)
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT l.currency, COUNT(*), SUM(l.principal_minor), COALESCE(SUM(i.invested_minor), 0)`+
+		loanListingFrom+where+`
+		GROUP BY l.currency
+		ORDER BY l.currency`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []*domain.CurrencyTotal{}
+	for rows.Next() {
+		var (
+			total               domain.CurrencyTotal
+			principal, invested int64
+		)
+		if err := rows.Scan(&total.Currency, &total.LoanCount, &principal, &invested); err != nil {
+			return nil, err
+		}
+		total.PrincipalAmount = domain.Money{Amount: principal, Currency: total.Currency}
+		total.TotalInvested = domain.Money{Amount: invested, Currency: total.Currency}
+		totals = append(totals, &total)
+	}
+
+	return totals, rows.Err()
+}
+
+func (r *SQLLoanRepository) SaveApproval(ctx context.Context, approval *domain.Approval) error {
+	if err := r.ensureLoanExists(ctx, approval.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO approvals (loan_id, proof_picture_url, field_validator_id, approval_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			proof_picture_url = excluded.proof_picture_url,
+			field_validator_id = excluded.field_validator_id,
+			approval_date = excluded.approval_date`,
+		approval.LoanID,
+		approval.ProofPictureURL,
+		approval.FieldValidatorID,
+		approval.ApprovalDate.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) SaveInvestment(ctx context.Context, investment *domain.Investment) error {
+	if err := r.ensureLoanExists(ctx, investment.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO investments (id, loan_id, investor_id, amount_minor, currency, status, invested_at, refunded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			investor_id = excluded.investor_id,
+			amount_minor = excluded.amount_minor,
+			currency = excluded.currency,
+			status = excluded.status,
+			invested_at = excluded.invested_at,
+			refunded_at = excluded.refunded_at`,
+		investment.ID,
+		investment.LoanID,
+		investment.InvestorID,
+		investment.Amount.Amount,
+		investment.Amount.Currency,
+		string(investment.Status),
+		investment.InvestedAt.UTC(),
+		nullableTime(investment.RefundedAt),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) GetLoanInvestments(ctx context.Context, loanID string) ([]*domain.Investment, error) {
+	if err := r.ensureLoanExists(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	return r.loanInvestments(ctx, loanID)
+}
+
+func (r *SQLLoanRepository) SaveDisbursement(ctx context.Context, disbursement *domain.Disbursement) error {
+	if err := r.ensureLoanExists(ctx, disbursement.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO disbursements (loan_id, agreement_document_url, field_officer_id, disbursement_date)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			agreement_document_url = excluded.agreement_document_url,
+			field_officer_id = excluded.field_officer_id,
+			disbursement_date = excluded.disbursement_date`,
+		disbursement.LoanID,
+		disbursement.AgreementDocumentURL,
+		disbursement.FieldOfficerID,
+		disbursement.DisbursementDate.UTC(),
+	)
+	return err
+}
+
+// SaveRepaymentSchedule replaces the loan's schedule and all of its
+// installments in one transaction (joining the caller's, if any).
+func (r *SQLLoanRepository) SaveRepaymentSchedule(ctx context.Context, schedule *domain.RepaymentSchedule) error {
+	return r.WithinTx(ctx, func(repo LoanRepository) error {
+		tx := repo.(*SQLLoanRepository)
+		if err := tx.ensureLoanExists(ctx, schedule.LoanID); err != nil {
+			return err
+		}
+
+		if _, err := tx.conn.ExecContext(ctx, `
+			INSERT INTO repayment_schedules (loan_id, tenor, frequency, interest_method, currency, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (loan_id) DO UPDATE SET
+				tenor = excluded.tenor,
+				frequency = excluded.frequency,
+				interest_method = excluded.interest_method,
+				currency = excluded.currency,
+				created_at = excluded.created_at`,
+			schedule.LoanID,
+			schedule.Terms.Tenor,
+			string(schedule.Terms.Frequency),
+			string(schedule.Terms.Method),
+			schedule.TotalAmount.Currency,
+			schedule.CreatedAt.UTC(),
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.conn.ExecContext(ctx,
+			`DELETE FROM repayment_installments WHERE loan_id = $1`, schedule.LoanID,
+		); err != nil {
+			return err
+		}
+
+		for _, installment := range schedule.Installments {
+			if _, err := tx.conn.ExecContext(ctx, `
+				INSERT INTO repayment_installments (loan_id, number, due_date, principal_minor, interest_minor)
+				VALUES ($1, $2, $3, $4, $5)`,
+				schedule.LoanID,
+				installment.Number,
+				installment.DueDate.UTC(),
+				installment.Principal.Amount,
+				installment.Interest.Amount,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *SQLLoanRepository) GetRepaymentSchedule(ctx context.Context, loanID string) (*domain.RepaymentSchedule, error) {
+	var (
+		schedule  = &domain.RepaymentSchedule{LoanID: loanID}
+		frequency string
+		method    string
+		currency  string
+	)
+	err := r.conn.QueryRowContext(ctx, `
+		SELECT tenor, frequency, interest_method, currency, created_at
+		FROM repayment_schedules WHERE loan_id = $1`, loanID,
+	).Scan(&schedule.Terms.Tenor, &frequency, &method, &currency, &schedule.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	schedule.Terms.Frequency = domain.RepaymentFrequency(frequency)
+	schedule.Terms.Method = domain.InterestMethod(method)
+
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT number, due_date, principal_minor, interest_minor
+		FROM repayment_installments WHERE loan_id = $1
+		ORDER BY number`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedule.Installments = []*domain.Installment{}
+	for rows.Next() {
+		installment := &domain.Installment{
+			Principal: domain.ZeroMoney(currency),
+			Interest:  domain.ZeroMoney(currency),
+		}
+		if err := rows.Scan(
+			&installment.Number,
+			&installment.DueDate,
+			&installment.Principal.Amount,
+			&installment.Interest.Amount,
+		); err != nil {
+			return nil, err
+		}
+		installment.Amount = domain.Money{
+			Amount:   installment.Principal.Amount + installment.Interest.Amount,
+			Currency: currency,
+		}
+		schedule.Installments = append(schedule.Installments, installment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schedule.RecalculateTotals(currency)
+
+	return schedule, nil
+}
+
+func (r *SQLLoanRepository) SaveRepayment(ctx context.Context, repayment *domain.Repayment) error {
+	if err := r.ensureLoanExists(ctx, repayment.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO repayments (id, loan_id, amount_minor, currency, paid_at, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			amount_minor = excluded.amount_minor,
+			currency = excluded.currency,
+			paid_at = excluded.paid_at,
+			recorded_at = excluded.recorded_at`,
+		repayment.ID,
+		repayment.LoanID,
+		repayment.Amount.Amount,
+		repayment.Amount.Currency,
+		repayment.PaidAt.UTC(),
+		repayment.RecordedAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) GetLoanRepayments(ctx context.Context, loanID string) ([]*domain.Repayment, error) {
+	if err := r.ensureLoanExists(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT id, loan_id, amount_minor, currency, paid_at, recorded_at
+		FROM repayments WHERE loan_id = $1
+		ORDER BY paid_at, recorded_at, id`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	repayments := []*domain.Repayment{}
+	for rows.Next() {
+		var repayment domain.Repayment
+		if err := rows.Scan(
+			&repayment.ID,
+			&repayment.LoanID,
+			&repayment.Amount.Amount,
+			&repayment.Amount.Currency,
+			&repayment.PaidAt,
+			&repayment.RecordedAt,
+		); err != nil {
+			return nil, err
+		}
+		repayments = append(repayments, &repayment)
+	}
+
+	return repayments, rows.Err()
+}
+
+func (r *SQLLoanRepository) SavePayout(ctx context.Context, payout *domain.Payout) error {
+	if err := r.ensureLoanExists(ctx, payout.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO payouts (id, loan_id, repayment_id, investment_id, investor_id, amount_minor, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			amount_minor = excluded.amount_minor,
+			currency = excluded.currency`,
+		payout.ID,
+		payout.LoanID,
+		payout.RepaymentID,
+		payout.InvestmentID,
+		payout.InvestorID,
+		payout.Amount.Amount,
+		payout.Amount.Currency,
+		payout.CreatedAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) GetLoanPayouts(ctx context.Context, loanID string) ([]*domain.Payout, error) {
+	if err := r.ensureLoanExists(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT p.id, p.loan_id, p.repayment_id, p.investment_id, p.investor_id, p.amount_minor, p.currency, p.created_at
+		FROM payouts p
+		JOIN repayments r ON r.id = p.repayment_id
+		JOIN investments i ON i.id = p.investment_id
+		WHERE p.loan_id = $1
+		ORDER BY r.recorded_at, r.id, i.invested_at, i.id`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payouts := []*domain.Payout{}
+	for rows.Next() {
+		var payout domain.Payout
+		if err := rows.Scan(
+			&payout.ID,
+			&payout.LoanID,
+			&payout.RepaymentID,
+			&payout.InvestmentID,
+			&payout.InvestorID,
+			&payout.Amount.Amount,
+			&payout.Amount.Currency,
+			&payout.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &payout)
+	}
+
+	return payouts, rows.Err()
+}
+
+func (r *SQLLoanRepository) SaveRejection(ctx context.Context, rejection *domain.Rejection) error {
+	if err := r.ensureLoanExists(ctx, rejection.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO rejections (loan_id, reason, reviewer_id, rejected_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			reason = excluded.reason,
+			reviewer_id = excluded.reviewer_id,
+			rejected_at = excluded.rejected_at`,
+		rejection.LoanID,
+		rejection.Reason,
+		rejection.ReviewerID,
+		rejection.RejectedAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) SaveCancellation(ctx context.Context, cancellation *domain.Cancellation) error {
+	if err := r.ensureLoanExists(ctx, cancellation.LoanID); err != nil {
+		return err
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO cancellations (loan_id, reason, cancelled_by, cancelled_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			reason = excluded.reason,
+			cancelled_by = excluded.cancelled_by,
+			cancelled_at = excluded.cancelled_at`,
+		cancellation.LoanID,
+		cancellation.Reason,
+		cancellation.CancelledBy,
+		cancellation.CancelledAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) SaveOutboxMessage(ctx context.Context, message *domain.OutboxMessage) error {
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO outbox_messages (id, topic, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			attempts = excluded.attempts,
+			last_error = excluded.last_error,
+			next_attempt_at = excluded.next_attempt_at,
+			updated_at = excluded.updated_at,
+			delivered_at = excluded.delivered_at`,
+		message.ID,
+		message.Topic,
+		string(message.Payload),
+		string(message.Status),
+		message.Attempts,
+		message.LastError,
+		message.NextAttemptAt.UTC(),
+		message.CreatedAt.UTC(),
+		message.UpdatedAt.UTC(),
+		nullableTime(message.DeliveredAt),
+	)
+	return err
+}
+
+const outboxColumns = `id, topic, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at, delivered_at`
+
+func (r *SQLLoanRepository) GetOutboxMessage(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	row := r.conn.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM outbox_messages WHERE id = $1`, id)
+
+	message, err := scanOutboxMessage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return message, err
+}
+
+func (r *SQLLoanRepository) ListOutboxMessages(ctx context.Context, status domain.OutboxStatus, page, pageSize int) ([]*domain.OutboxMessage, int, error) {
+	var (
+		where string
+		args  []interface{}
+	)
+	if status != "" {
+		where = ` WHERE status = $1`
+		args = append(args, string(status))
+	}
+
+	var total int
+	if err := r.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox_messages`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + outboxColumns + ` FROM outbox_messages` + where + ` ORDER BY created_at, id`
+	if page > 0 && pageSize > 0 {
+		args = append(args, pageSize, (page-1)*pageSize)
+		query += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	}
+
+	messages, err := r.queryOutboxMessages(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+func (r *SQLLoanRepository) ListDueOutboxMessages(ctx context.Context, asOf time.Time, limit int) ([]*domain.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_messages
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id`
+	args := []interface{}{string(domain.OutboxStatusPending), asOf.UTC()}
+	if limit > 0 {
+		query += ` LIMIT $3`
+		args = append(args, limit)
+	}
+
+	return r.queryOutboxMessages(ctx, query, args...)
+}
+
+func (r *SQLLoanRepository) queryOutboxMessages(ctx context.Context, query string, args ...interface{}) ([]*domain.OutboxMessage, error) {
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*domain.OutboxMessage{}
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func scanOutboxMessage(row rowScanner) (*domain.OutboxMessage, error) {
+	var (
+		message     domain.OutboxMessage
+		payload     string
+		status      string
+		deliveredAt sql.NullTime
+	)
+
+	if err := row.Scan(
+		&message.ID,
+		&message.Topic,
+		&payload,
+		&status,
+		&message.Attempts,
+		&message.LastError,
+		&message.NextAttemptAt,
+		&message.CreatedAt,
+		&message.UpdatedAt,
+		&deliveredAt,
+	); err != nil {
+		return nil, err
+	}
+
+	message.Payload = []byte(payload)
+	message.Status = domain.OutboxStatus(status)
+	if deliveredAt.Valid {
+		message.DeliveredAt = &deliveredAt.Time
+	}
+
+	return &message, nil
+}
+
+func (r *SQLLoanRepository) SaveWebhookSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	events := make([]string, len(subscription.Events))
+	for i, event := range subscription.Events {
+		events[i] = string(event)
+	}
+
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, url, secret, events, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			url = excluded.url,
+			events = excluded.events,
+			active = excluded.active,
+			updated_at = excluded.updated_at`,
+		subscription.ID,
+		subscription.URL,
+		subscription.Secret,
+		strings.Join(events, ","),
+		subscription.Active,
+		subscription.CreatedAt.UTC(),
+		subscription.UpdatedAt.UTC(),
+	)
+	return err
+}
+
+const webhookSubscriptionColumns = `id, url, secret, events, active, created_at, updated_at`
+
+func (r *SQLLoanRepository) GetWebhookSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	row := r.conn.QueryRowContext(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+
+	subscription, err := scanWebhookSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+	return subscription, err
+}
+
+func (r *SQLLoanRepository) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	rows, err := r.conn.QueryContext(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func scanWebhookSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var (
+		subscription domain.WebhookSubscription
+		events       string
+	)
+
+	if err := row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		&subscription.Secret,
+		&events,
+		&subscription.Active,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	subscription.Events = []domain.WebhookEventType{}
+	if events != "" {
+		for _, event := range strings.Split(events, ",") {
+			subscription.Events = append(subscription.Events, domain.WebhookEventType(event))
+		}
+	}
+
+	return &subscription, nil
+}
+
+func (r *SQLLoanRepository) SaveWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, url, attempt, status_code, error, succeeded, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		string(delivery.EventType),
+		delivery.URL,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.Succeeded,
+		delivery.DurationMillis,
+		delivery.AttemptedAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) ListWebhookDeliveries(ctx context.Context, subscriptionID string, page, pageSize int) ([]*domain.WebhookDelivery, int, error) {
+	if _, err := r.GetWebhookSubscription(ctx, subscriptionID); err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE subscription_id = $1`, subscriptionID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, subscription_id, event_id, event_type, url, attempt, status_code, error, succeeded, duration_ms, attempted_at
+		FROM webhook_deliveries WHERE subscription_id = $1
+		ORDER BY attempted_at DESC, id`
+	args := []interface{}{subscriptionID}
+	if page > 0 && pageSize > 0 {
+		query += ` LIMIT $2 OFFSET $3`
+		args = append(args, pageSize, (page-1)*pageSize)
+	}
+
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			delivery  domain.WebhookDelivery
+			eventType string
+		)
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&eventType,
+			&delivery.URL,
+			&delivery.Attempt,
+			&delivery.StatusCode,
+			&delivery.Error,
+			&delivery.Succeeded,
+			&delivery.DurationMillis,
+			&delivery.AttemptedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		delivery.EventType = domain.WebhookEventType(eventType)
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+// AppendLoanEvents numbers each event after the last one stored for its loan
+// and inserts it, in one transaction (joining the caller's, if any)
+func (r *SQLLoanRepository) AppendLoanEvents(ctx context.Context, events ...*domain.LoanEvent) error {
+	return r.WithinTx(ctx, func(repo LoanRepository) error {
+		tx := repo.(*SQLLoanRepository)
+		for _, event := range events {
+			if err := tx.ensureLoanExists(ctx, event.LoanID); err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				return err
+			}
+
+			var last int
+			if err := tx.conn.QueryRowContext(ctx,
+				`SELECT COALESCE(MAX(sequence), 0) FROM loan_events WHERE loan_id = $1`, event.LoanID,
+			).Scan(&last); err != nil {
+				return err
+			}
+
+			if _, err := tx.conn.ExecContext(ctx, `
+				INSERT INTO loan_events (id, loan_id, sequence, type, data, occurred_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				event.ID,
+				event.LoanID,
+				last+1,
+				string(event.Type),
+				string(data),
+				event.OccurredAt.UTC(),
+			); err != nil {
+				return err
+			}
+			event.Sequence = last + 1
+		}
+
+		return nil
+	})
+}
+
+func (r *SQLLoanRepository) GetLoanEvents(ctx context.Context, loanID string) ([]*domain.LoanEvent, error) {
+	if err := r.ensureLoanExists(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT id, loan_id, sequence, type, data, occurred_at
+		FROM loan_events WHERE loan_id = $1
+		ORDER BY sequence`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.LoanEvent{}
+	for rows.Next() {
+		var (
+			event     domain.LoanEvent
+			eventType string
+			data      string
+		)
+		if err := rows.Scan(
+			&event.ID,
+			&event.LoanID,
+			&event.Sequence,
+			&eventType,
+			&data,
+			&event.OccurredAt,
+		); err != nil {
+			return nil, err
+		}
+
+		event.Type = domain.LoanEventType(eventType)
+		if event.Data, err = domain.DecodeLoanEventData(event.Type, []byte(data)); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *SQLLoanRepository) AppendAuditEntry(ctx context.Context, entry *domain.AuditEntry) error {
+	_, err := r.conn.ExecContext(ctx, `
+		INSERT INTO audit_entries (id, sequence, actor, action, loan_id, request_id, before_state, after_state, occurred_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		entry.ID,
+		entry.Sequence,
+		entry.Actor,
+		string(entry.Action),
+		entry.LoanID,
+		entry.RequestID,
+		string(entry.Before),
+		string(entry.After),
+		entry.OccurredAt.UTC(),
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.ExecContext(ctx, `UPDATE audit_chain_head SET sequence = $1 WHERE id = 1`, entry.Sequence)
+	return err
+}
+
+const auditEntryColumns = `id, sequence, actor, action, loan_id, request_id, before_state, after_state, occurred_at, prev_hash, hash`
+
+// LastAuditEntry reads the chain head with a no-op UPDATE, which holds the
+// row lock until the transaction ends. Another transaction appending to the
+// trail waits here, then sees the entry this one appended.
+func (r *SQLLoanRepository) LastAuditEntry(ctx context.Context) (*domain.AuditEntry, error) {
+	var sequence int64
+	if err := r.conn.QueryRowContext(ctx, `UPDATE audit_chain_head SET sequence = sequence WHERE id = 1 RETURNING sequence`).Scan(&sequence); err != nil {
+		return nil, err
+	}
+
+	row := r.conn.QueryRowContext(ctx, `SELECT `+auditEntryColumns+` FROM audit_entries WHERE sequence = $1`, sequence)
+
+	entry, err := scanAuditEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+func (r *SQLLoanRepository) ListAuditEntries(ctx context.Context, filter domain.AuditFilter, page, pageSize int) ([]*domain.AuditEntry, int, error) {
+	where, args := auditFilterClause(filter)
+
+	var total int
+	if err := r.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_entries`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + auditEntryColumns + ` FROM audit_entries` + where + ` ORDER BY sequence`
+	if page > 0 && pageSize > 0 {
+		args = append(args, pageSize, (page-1)*pageSize)
+		query += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	}
+
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*domain.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+func scanAuditEntry(row rowScanner) (*domain.AuditEntry, error) {
+	var (
+		entry  domain.AuditEntry
+		action string
+		before string
+		after  string
+	)
+	if err := row.Scan(
+		&entry.ID,
+		&entry.Sequence,
+		&entry.Actor,
+		&action,
+		&entry.LoanID,
+		&entry.RequestID,
+		&before,
+		&after,
+		&entry.OccurredAt,
+		&entry.PrevHash,
+		&entry.Hash,
+	); err != nil {
+		return nil, err
+	}
+
+	entry.Action = domain.AuditAction(action)
+	if before != "" {
+		entry.Before = json.RawMessage(before)
+	}
+	if after != "" {
+		entry.After = json.RawMessage(after)
+	}
+
+	return &entry, nil
+}
+
+// ReserveIdempotencyKey inserts the record, or takes over an expired one,
+// in a single statement so that concurrent requests cannot both reserve the
+// key
+func (r *SQLLoanRepository) ReserveIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	result, err := r.conn.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (actor, idempotency_key, request_hash, status_code, body, created_at)
+		VALUES ($1, $2, $3, 0, '', $4)
+		ON CONFLICT (actor, idempotency_key) DO UPDATE
+		SET request_hash = excluded.request_hash, status_code = 0, body = '', created_at = excluded.created_at
+		WHERE idempotency_keys.created_at <= $5`,
+		record.Actor,
+		record.Key,
+		record.RequestHash,
+		record.CreatedAt.UTC(),
+		record.CreatedAt.Add(-domain.IdempotencyKeyTTL).UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if reserved > 0 {
+		return nil, nil
+	}
+
+	existing := domain.IdempotencyRecord{Actor: record.Actor, Key: record.Key}
+	var body string
+	err = r.conn.QueryRowContext(ctx, `
+		SELECT request_hash, status_code, body, created_at
+		FROM idempotency_keys WHERE actor = $1 AND idempotency_key = $2`,
+		record.Actor,
+		record.Key,
+	).Scan(&existing.RequestHash, &existing.StatusCode, &body, &existing.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	existing.Body = []byte(body)
+
+	return &existing, nil
+}
+
+func (r *SQLLoanRepository) SaveIdempotencyRecord(ctx context.Context, record *domain.IdempotencyRecord) error {
+	_, err := r.conn.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $1, body = $2
+		WHERE actor = $3 AND idempotency_key = $4`,
+		record.StatusCode,
+		string(record.Body),
+		record.Actor,
+		record.Key,
+	)
+	return err
+}
+
+func (r *SQLLoanRepository) DeleteIdempotencyRecord(ctx context.Context, actor, key string) error {
+	_, err := r.conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE actor = $1 AND idempotency_key = $2`, actor, key)
+	return err
+}
+
+// PurgeExpiredIdempotencyRecords deletes records reserved at least
+// domain.IdempotencyKeyTTL before asOf, the same ones ReserveIdempotencyKey
+// would take over
+func (r *SQLLoanRepository) PurgeExpiredIdempotencyRecords(ctx context.Context, asOf time.Time) (int, error) {
+	result, err := r.conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at <= $1`, asOf.Add(-domain.IdempotencyKeyTTL).UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+func auditFilterClause(filter domain.AuditFilter) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if filter.LoanID != "" {
+		args = append(args, filter.LoanID)
+		conditions = append(conditions, fmt.Sprintf("loan_id = $%d", len(args)))
+	}
+
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		conditions = append(conditions, fmt.Sprintf("actor = $%d", len(args)))
+	}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// loanFilterClause renders filter as a WHERE clause using $N placeholders,
+// returning the clause (empty when nothing is filtered) and its arguments
+func loanFilterClause(filter domain.LoanFilter) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if len(filter.States) > 0 {
+		placeholders := make([]string, len(filter.States))
+		for i, state := range filter.States {
+			args = append(args, string(state))
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, "l.state IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if filter.BorrowerID != "" {
+		args = append(args, filter.BorrowerID)
+		conditions = append(conditions, fmt.Sprintf("l.borrower_id = $%d", len(args)))
+	}
+
+	for _, bound := range []struct {
+		amount *domain.Money
+		op     string
+	}{{filter.MinPrincipal, ">="}, {filter.MaxPrincipal, "<="}} {
+		if bound.amount != nil {
+			args = append(args, bound.amount.Currency, bound.amount.Amount)
+			conditions = append(conditions, fmt.Sprintf("l.currency = $%d AND l.principal_minor %s $%d", len(args)-1, bound.op, len(args)))
+		}
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		args = append(args, filter.CreatedFrom.UTC())
+		conditions = append(conditions, fmt.Sprintf("l.created_at >= $%d", len(args)))
+	}
+
+	if !filter.CreatedTo.IsZero() {
+		args = append(args, filter.CreatedTo.UTC())
+		conditions = append(conditions, fmt.Sprintf("l.created_at < $%d", len(args)))
+	}
+
+	// Compared without dividing, as domain.LoanFilter.Matches does
+	for _, bound := range []struct {
+		percent *float64
+		op      string
+	}{{filter.MinFundedPercent, ">="}, {filter.MaxFundedPercent, "<="}} {
+		if bound.percent != nil {
+			args = append(args, *bound.percent)
+			conditions = append(conditions, fmt.Sprintf("COALESCE(i.invested_minor, 0) * 100.0 %s CAST($%d AS DOUBLE PRECISION) * l.principal_minor", bound.op, len(args)))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// loanSortKey is an expression a listing is ordered by, with the cursor's
+// position on it
+type loanSortKey struct {
+	expr   string
+	values []interface{}
+	// compared returns the loan's side and the cursor's side of a
+	// comparison on this key, given the placeholders bound to values
+	compared func(placeholders []string) (string, string)
+}
+
+func columnSortKey(expr string, value interface{}) loanSortKey {
+	return loanSortKey{
+		expr:   expr,
+		values: []interface{}{value},
+		compared: func(placeholders []string) (string, string) {
+			return expr, placeholders[0]
+		},
+	}
+}
+
+// loanSortKeys are the keys a listing is ordered by, most significant
+// first. The funded share is ordered by its quotient but compared with the
+// cursor by cross-multiplying the integer amounts, as
+// domain.LoanSort.Less does, so that pages meet exactly whatever numeric
+// type the engine divides in.
+func loanSortKeys(order domain.LoanSort, cursor *domain.LoanCursor) []loanSortKey {
+	var keys []loanSortKey
+	switch order.Field {
+	case domain.LoanSortPrincipalAmount:
+		keys = append(keys, columnSortKey("l.principal_minor", cursor.PrincipalMinor))
+	case domain.LoanSortFundedPercent:
+		keys = append(keys, loanSortKey{
+			expr:   "COALESCE(i.invested_minor, 0) * 1.0 / l.principal_minor",
+			values: []interface{}{cursor.InvestedMinor, cursor.PrincipalMinor},
+			compared: func(placeholders []string) (string, string) {
+				return "COALESCE(i.invested_minor, 0) * " + placeholders[1], placeholders[0] + " * l.principal_minor"
+			},
+		})
+	}
+
+	return append(keys, columnSortKey("l.created_at", cursor.CreatedAt.UTC()), columnSortKey("l.id", cursor.ID))
+}
+
+// loanOrderClause orders a listing as domain.LoanSort.Less does
+func loanOrderClause(order domain.LoanSort) string {
+	direction := " ASC"
+	if order.Descending {
+		direction = " DESC"
+	}
+
+	var exprs []string
+	for _, key := range loanSortKeys(order, &domain.LoanCursor{}) {
+		exprs = append(exprs, key.expr)
+	}
+	return " ORDER BY " + strings.Join(exprs, direction+", ") + direction
+}
+
+// loanAfterCondition selects the loans ordered after the cursor: those
+// beyond it on the first key, or equal on it and beyond on the next, and so
+// on
+func loanAfterCondition(order domain.LoanSort, after *domain.LoanCursor, args []interface{}) (string, []interface{}) {
+	op := ">"
+	if order.Descending {
+		op = "<"
+	}
+
+	keys := loanSortKeys(order, after)
+	loanSides := make([]string, len(keys))
+	cursorSides := make([]string, len(keys))
+	for i, key := range keys {
+		placeholders := make([]string, len(key.values))
+		for j, value := range key.values {
+			args = append(args, value)
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+		}
+		loanSides[i], cursorSides[i] = key.compared(placeholders)
+	}
+
+	last := len(keys) - 1
+	condition := fmt.Sprintf("%s %s %s", loanSides[last], op, cursorSides[last])
+	for i := last - 1; i >= 0; i-- {
+		condition = fmt.Sprintf("(%s %s %s OR (%s = %s AND %s))", loanSides[i], op, cursorSides[i], loanSides[i], cursorSides[i], condition)
+	}
+
+	return condition, args
+}
+
+func (r *SQLLoanRepository) ensureLoanExists(ctx context.Context, loanID string) error {
+	var exists int
+	err := r.conn.QueryRowContext(ctx, `SELECT 1 FROM loans WHERE id = $1`, loanID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLoanNotFound
+	}
+	return err
+}
+
+func (r *SQLLoanRepository) loadRelations(ctx context.Context, loan *domain.Loan) error {
+	approval := &domain.Approval{LoanID: loan.ID}
+	err := r.conn.QueryRowContext(ctx, `
+		SELECT proof_picture_url, field_validator_id, approval_date
+		FROM approvals WHERE loan_id = $1`, loan.ID,
+	).Scan(&approval.ProofPictureURL, &approval.FieldValidatorID, &approval.ApprovalDate)
+	switch {
+	case err == nil:
+		loan.Approval = approval
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load approval: %w", err)
+	}
+
+	investments, err := r.loanInvestments(ctx, loan.ID)
+	if err != nil {
+		return fmt.Errorf("load investments: %w", err)
+	}
+	loan.Investments = investments
+
+	disbursement := &domain.Disbursement{LoanID: loan.ID}
+	err = r.conn.QueryRowContext(ctx, `
+		SELECT agreement_document_url, field_officer_id, disbursement_date
+		FROM disbursements WHERE loan_id = $1`, loan.ID,
+	).Scan(&disbursement.AgreementDocumentURL, &disbursement.FieldOfficerID, &disbursement.DisbursementDate)
+	switch {
+	case err == nil:
+		loan.Disbursement = disbursement
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load disbursement: %w", err)
+	}
+
+	rejection := &domain.Rejection{LoanID: loan.ID}
+	err = r.conn.QueryRowContext(ctx, `
+		SELECT reason, reviewer_id, rejected_at
+		FROM rejections WHERE loan_id = $1`, loan.ID,
+	).Scan(&rejection.Reason, &rejection.ReviewerID, &rejection.RejectedAt)
+	switch {
+	case err == nil:
+		loan.Rejection = rejection
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load rejection: %w", err)
+	}
+
+	cancellation := &domain.Cancellation{LoanID: loan.ID}
+	err = r.conn.QueryRowContext(ctx, `
+		SELECT reason, cancelled_by, cancelled_at
+		FROM cancellations WHERE loan_id = $1`, loan.ID,
+	).Scan(&cancellation.Reason, &cancellation.CancelledBy, &cancellation.CancelledAt)
+	switch {
+	case err == nil:
+		loan.Cancellation = cancellation
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load cancellation: %w", err)
+	}
+
+	return nil
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (r *SQLLoanRepository) loanInvestments(ctx context.Context, loanID string) ([]*domain.Investment, error) {
+	rows, err := r.conn.QueryContext(ctx, `
+		SELECT id, loan_id, investor_id, amount_minor, currency, status, invested_at, refunded_at
+		FROM investments WHERE loan_id = $1
+		ORDER BY invested_at, id`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	investments := []*domain.Investment{}
+	for rows.Next() {
+		var (
+			investment domain.Investment
+			status     string
+			refundedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&investment.ID,
+			&investment.LoanID,
+			&investment.InvestorID,
+			&investment.Amount.Amount,
+			&investment.Amount.Currency,
+			&status,
+			&investment.InvestedAt,
+			&refundedAt,
+		); err != nil {
+			return nil, err
+		}
+		investment.Status = domain.InvestmentStatus(status)
+		if refundedAt.Valid {
+			investment.RefundedAt = &refundedAt.Time
+		}
+		investments = append(investments, &investment)
+	}
+
+	return investments, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLoan(row rowScanner) (*domain.Loan, error) {
+	var (
+		loan  domain.Loan
+		state string
+	)
+
+	if err := row.Scan(
+		&loan.ID,
+		&loan.BorrowerID,
+		&loan.PrincipalAmount.Amount,
+		&loan.PrincipalAmount.Currency,
+		&loan.Rate,
+		&loan.ROI,
+		&state,
+		&loan.AgreementLetterURL,
+		&loan.CreatedAt,
+		&loan.UpdatedAt,
+		&loan.Version,
+	); err != nil {
+		return nil, err
+	}
+
+	loan.State = domain.LoanState(state)
+	loan.Investments = []*domain.Investment{}
+
+	return &loan, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"loan/internal/document"
+	"loan/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed templates/agreement_letter.html
+var agreementTemplates embed.FS
+
+var agreementLetterTemplate = template.Must(
+	template.ParseFS(agreementTemplates, "templates/agreement_letter.html"),
+)
+
+// AgreementLetterGenerator produces the agreement letter for a fully invested
+// loan and returns the URL investors are sent. AgreementLetter reads a
+// generated letter back as a PDF, returning an error matching
+// domain.ErrNotFound if there is none.
+type AgreementLetterGenerator interface {
+	GenerateAgreementLetter(ctx context.Context, loan *domain.Loan) (string, error)
+	AgreementLetter(ctx context.Context, loanID string) ([]byte, error)
+}
+
+// DocumentAgreementLetterGenerator renders the letter as HTML and PDF and
+// saves both to a document store under the loan's ID. Names are fixed per
+// loan, so regenerating a letter replaces the old one. The URL it returns is
+// the API route serving the PDF, below baseURL.
+type DocumentAgreementLetterGenerator struct {
+	store   document.Store
+	baseURL string
+}
+
+func NewAgreementLetterGenerator(store document.Store, baseURL string) *DocumentAgreementLetterGenerator {
+	return &DocumentAgreementLetterGenerator{
+		store:   store,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (g *DocumentAgreementLetterGenerator) GenerateAgreementLetter(ctx context.Context, loan *domain.Loan) (string, error) {
+	data := newAgreementLetterData(loan, time.Now())
+
+	var html bytes.Buffer
+	if err := agreementLetterTemplate.Execute(&html, data); err != nil {
+		return "", fmt.Errorf("render agreement letter: %w", err)
+	}
+
+	if err := g.store.Put(ctx, loan.ID+"/agreement_letter.html", "text/html; charset=utf-8", html.Bytes()); err != nil {
+		return "", fmt.Errorf("store agreement letter: %w", err)
+	}
+
+	if err := g.store.Put(ctx, loan.ID+"/agreement_letter.pdf", "application/pdf", document.RenderPDF(data.lines())); err != nil {
+		return "", fmt.Errorf("store agreement letter: %w", err)
+	}
+
+	return g.baseURL + "/api/v1/loans/" + loan.ID + "/agreement-letter", nil
+}
+
+func (g *DocumentAgreementLetterGenerator) AgreementLetter(ctx context.Context, loanID string) ([]byte, error) {
+	pdf, err := g.store.Get(ctx, loanID+"/agreement_letter.pdf")
+	if errors.Is(err, document.ErrNotFound) {
+		return nil, domain.NotFoundError("no agreement letter for loan %s", loanID)
+	}
+	return pdf, err
+}
+
+// GetAgreementLetter returns the PDF agreement letter of a loan. Only the
+// borrower and investors of the loan may read it.
+func (s *LoanService) GetAgreementLetter(ctx context.Context, loanID string) ([]byte, error) {
+	loan, err := s.repo.GetLoanByID(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeParty(ctx, loan); err != nil {
+		return nil, err
+	}
+
+	if loan.AgreementLetterURL == "" || s.agreementLetters == nil {
+		return nil, domain.NotFoundError("no agreement letter for loan %s", loanID)
+	}
+	return s.agreementLetters.AgreementLetter(ctx, loanID)
+}
+
+type agreementLetterData struct {
+	LoanID     string
+	BorrowerID string
+	Date       string
+	Principal  string
+	Rate       string
+	ROI        string
+	Investors  []agreementLetterInvestor
+}
+
+type agreementLetterInvestor struct {
+	InvestorID string
+	Amount     string
+	Share      string
+}
+
+func newAgreementLetterData(loan *domain.Loan, date time.Time) agreementLetterData {
+	data := agreementLetterData{
+		LoanID:     loan.ID,
+		BorrowerID: loan.BorrowerID,
+		Date:       date.Format("2006-01-02"),
+		Principal:  loan.PrincipalAmount.String(),
+		Rate:       formatPercent(loan.Rate),
+		ROI:        formatPercent(loan.ROI),
+	}
+
+	for _, investment := range loan.Investments {
+		if investment.Status != domain.InvestmentStatusActive {
+			continue
+		}
+		data.Investors = append(data.Investors, agreementLetterInvestor{
+			InvestorID: investment.InvestorID,
+			Amount:     investment.Amount.String(),
+			Share:      formatPercent(float64(investment.Amount.Amount) / float64(loan.PrincipalAmount.Amount)),
+		})
+	}
+
+	return data
+}
+
+// lines is the plain-text layout of the letter used for the PDF
+func (d agreementLetterData) lines() []string {
+	lines := []string{
+		"LOAN AGREEMENT",
+		"Dated " + d.Date,
+		"",
+		"Loan ID:        " + d.LoanID,
+		"Borrower ID:    " + d.BorrowerID,
+		"Principal:      " + d.Principal,
+		"Interest rate:  " + d.Rate,
+		"Investor ROI:   " + d.ROI,
+		"",
+		"INVESTORS",
+	}
+
+	for _, investor := range d.Investors {
+		lines = append(lines, fmt.Sprintf("%s  %s  (%s)", investor.InvestorID, investor.Amount, investor.Share))
+	}
+
+	return append(lines,
+		"",
+		"The borrower agrees to repay the principal with interest at the rate above.",
+		"The investors above have funded the principal in full and will receive",
+		"repayments in proportion to their share, with returns at the ROI above.",
+	)
+}
+
+// formatPercent renders a fraction such as 0.075 as "7.5%"
+func formatPercent(f float64) string {
+	percent := strconv.FormatFloat(f*100, 'f', 2, 64)
+	percent = strings.TrimRight(strings.TrimRight(percent, "0"), ".")
+	return percent + "%"
+}
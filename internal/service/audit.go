@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"loan/internal/domain"
+	"loan/internal/repository"
+)
+
+// snapshot captures a record as JSON before it is changed
+func snapshot(record interface{}) (json.RawMessage, error) {
+	return json.Marshal(record)
+}
+
+// audit appends an entry for action, by the context's actor, to the audit
+// trail as part of the caller's unit of work, so only committed changes are
+// audited. after is snapshotted now; before is nil for a new record.
+func audit(ctx context.Context, repo repository.LoanRepository, action domain.AuditAction, loanID string, before json.RawMessage, after interface{}) error {
+	afterSnapshot, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	last, err := repo.LastAuditEntry(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := domain.NewAuditEntry(ctx, action, loanID, before, afterSnapshot)
+	entry.Chain(last)
+	return repo.AppendAuditEntry(ctx, entry)
+}
+
+// ListAuditEntries pages through the audit trail in the order it was written
+func (s *LoanService) ListAuditEntries(ctx context.Context, filter domain.AuditFilter, page, pageSize int) ([]*domain.AuditEntry, int, error) {
+	return s.repo.ListAuditEntries(ctx, filter, page, pageSize)
+}
+
+// VerifyAuditTrail recomputes the hash chain over the whole audit trail
+func (s *LoanService) VerifyAuditTrail(ctx context.Context) (*domain.AuditVerification, error) {
+	entries, _, err := s.repo.ListAuditEntries(ctx, domain.AuditFilter{}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.VerifyAuditTrail(entries), nil
+}
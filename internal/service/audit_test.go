@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"testing"
+	"time"
+)
+
+func TestMutationsAreAudited(t *testing.T) {
+	// Arrange
+	alice := domain.ContextWithRequestID(domain.ContextWithPrincipal(context.Background(), &domain.Principal{Subject: "alice", Roles: []domain.Role{domain.RoleAdmin}}), "req_1")
+	bob := domain.ContextWithPrincipal(context.Background(), &domain.Principal{Subject: "bob", Roles: []domain.Role{domain.RoleAdmin}})
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+
+	// Act
+	loan, _ := loanService.CreateLoan(alice, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(bob, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(bob, loan.ID, "investor123", mustMoney("2000.00")) // over-funds, so fails
+	_, _ = loanService.CreateWebhookSubscription(alice, "https://example.com/hooks", nil)
+
+	// Assert
+	entries, total, err := loanService.ListAuditEntries(context.Background(), domain.AuditFilter{}, 0, 0)
+	if err != nil || total != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d (%v)", total, err)
+	}
+
+	created, approved, subscribed := entries[0], entries[1], entries[2]
+	if created.Action != domain.AuditCreateLoan || created.Actor != "alice" || created.RequestID != "req_1" || created.LoanID != loan.ID || created.Before != nil {
+		t.Errorf("Expected loan creation by alice in req_1, got %+v", created)
+	}
+	if approved.Action != domain.AuditApproveLoan || approved.Actor != "bob" || approved.LoanID != loan.ID {
+		t.Errorf("Expected approval by bob, got %+v", approved)
+	}
+
+	var before, after domain.Loan
+	_ = json.Unmarshal(approved.Before, &before)
+	_ = json.Unmarshal(approved.After, &after)
+	if before.State != domain.LoanStateProposed || after.State != domain.LoanStateApproved {
+		t.Errorf("Expected approval to record PROPOSED -> APPROVED, got %s -> %s", before.State, after.State)
+	}
+
+	var subscription domain.WebhookSubscription
+	_ = json.Unmarshal(subscribed.After, &subscription)
+	if subscribed.Action != domain.AuditCreateWebhook || subscription.URL != "https://example.com/hooks" || subscription.Secret != "" {
+		t.Errorf("Expected a webhook subscription without its secret, got %s", subscribed.After)
+	}
+
+	verification, err := loanService.VerifyAuditTrail(context.Background())
+	if err != nil || !verification.Valid || verification.EntriesChecked != 3 {
+		t.Errorf("Expected the audit trail to verify, got %+v (%v)", verification, err)
+	}
+
+	byBob, total, _ := loanService.ListAuditEntries(context.Background(), domain.AuditFilter{Actor: "bob", LoanID: loan.ID}, 1, 10)
+	if total != 1 || byBob[0].ID != approved.ID {
+		t.Errorf("Expected only bob's approval, got %+v", byBob)
+	}
+}
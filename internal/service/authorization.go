@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"loan/internal/domain"
+)
+
+// authorize checks that the caller in ctx holds role and is the person
+// identified by id. Admins may act on behalf of anyone. Calls made outside
+// a request, such as background jobs, carry no principal and are trusted.
+// Other callers get an error matching domain.ErrForbidden.
+func authorize(ctx context.Context, role domain.Role, id string) error {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok || principal.HasRole(domain.RoleAdmin) {
+		return nil
+	}
+
+	if !principal.HasRole(role) || principal.Subject != id {
+		return domain.ForbiddenError("only %s %s may perform this action", role, id)
+	}
+	return nil
+}
+
+// authorizeParty checks that the caller in ctx is the borrower of loan or one
+// of its investors, under the same rules as authorize
+func authorizeParty(ctx context.Context, loan *domain.Loan) error {
+	if authorize(ctx, domain.RoleBorrower, loan.BorrowerID) == nil {
+		return nil
+	}
+	for _, investment := range loan.Investments {
+		if authorize(ctx, domain.RoleInvestor, investment.InvestorID) == nil {
+			return nil
+		}
+	}
+	return domain.ForbiddenError("only the borrower and investors of loan %s may perform this action", loan.ID)
+}
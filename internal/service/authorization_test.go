@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"loan/internal/document"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"testing"
+	"time"
+)
+
+func as(subject string, roles ...domain.Role) context.Context {
+	return domain.ContextWithPrincipal(context.Background(), &domain.Principal{Subject: subject, Roles: roles})
+}
+
+func TestCallersMayOnlyActAsThemselves(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	borrower := as("borrower123", domain.RoleBorrower)
+	validator := as("validator123", domain.RoleFieldValidator)
+	investor := as("investor123", domain.RoleInvestor)
+	officer := as("officer123", domain.RoleFieldOfficer)
+	admin := as("admin1", domain.RoleAdmin)
+
+	loan, err := loanService.CreateLoan(borrower, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	if err != nil {
+		t.Fatalf("Expected borrowers to create their own loans, got %v", err)
+	}
+
+	tests := map[string]func() error{
+		"borrower creating a loan for someone else": func() error {
+			_, err := loanService.CreateLoan(borrower, "borrower456", mustMoney("1000.00"), 0.1, 0.08)
+			return err
+		},
+		"validator approving as another validator": func() error {
+			_, err := loanService.ApproveLoan(validator, loan.ID, "proof.jpg", "validator456", time.Now())
+			return err
+		},
+		"investor approving as a validator": func() error {
+			_, err := loanService.ApproveLoan(as("validator123", domain.RoleInvestor), loan.ID, "proof.jpg", "validator123", time.Now())
+			return err
+		},
+		"validator rejecting as another validator": func() error {
+			_, err := loanService.RejectLoan(validator, loan.ID, "incomplete", "validator456", time.Now())
+			return err
+		},
+		"borrower cancelling another borrower's loan": func() error {
+			_, err := loanService.CancelLoan(as("borrower456", domain.RoleBorrower), loan.ID, "changed my mind", "borrower456", time.Now())
+			return err
+		},
+	}
+
+	for name, act := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			err := act()
+
+			// Assert
+			if !errors.Is(err, domain.ErrForbidden) {
+				t.Errorf("Expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+
+	// Act
+	_, err = loanService.ApproveLoan(validator, loan.ID, "proof.jpg", "validator123", time.Now())
+	if err != nil {
+		t.Fatalf("Expected the validator to approve as themselves, got %v", err)
+	}
+
+	_, forbidden := loanService.AddInvestment(investor, loan.ID, "investor456", mustMoney("500.00"))
+	_, allowed := loanService.AddInvestment(investor, loan.ID, "investor123", mustMoney("500.00"))
+	_, onBehalf := loanService.AddInvestment(admin, loan.ID, "investor456", mustMoney("500.00"))
+
+	// Assert
+	if !errors.Is(forbidden, domain.ErrForbidden) {
+		t.Errorf("Expected investing as another investor to be forbidden, got %v", forbidden)
+	}
+	if allowed != nil || onBehalf != nil {
+		t.Errorf("Expected the investor and an admin on their behalf to invest, got %v and %v", allowed, onBehalf)
+	}
+
+	_, forbidden = loanService.DisburseLoan(officer, loan.ID, "agreement.pdf", "officer456", time.Now())
+	if !errors.Is(forbidden, domain.ErrForbidden) {
+		t.Errorf("Expected disbursing as another field officer to be forbidden, got %v", forbidden)
+	}
+
+	disbursed, err := loanService.DisburseLoan(officer, loan.ID, "agreement.pdf", "officer123", time.Now())
+	if err != nil || disbursed.State != domain.LoanStateDisbursed {
+		t.Errorf("Expected the field officer to disburse as themselves, got %v", err)
+	}
+}
+
+func TestOnlyPartiesToALoanMayReadItsAgreementLetter(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithAgreementLetterGenerator(service.NewAgreementLetterGenerator(document.NewMemoryStore(), "https://loans.example.com")))
+	ctx := context.Background()
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor123", mustMoney("1000.00"))
+
+	tests := map[string]struct {
+		caller  context.Context
+		allowed bool
+	}{
+		"borrower":             {as("borrower123", domain.RoleBorrower), true},
+		"investor":             {as("investor123", domain.RoleInvestor), true},
+		"admin":                {as("admin1", domain.RoleAdmin), true},
+		"another borrower":     {as("borrower456", domain.RoleBorrower), false},
+		"another investor":     {as("investor456", domain.RoleInvestor), false},
+		"borrower as investor": {as("borrower123", domain.RoleInvestor), false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			letter, err := loanService.GetAgreementLetter(tt.caller, loan.ID)
+
+			// Assert
+			if tt.allowed && (err != nil || len(letter) == 0) {
+				t.Errorf("Expected the letter, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, domain.ErrForbidden) {
+				t.Errorf("Expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
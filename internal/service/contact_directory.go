@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loan/internal/domain"
+	"os"
+	"sort"
+)
+
+// ContactDirectory resolves the borrower, investor and staff IDs that loans
+// carry to contact details and channel preferences
+type ContactDirectory interface {
+	LookupContact(ctx context.Context, role domain.RecipientRole, id string) (*domain.Contact, error)
+	ListContacts(ctx context.Context, role domain.RecipientRole) ([]*domain.Contact, error)
+}
+
+// StaticContactDirectory is a ContactDirectory over a fixed set of contacts,
+// e.g. loaded from a file at startup
+type StaticContactDirectory struct {
+	contacts map[domain.RecipientRole]map[string]*domain.Contact
+}
+
+func NewStaticContactDirectory(contacts ...*domain.Contact) *StaticContactDirectory {
+	directory := &StaticContactDirectory{
+		contacts: make(map[domain.RecipientRole]map[string]*domain.Contact),
+	}
+	for _, contact := range contacts {
+		if directory.contacts[contact.Role] == nil {
+			directory.contacts[contact.Role] = make(map[string]*domain.Contact)
+		}
+		directory.contacts[contact.Role][contact.ID] = contact
+	}
+	return directory
+}
+
+// LoadContactDirectory reads a JSON array of contacts from path
+func LoadContactDirectory(path string) (*StaticContactDirectory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []*domain.Contact
+	if err := json.Unmarshal(data, &contacts); err != nil {
+		return nil, fmt.Errorf("parse contact directory %s: %w", path, err)
+	}
+
+	for _, contact := range contacts {
+		if err := contact.Validate(); err != nil {
+			return nil, fmt.Errorf("contact directory %s: %w", path, err)
+		}
+	}
+
+	return NewStaticContactDirectory(contacts...), nil
+}
+
+func (d *StaticContactDirectory) LookupContact(ctx context.Context, role domain.RecipientRole, id string) (*domain.Contact, error) {
+	contact, exists := d.contacts[role][id]
+	if !exists {
+		return nil, domain.NotFoundError("%s %s not found in contact directory", role, id)
+	}
+	return contact, nil
+}
+
+// ListContacts returns every contact with the role, ordered by ID
+func (d *StaticContactDirectory) ListContacts(ctx context.Context, role domain.RecipientRole) ([]*domain.Contact, error) {
+	contacts := make([]*domain.Contact, 0, len(d.contacts[role]))
+	for _, contact := range d.contacts[role] {
+		contacts = append(contacts, contact)
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].ID < contacts[j].ID
+	})
+	return contacts, nil
+}
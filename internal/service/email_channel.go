@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"loan/util"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSMTPTimeout bounds a single delivery when SMTPConfig.Timeout is zero
+const defaultSMTPTimeout = 30 * time.Second
+
+// Mailer delivers a rendered message to one address
+type Mailer interface {
+	Send(ctx context.Context, to *mail.Address, message *EmailMessage) error
+}
+
+// EmailChannel renders notifications from templates and mails them to the
+// contact's email address
+type EmailChannel struct {
+	mailer    Mailer
+	templates *NotificationTemplates
+}
+
+// NewEmailChannel uses the default templates when templates is nil
+func NewEmailChannel(mailer Mailer, templates *NotificationTemplates) *EmailChannel {
+	if templates == nil {
+		templates = DefaultNotificationTemplates()
+	}
+	return &EmailChannel{
+		mailer:    mailer,
+		templates: templates,
+	}
+}
+
+func (c *EmailChannel) Name() domain.NotificationChannel {
+	return domain.ChannelEmail
+}
+
+func (c *EmailChannel) Send(ctx context.Context, contact *domain.Contact, notification *domain.Notification) error {
+	if contact.Email == "" {
+		return fmt.Errorf("%s %s has no email address", contact.Role, contact.ID)
+	}
+
+	message, err := c.templates.Render(contact, notification)
+	if err != nil {
+		return err
+	}
+
+	return c.mailer.Send(ctx, &mail.Address{Name: contact.Name, Address: contact.Email}, message)
+}
+
+// LogMailer prints messages instead of sending them, for development
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to *mail.Address, message *EmailMessage) error {
+	// Logging the email as simulation
+	domain.LoggerFromContext(ctx).Info("sending email", "to", to.String(), "subject", message.Subject)
+	return nil
+}
+
+// SMTPConfig holds the mail server and sender used by SMTPMailer. STARTTLS
+// is used whenever the server offers it, and authentication is attempted
+// only when Username is set.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string        // sender address, e.g. "Loans <loans@example.com>"
+	Timeout  time.Duration // per message, including connecting
+}
+
+func (c SMTPConfig) Validate() error {
+	if c.Host == "" {
+		return errors.New("SMTP host cannot be empty")
+	}
+
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", c.Port)
+	}
+
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", c.From, err)
+	}
+
+	return nil
+}
+
+// SMTPMailer sends messages as multipart text/HTML mail over SMTP
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{
+		config: config,
+	}
+}
+
+// Send delivers one message, honouring ctx and the configured timeout
+func (m *SMTPMailer) Send(ctx context.Context, to *mail.Address, email *EmailMessage) error {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	message, err := buildMessage(from, to, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	timeout := m.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultSMTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port)))
+	if err != nil {
+		return fmt.Errorf("connect to SMTP server: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP RCPT TO %s: %w", to.Address, err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage formats a multipart/alternative message with quoted-printable
+// text and HTML parts
+func buildMessage(from, to *mail.Address, email *EmailMessage, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	domainPart := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", email.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + util.GenerateUUID() + "@" + domainPart + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	} {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
@@ -0,0 +1,237 @@
+package service_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"loan/internal/domain"
+	"loan/internal/service"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeSMTPServer accepts mail on a loopback port and keeps each message it
+// receives, speaking just enough SMTP for net/smtp
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu         sync.Mutex
+	recipients []string
+	messages   []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected to listen on loopback, got %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// received returns the recipients and messages accepted so far
+func (s *fakeSMTPServer) received() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.recipients...), append([]string(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake.smtp")
+		case strings.HasPrefix(command, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO"):
+			s.mu.Lock()
+			s.recipients = append(s.recipients, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// bodyParts decodes each part of a multipart message by content type
+func bodyParts(t *testing.T, message *mail.Message) map[string]string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative message, got %q (%v)", mediaType, err)
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("Expected to read message part, got %v", err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		content, _ := io.ReadAll(quotedprintable.NewReader(part))
+		parts[contentType] = string(content)
+	}
+}
+
+// investedNotification is a LOAN_INVESTED notification to investor1
+func investedNotification() *domain.Notification {
+	notification := domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor1", "loan_123").
+		WithAmount(mustMoney("600.00"))
+	notification.AgreementLetterURL = "https://docs.example.com/loan_123/agreement_letter.pdf"
+	return notification
+}
+
+func TestEmailChannelSendsOverSMTP(t *testing.T) {
+	// Arrange
+	server := newFakeSMTPServer(t)
+	channel := service.NewEmailChannel(service.NewSMTPMailer(service.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "Loans <loans@example.com>",
+	}), nil)
+	contact := &domain.Contact{ID: "investor1", Role: domain.RecipientInvestor, Name: "Ayu Lestari", Email: "ayu@example.com"}
+
+	// Act
+	err := channel.Send(context.Background(), contact, investedNotification())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error sending notification, got %v", err)
+	}
+	recipients, messages := server.received()
+	if len(messages) != 1 || recipients[0] != "<ayu@example.com>" {
+		t.Fatalf("Expected one message to ayu@example.com, got %d to %v", len(messages), recipients)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(messages[0]))
+	if err != nil {
+		t.Fatalf("Expected a well-formed message, got %v", err)
+	}
+	if subject := message.Header.Get("Subject"); !strings.Contains(subject, "loan_123") {
+		t.Errorf("Expected subject to mention the loan, got %q", subject)
+	}
+	if to := message.Header.Get("To"); !strings.Contains(to, "ayu@example.com") {
+		t.Errorf("Expected To header for ayu@example.com, got %q", to)
+	}
+
+	parts := bodyParts(t, message)
+	for _, contentType := range []string{"text/plain", "text/html"} {
+		body := parts[contentType]
+		for _, want := range []string{"Ayu Lestari", "loan_123", "600.00 IDR", "https://docs.example.com/loan_123/agreement_letter.pdf"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected %s part to contain %q, got %q", contentType, want, body)
+			}
+		}
+	}
+}
+
+func TestEmailChannelUsesOverriddenTemplates(t *testing.T) {
+	// Arrange
+	server := newFakeSMTPServer(t)
+	templates, err := service.LoadNotificationTemplates(fstest.MapFS{
+		"loan_invested.subject.txt": {Data: []byte("Funded: {{.LoanID}}")},
+	})
+	if err != nil {
+		t.Fatalf("Expected templates to load, got %v", err)
+	}
+	channel := service.NewEmailChannel(service.NewSMTPMailer(service.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "loans@example.com",
+	}), templates)
+	contact := &domain.Contact{ID: "investor1", Role: domain.RecipientInvestor, Email: "investor1@example.com"}
+
+	// Act
+	err = channel.Send(context.Background(), contact, investedNotification())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error sending notification, got %v", err)
+	}
+	_, messages := server.received()
+	message, _ := mail.ReadMessage(strings.NewReader(messages[0]))
+	if subject := message.Header.Get("Subject"); subject != "Funded: loan_123" {
+		t.Errorf("Expected overridden subject, got %q", subject)
+	}
+	if body := bodyParts(t, message)["text/plain"]; !strings.Contains(body, "Dear investor1") {
+		t.Errorf("Expected default text body addressed by contact ID, got %q", body)
+	}
+}
+
+func TestNotificationTemplatesRenderEveryEvent(t *testing.T) {
+	templates := service.DefaultNotificationTemplates()
+	contact := &domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Name: "Budi"}
+
+	for _, event := range domain.NotificationEvents {
+		notification := domain.NewNotification(event, domain.RecipientBorrower, "borrower1", "loan_123").WithAmount(mustMoney("110.00"))
+
+		message, err := templates.Render(contact, notification)
+		if err != nil {
+			t.Errorf("Expected %s to render, got %v", event, err)
+			continue
+		}
+		if !strings.Contains(message.Subject, "loan_123") || !strings.Contains(message.Text, "Budi") || !strings.Contains(message.HTML, "loan_123") {
+			t.Errorf("Expected %s message to name the loan and recipient, got %+v", event, message)
+		}
+	}
+}
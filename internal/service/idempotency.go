@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"loan/internal/domain"
+	"time"
+)
+
+// ReserveIdempotencyKey claims record's key for its actor. It returns the
+// record of an earlier request made with the key, or nil when this request
+// is the first.
+func (s *LoanService) ReserveIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	return s.repo.ReserveIdempotencyKey(ctx, record)
+}
+
+// CompleteIdempotencyKey stores the response to replay for a reserved key
+func (s *LoanService) CompleteIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return s.repo.SaveIdempotencyRecord(ctx, record)
+}
+
+// ReleaseIdempotencyKey frees a reserved key whose request failed, so that
+// a retry runs the request again
+func (s *LoanService) ReleaseIdempotencyKey(ctx context.Context, record *domain.IdempotencyRecord) error {
+	return s.repo.DeleteIdempotencyRecord(ctx, record.Actor, record.Key)
+}
+
+// PurgeExpiredIdempotencyKeys deletes the stored responses of keys that have
+// expired, returning how many were deleted
+func (s *LoanService) PurgeExpiredIdempotencyKeys(ctx context.Context) (int, error) {
+	return s.repo.PurgeExpiredIdempotencyRecords(ctx, time.Now())
+}
+
+// RunIdempotencyKeyPurge calls PurgeExpiredIdempotencyKeys every interval
+// until ctx is cancelled, logging failures
+func (s *LoanService) RunIdempotencyKeyPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpiredIdempotencyKeys(ctx); err != nil && ctx.Err() == nil {
+				domain.LoggerFromContext(ctx).Error("idempotency key purge failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"loan/internal/domain"
+	"loan/internal/repository"
+)
+
+// saveLoan saves the loan and appends the events raised since it was loaded
+// to its history, so the two are committed in the same unit of work
+func saveLoan(ctx context.Context, repo repository.LoanRepository, loan *domain.Loan) error {
+	if err := repo.SaveLoan(ctx, loan); err != nil {
+		return err
+	}
+
+	if events := loan.PendingEvents(); len(events) > 0 {
+		if err := repo.AppendLoanEvents(ctx, events...); err != nil {
+			return err
+		}
+	}
+
+	loan.ClearPendingEvents()
+	return nil
+}
+
+// GetLoanEvents returns the loan's history, oldest first
+func (s *LoanService) GetLoanEvents(ctx context.Context, loanID string) ([]*domain.LoanEvent, error) {
+	if _, err := s.repo.GetLoanByID(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLoanEvents(ctx, loanID)
+}
+
+// RebuildLoan reconstructs the loan by replaying its history. The result
+// matches the stored loan apart from its version.
+func (s *LoanService) RebuildLoan(ctx context.Context, loanID string) (*domain.Loan, error) {
+	events, err := s.repo.GetLoanEvents(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.ReplayLoan(events)
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"testing"
+	"time"
+)
+
+// eventTypes lists the loan's recorded history
+func eventTypes(t *testing.T, loanService *service.LoanService, loanID string) []domain.LoanEventType {
+	t.Helper()
+	events, err := loanService.GetLoanEvents(context.Background(), loanID)
+	if err != nil {
+		t.Fatalf("Expected no error getting loan events, got %v", err)
+	}
+
+	types := make([]domain.LoanEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestLoanLifecycleIsRecordedAsEvents(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	terms := domain.ScheduleTerms{Tenor: 1, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithScheduleTerms(terms))
+
+	// Act
+	loan := disbursedLoan(t, loanService, "1000.00", time.Now())
+	if _, err := loanService.RecordRepayment(ctx, loan.ID, mustMoney("1100.00"), time.Now()); err != nil {
+		t.Fatalf("Expected no error recording repayment, got %v", err)
+	}
+
+	// Assert
+	want := []domain.LoanEventType{
+		domain.LoanEventCreated,
+		domain.LoanEventApproved,
+		domain.LoanEventInvestmentAdded,
+		domain.LoanEventInvested,
+		domain.LoanEventDisbursed,
+		domain.LoanEventRepaid,
+	}
+	got := eventTypes(t, loanService, loan.ID)
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i+1, want[i], got[i])
+		}
+	}
+
+	stored, _ := loanService.GetLoan(ctx, loan.ID)
+	rebuilt, err := loanService.RebuildLoan(ctx, loan.ID)
+	if err != nil {
+		t.Fatalf("Expected no error rebuilding loan, got %v", err)
+	}
+	if rebuilt.State != stored.State || rebuilt.Disbursement == nil || rebuilt.Disbursement.FieldOfficerID != stored.Disbursement.FieldOfficerID {
+		t.Errorf("Expected rebuilt loan to match %+v, got %+v", stored, rebuilt)
+	}
+	if len(rebuilt.Investments) != 1 || rebuilt.Investments[0].ID != stored.Investments[0].ID {
+		t.Errorf("Expected rebuilt investments %+v, got %+v", stored.Investments, rebuilt.Investments)
+	}
+}
+
+func TestRebuildCancelledLoanRefundsInvestments(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor123", mustMoney("400.00"))
+	if _, err := loanService.CancelLoan(ctx, loan.ID, "borrower withdrew", "staff123", time.Now()); err != nil {
+		t.Fatalf("Expected no error cancelling loan, got %v", err)
+	}
+
+	// Act
+	rebuilt, err := loanService.RebuildLoan(ctx, loan.ID)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error rebuilding loan, got %v", err)
+	}
+	if rebuilt.State != domain.LoanStateCancelled || rebuilt.Cancellation == nil {
+		t.Errorf("Expected a CANCELLED loan, got %s", rebuilt.State)
+	}
+	if len(rebuilt.Investments) != 1 || rebuilt.Investments[0].Status != domain.InvestmentStatusRefunded {
+		t.Errorf("Expected the investment to be refunded, got %+v", rebuilt.Investments)
+	}
+}
+
+func TestFailedTransitionRecordsNoEvents(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	failingService := service.NewLoanService(&failingSaveLoanRepository{LoanRepository: repo}, service.NewMockNotifier())
+
+	// Act
+	_, _ = failingService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.ApproveLoan(context.Background(), loan.ID, "", "validator123", time.Now())
+
+	// Assert
+	if got := eventTypes(t, loanService, loan.ID); len(got) != 1 || got[0] != domain.LoanEventCreated {
+		t.Errorf("Expected only LOAN_CREATED, got %v", got)
+	}
+}
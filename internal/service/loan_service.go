@@ -6,43 +6,81 @@ import (
 	"fmt"
 	"loan/internal/domain"
 	"loan/internal/repository"
-	"log"
+	"math/rand"
+	"net/http"
 	"time"
 )
 
+// maxConflictRetries bounds how many times a write is re-attempted after
+// losing an optimistic-locking race to a concurrent writer. Between attempts
+// the writer backs off exponentially from conflictBackoffBase, up to
+// conflictBackoffCap.
+const (
+	maxConflictRetries  = 20
+	conflictBackoffBase = time.Millisecond
+	conflictBackoffCap  = time.Second
+)
+
 // LoanService handles the business logic for loan operations
 type LoanService struct {
-	repo         repository.LoanRepository
-	emailService EmailService
+	repo              repository.LoanRepository
+	notifier          Notifier
+	scheduleTerms     domain.ScheduleTerms
+	delinquencyPolicy domain.DelinquencyPolicy
+	agreementLetters  AgreementLetterGenerator
+	retryPolicy       RetryPolicy
+	reminderLeadDays  int
+	webhookClient     *http.Client
 }
 
-func NewLoanService(repo repository.LoanRepository, emailService EmailService) *LoanService {
-	return &LoanService{
-		repo:         repo,
-		emailService: emailService,
+func NewLoanService(repo repository.LoanRepository, notifier Notifier, opts ...Option) *LoanService {
+	s := &LoanService{
+		repo:              repo,
+		notifier:          notifier,
+		scheduleTerms:     DefaultScheduleTerms,
+		delinquencyPolicy: DefaultDelinquencyPolicy,
+		retryPolicy:       DefaultRetryPolicy,
+		reminderLeadDays:  DefaultReminderLeadDays,
+		webhookClient:     &http.Client{Timeout: defaultWebhookTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-func (s *LoanService) CreateLoan(ctx context.Context, borrowerID string, principalAmount, rate, roi float64) (*domain.Loan, error) {
+func (s *LoanService) CreateLoan(ctx context.Context, borrowerID string, principalAmount domain.Money, rate, roi float64) (*domain.Loan, error) {
 	if borrowerID == "" {
-		return nil, errors.New("borrower ID cannot be empty")
+		return nil, domain.ValidationError("borrower ID cannot be empty")
 	}
 
-	if principalAmount <= 0 {
-		return nil, errors.New("principal amount must be greater than zero")
+	if err := authorize(ctx, domain.RoleBorrower, borrowerID); err != nil {
+		return nil, err
+	}
+
+	if !principalAmount.IsPositive() {
+		return nil, domain.ValidationError("principal amount must be greater than zero")
 	}
 
 	if rate < 0 {
-		return nil, errors.New("rate cannot be negative")
+		return nil, domain.ValidationError("rate cannot be negative")
 	}
 
 	if roi < 0 {
-		return nil, errors.New("ROI cannot be negative")
+		return nil, domain.ValidationError("ROI cannot be negative")
 	}
 
 	loan := domain.NewLoan(borrowerID, principalAmount, rate, roi)
 
-	if err := s.repo.SaveLoan(ctx, loan); err != nil {
+	if err := s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		if err := saveLoan(ctx, repo, loan); err != nil {
+			return err
+		}
+
+		return audit(ctx, repo, domain.AuditCreateLoan, loan.ID, nil, loan)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -54,110 +92,610 @@ func (s *LoanService) GetLoan(ctx context.Context, id string) (*domain.Loan, err
 	return s.repo.GetLoanByID(ctx, id)
 }
 
-// ListLoans retrieves loans based on filters and pagination
-func (s *LoanService) ListLoans(ctx context.Context, page, pageSize int) ([]*domain.Loan, int, error) {
-	return s.repo.ListLoans(ctx, page, pageSize)
+// ListLoans retrieves the loans matching filter in the given order, a page
+// at a time
+func (s *LoanService) ListLoans(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, page, pageSize int) ([]*domain.Loan, int, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	return s.repo.ListLoans(ctx, filter, order, page, pageSize)
+}
+
+// ListLoansAfter retrieves up to limit loans matching filter that are listed
+// after the cursor, or from the first when after is nil. It returns the
+// cursor for the next page, which is nil on the last page.
+func (s *LoanService) ListLoansAfter(ctx context.Context, filter domain.LoanFilter, order domain.LoanSort, after *domain.LoanCursor, limit int) ([]*domain.Loan, int, *domain.LoanCursor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, nil, err
+	}
+
+	// One extra loan shows whether there is a next page
+	loans, total, err := s.repo.ListLoansAfter(ctx, filter, order, after, limit+1)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if len(loans) <= limit {
+		return loans, total, nil, nil
+	}
+
+	loans = loans[:limit]
+	return loans, total, domain.NewLoanCursor(order, loans[len(loans)-1]), nil
+}
+
+// SummarizeLoans reports principal and invested totals for each currency of
+// the loans matching the filter
+func (s *LoanService) SummarizeLoans(ctx context.Context, filter domain.LoanFilter) ([]*domain.CurrencyTotal, error) {
+	return s.repo.SummarizeLoans(ctx, filter)
 }
 
-// ApproveLoan changes a loan state from PROPOSED to APPROVED
+// ApproveLoan changes a loan state from PROPOSED to APPROVED and notifies the
+// borrower and staff. The caller must be the approving field validator.
 func (s *LoanService) ApproveLoan(ctx context.Context, loanID, proofPictureURL, fieldValidatorID string, approvalDate time.Time) (*domain.Loan, error) {
-	loan, err := s.repo.GetLoanByID(ctx, loanID)
+	if err := authorize(ctx, domain.RoleFieldValidator, fieldValidatorID); err != nil {
+		return nil, err
+	}
+
+	var loan *domain.Loan
+	err := s.retryOnConflict(ctx, func() error {
+		var err error
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		approval, err := domain.NewApproval(loanID, proofPictureURL, fieldValidatorID, approvalDate)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.Approve(approval); err != nil {
+			return err
+		}
+
+		return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveApproval(ctx, approval); err != nil {
+				return err
+			}
+
+			if err := queueNotifications(ctx, repo,
+				domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientBorrower, loan.BorrowerID, loan.ID).WithAmount(loan.PrincipalAmount),
+				domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientStaff, "", loan.ID).WithAmount(loan.PrincipalAmount),
+			); err != nil {
+				return err
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			if err := queueWebhookEvent(ctx, repo, domain.WebhookLoanApproved, loan, nil); err != nil {
+				return err
+			}
+
+			return audit(ctx, repo, domain.AuditApproveLoan, loan.ID, before, loan)
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	approval, err := domain.NewApproval(loanID, proofPictureURL, fieldValidatorID, approvalDate)
+	return loan, nil
+}
+
+// AddInvestment records an investment against an APPROVED loan. Concurrent
+// investors race on the loan version; the loser re-reads the loan and
+// re-validates, so the principal can never be over-funded. The caller must
+// be the investor.
+func (s *LoanService) AddInvestment(ctx context.Context, loanID, investorID string, amount domain.Money) (*domain.Investment, error) {
+	if err := authorize(ctx, domain.RoleInvestor, investorID); err != nil {
+		return nil, err
+	}
+
+	var (
+		loan       *domain.Loan
+		investment *domain.Investment
+	)
+	err := s.retryOnConflict(ctx, func() error {
+		var err error
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		investment, err = domain.NewInvestment(loanID, investorID, amount)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.AddInvestment(investment); err != nil {
+			return err
+		}
+
+		// The letter is generated before the loan is saved so that the URL is
+		// committed with the INVESTED state and is in place for notifications.
+		// A retried attempt regenerates it under the same name.
+		if loan.State == domain.LoanStateInvested && s.agreementLetters != nil {
+			url, err := s.agreementLetters.GenerateAgreementLetter(ctx, loan)
+			if err != nil {
+				return err
+			}
+			loan.AttachAgreementLetter(url)
+		}
+
+		// The investor is told their investment was received. If the loan has
+		// transitioned to INVESTED state, every investor, the borrower and
+		// staff are told too, with the agreement letter. Notifications go
+		// through the outbox so they are only sent if the investment commits,
+		// and are retried until delivered.
+		notifications := []*domain.Notification{
+			domain.NewNotification(domain.NotificationInvestmentReceived, domain.RecipientInvestor, investorID, loanID).WithAmount(amount),
+		}
+		if loan.State == domain.LoanStateInvested {
+			notifications = append(notifications, investorNotifications(domain.NotificationLoanInvested, loan)...)
+			notifications = append(notifications,
+				domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientBorrower, loan.BorrowerID, loanID).WithAmount(loan.PrincipalAmount),
+				domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientStaff, "", loanID),
+			)
+			for _, notification := range notifications[1:] {
+				notification.AgreementLetterURL = loan.AgreementLetterURL
+			}
+		}
+
+		return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveInvestment(ctx, investment); err != nil {
+				return err
+			}
+
+			if err := queueNotifications(ctx, repo, notifications...); err != nil {
+				return err
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			if err := queueWebhookEvent(ctx, repo, domain.WebhookInvestmentAdded, loan, investment); err != nil {
+				return err
+			}
+			if loan.State == domain.LoanStateInvested {
+				if err := queueWebhookEvent(ctx, repo, domain.WebhookLoanInvested, loan, investment); err != nil {
+					return err
+				}
+			}
+
+			return audit(ctx, repo, domain.AuditAddInvestment, loan.ID, before, loan)
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := loan.Approve(approval); err != nil {
+	return investment, nil
+}
+
+func (s *LoanService) GetLoanInvestments(ctx context.Context, loanID string) ([]*domain.Investment, error) {
+	if _, err := s.repo.GetLoanByID(ctx, loanID); err != nil {
 		return nil, err
 	}
 
-	// use transaction in real implementation with commit and rollback defer function
+	return s.repo.GetLoanInvestments(ctx, loanID)
+}
 
-	if err := s.repo.SaveApproval(ctx, approval); err != nil {
+// DisburseLoan changes a loan state from INVESTED to DISBURSED and generates
+// its repayment schedule, starting from the disbursement date, in the same
+// unit of work. The borrower and investors are notified. The caller must be
+// the disbursing field officer.
+func (s *LoanService) DisburseLoan(ctx context.Context, loanID, agreementDocumentURL, fieldOfficerID string, disbursementDate time.Time) (*domain.Loan, error) {
+	if err := authorize(ctx, domain.RoleFieldOfficer, fieldOfficerID); err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SaveLoan(ctx, loan); err != nil {
+	var loan *domain.Loan
+	err := s.retryOnConflict(ctx, func() error {
+		var err error
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		disbursement, err := domain.NewDisbursement(loanID, agreementDocumentURL, fieldOfficerID, disbursementDate)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.Disburse(disbursement); err != nil {
+			return err
+		}
+
+		schedule, err := domain.NewRepaymentSchedule(loan, s.scheduleTerms, disbursementDate)
+		if err != nil {
+			return err
+		}
+
+		return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveDisbursement(ctx, disbursement); err != nil {
+				return err
+			}
+
+			if err := repo.SaveRepaymentSchedule(ctx, schedule); err != nil {
+				return err
+			}
+
+			borrower := domain.NewNotification(domain.NotificationLoanDisbursed, domain.RecipientBorrower, loan.BorrowerID, loanID).WithAmount(loan.PrincipalAmount)
+			if len(schedule.Installments) > 0 {
+				borrower.DueDate = &schedule.Installments[0].DueDate
+			}
+			notifications := append(investorNotifications(domain.NotificationLoanDisbursed, loan), borrower)
+			if err := queueNotifications(ctx, repo, notifications...); err != nil {
+				return err
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			if err := queueWebhookEvent(ctx, repo, domain.WebhookLoanDisbursed, loan, nil); err != nil {
+				return err
+			}
+
+			return audit(ctx, repo, domain.AuditDisburseLoan, loan.ID, before, loan)
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return loan, nil
 }
 
-func (s *LoanService) AddInvestment(ctx context.Context, loanID, investorID string, amount float64) (*domain.Investment, error) {
-	loan, err := s.repo.GetLoanByID(ctx, loanID)
-	if err != nil {
+// GetRepaymentSchedule returns the repayment schedule generated when the loan
+// was disbursed
+func (s *LoanService) GetRepaymentSchedule(ctx context.Context, loanID string) (*domain.RepaymentSchedule, error) {
+	if _, err := s.repo.GetLoanByID(ctx, loanID); err != nil {
 		return nil, err
 	}
 
-	// use transaction in real implementation with commit and rollback defer function
+	return s.repo.GetRepaymentSchedule(ctx, loanID)
+}
+
+// RecordRepayment records a borrower payment against a DISBURSED loan and
+// credits the investors' share of it to the payout ledger. The loan moves to
+// REPAID when nothing remains outstanding, or to DEFAULTED when it is still
+// past the default threshold after the payment.
+func (s *LoanService) RecordRepayment(ctx context.Context, loanID string, amount domain.Money, paidAt time.Time) (*domain.RepaymentReceipt, error) {
+	var receipt *domain.RepaymentReceipt
+	err := s.retryOnConflict(ctx, func() error {
+		loan, err := s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.CanRecordRepayment(amount); err != nil {
+			return err
+		}
+
+		repayment, err := domain.NewRepayment(loanID, amount, paidAt)
+		if err != nil {
+			return err
+		}
+
+		status, err := s.repaymentStatus(ctx, loanID, repayment)
+		if err != nil {
+			return err
+		}
+
+		paidBefore, err := status.TotalPaid.Sub(repayment.Amount)
+		if err != nil {
+			return err
+		}
+
+		distribution, err := domain.DistributeRepayment(loan, status.TotalDue, paidBefore, repayment)
+		if err != nil {
+			return err
+		}
+
+		loan.SettleRepayments(status, s.delinquencyPolicy)
+
+		// the loan is saved even when its state is unchanged so its version
+		// serialises concurrent repayments and their payouts
+		err = s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveRepayment(ctx, repayment); err != nil {
+				return err
+			}
+
+			for _, payout := range distribution.Payouts {
+				if err := repo.SavePayout(ctx, payout); err != nil {
+					return err
+				}
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			return audit(ctx, repo, domain.AuditRecordRepayment, loan.ID, before, loan)
+		})
+		if err != nil {
+			return err
+		}
 
-	investment, err := domain.NewInvestment(loanID, investorID, amount)
+		receipt = &domain.RepaymentReceipt{
+			Repayment:    repayment,
+			Distribution: distribution,
+			LoanState:    loan.State,
+			Status:       status,
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("investment id", investment.ID)
 
-	// inv_9ea12a9b-85f1-416b-8737-a9ba0c4e1165
+	return receipt, nil
+}
 
-	if err := loan.AddInvestment(investment); err != nil {
+// GetRepaymentStatus reports the loan's repayments against its schedule as
+// of now
+func (s *LoanService) GetRepaymentStatus(ctx context.Context, loanID string) (*domain.RepaymentStatus, error) {
+	if _, err := s.repo.GetLoanByID(ctx, loanID); err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SaveInvestment(ctx, investment); err != nil {
+	return s.repaymentStatus(ctx, loanID)
+}
+
+// GetPayoutLedger returns the payouts credited to the loan's investors
+func (s *LoanService) GetPayoutLedger(ctx context.Context, loanID string) (*domain.PayoutLedger, error) {
+	loan, err := s.repo.GetLoanByID(ctx, loanID)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SaveLoan(ctx, loan); err != nil {
+	payouts, err := s.repo.GetLoanPayouts(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewPayoutLedger(loan, payouts), nil
+}
+
+// AssessDelinquency re-evaluates every DISBURSED loan as of now and moves
+// those past the default threshold to DEFAULTED, returning them
+func (s *LoanService) AssessDelinquency(ctx context.Context) ([]*domain.Loan, error) {
+	loans, _, err := s.repo.ListLoans(ctx, domain.LoanFilter{States: []domain.LoanState{domain.LoanStateDisbursed}}, domain.LoanSort{}, 0, 0)
+	if err != nil {
 		return nil, err
 	}
 
-	// If the loan has transitioned to INVESTED state, send notifications to all investors
-	if loan.State == domain.LoanStateInvested {
-		for _, inv := range loan.Investments {
-			err := s.emailService.SendInvestmentNotification(ctx, inv.InvestorID, loanID, loan.AgreementLetterURL)
+	var defaulted []*domain.Loan
+	for _, candidate := range loans {
+		var (
+			loan    *domain.Loan
+			changed bool
+		)
+		err := s.retryOnConflict(ctx, func() error {
+			var err error
+			loan, err = s.repo.GetLoanByID(ctx, candidate.ID)
 			if err != nil {
-				// Log error but continue processing
-				// In a real implementation, we might use a retry mechanism
-				// or queue for handling notification failures (using NSQ, Kafka, etc.)
-				log.Printf("Failed to send notification to investor %s: %v", inv.InvestorID, err)
+				return err
 			}
+
+			status, err := s.repaymentStatus(ctx, loan.ID)
+			if err != nil {
+				return err
+			}
+
+			changed = loan.SettleRepayments(status, s.delinquencyPolicy)
+			if !changed {
+				return nil
+			}
+
+			return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+				return saveLoan(ctx, repo, loan)
+			})
+		})
+		if err != nil {
+			return defaulted, fmt.Errorf("assess loan %s: %w", candidate.ID, err)
+		}
+
+		if changed && loan.State == domain.LoanStateDefaulted {
+			defaulted = append(defaulted, loan)
 		}
 	}
 
-	return investment, nil
+	return defaulted, nil
 }
 
-func (s *LoanService) GetLoanInvestments(ctx context.Context, loanID string) ([]*domain.Investment, error) {
-	return s.repo.GetLoanInvestments(ctx, loanID)
+// RunDelinquencyChecks calls AssessDelinquency every interval until ctx is
+// cancelled, logging failures
+func (s *LoanService) RunDelinquencyChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			defaulted, err := s.AssessDelinquency(ctx)
+			logger := domain.LoggerFromContext(ctx)
+			if err != nil {
+				logger.Error("delinquency check failed", "error", err)
+			}
+			for _, loan := range defaulted {
+				logger.Warn("loan defaulted", "loan_id", loan.ID)
+			}
+		}
+	}
 }
 
-func (s *LoanService) DisburseLoan(ctx context.Context, loanID, agreementDocumentURL, fieldOfficerID string, disbursementDate time.Time) (*domain.Loan, error) {
-	loan, err := s.repo.GetLoanByID(ctx, loanID)
+// repaymentStatus applies the loan's stored repayments, plus any pending ones
+// not yet saved, to its schedule as of now
+func (s *LoanService) repaymentStatus(ctx context.Context, loanID string, pending ...*domain.Repayment) (*domain.RepaymentStatus, error) {
+	schedule, err := s.repo.GetRepaymentSchedule(ctx, loanID)
 	if err != nil {
 		return nil, err
 	}
 
-	disbursement, err := domain.NewDisbursement(loanID, agreementDocumentURL, fieldOfficerID, disbursementDate)
+	repayments, err := s.repo.GetLoanRepayments(ctx, loanID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := loan.Disburse(disbursement); err != nil {
+	return domain.NewRepaymentStatus(schedule, append(repayments, pending...), s.delinquencyPolicy, time.Now())
+}
+
+// RejectLoan changes a loan state from PROPOSED to REJECTED. The caller must
+// be the reviewing field validator.
+func (s *LoanService) RejectLoan(ctx context.Context, loanID, reason, reviewerID string, rejectedAt time.Time) (*domain.Loan, error) {
+	if err := authorize(ctx, domain.RoleFieldValidator, reviewerID); err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SaveDisbursement(ctx, disbursement); err != nil {
+	var loan *domain.Loan
+	err := s.retryOnConflict(ctx, func() error {
+		var err error
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		rejection, err := domain.NewRejection(loanID, reason, reviewerID, rejectedAt)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.Reject(rejection); err != nil {
+			return err
+		}
+
+		return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveRejection(ctx, rejection); err != nil {
+				return err
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			return audit(ctx, repo, domain.AuditRejectLoan, loan.ID, before, loan)
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SaveLoan(ctx, loan); err != nil {
+	return loan, nil
+}
+
+// CancelLoan changes a PROPOSED or APPROVED loan to CANCELLED, refunding any
+// investments already made in the same unit of work. Borrowers may only
+// cancel their own loans.
+func (s *LoanService) CancelLoan(ctx context.Context, loanID, reason, cancelledBy string, cancelledAt time.Time) (*domain.Loan, error) {
+	if err := authorize(ctx, domain.RoleBorrower, cancelledBy); err != nil {
+		return nil, err
+	}
+
+	var loan *domain.Loan
+	err := s.retryOnConflict(ctx, func() error {
+		var err error
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+		if err != nil {
+			return err
+		}
+
+		if err := authorize(ctx, domain.RoleBorrower, loan.BorrowerID); err != nil {
+			return err
+		}
+
+		before, err := snapshot(loan)
+		if err != nil {
+			return err
+		}
+
+		cancellation, err := domain.NewCancellation(loanID, reason, cancelledBy, cancelledAt)
+		if err != nil {
+			return err
+		}
+
+		if err := loan.Cancel(cancellation); err != nil {
+			return err
+		}
+
+		return s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+			if err := repo.SaveCancellation(ctx, cancellation); err != nil {
+				return err
+			}
+
+			for _, investment := range loan.Investments {
+				if err := repo.SaveInvestment(ctx, investment); err != nil {
+					return err
+				}
+			}
+
+			if err := saveLoan(ctx, repo, loan); err != nil {
+				return err
+			}
+
+			return audit(ctx, repo, domain.AuditCancelLoan, loan.ID, before, loan)
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return loan, nil
 }
+
+// retryOnConflict re-runs fn while it fails with repository.ErrConflict, up to
+// maxConflictRetries attempts, and returns the last conflict without waiting.
+// Before each retry it sleeps a random interval below a ceiling that doubles
+// with every attempt, up to conflictBackoffCap, so that writers who keep
+// colliding spread further apart. fn must re-read any state it writes.
+func (s *LoanService) retryOnConflict(ctx context.Context, fn func() error) error {
+	ceiling := conflictBackoffBase
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !errors.Is(err, repository.ErrConflict) || attempt == maxConflictRetries {
+			return err
+		}
+		domain.LoggerFromContext(ctx).Debug("retrying after a conflicting write", "attempt", attempt)
+
+		backoff := time.Duration(rand.Int63n(int64(ceiling)))
+		if ceiling *= 2; ceiling > conflictBackoffCap {
+			ceiling = conflictBackoffCap
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
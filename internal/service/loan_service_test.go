@@ -2,38 +2,131 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"loan/internal/document"
 	"loan/internal/domain"
 	"loan/internal/repository"
 	"loan/internal/service"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
-// MockEmailService that tracks notifications
-type MockEmailServiceWithTracking struct {
-	NotificationsSent map[string]bool
+// MockNotifierWithTracking records the notifications it is given
+type MockNotifierWithTracking struct {
+	mu   sync.Mutex
+	Sent []*domain.Notification
 }
 
-func NewMockEmailServiceWithTracking() *MockEmailServiceWithTracking {
-	return &MockEmailServiceWithTracking{
-		NotificationsSent: make(map[string]bool),
-	}
+func NewMockNotifierWithTracking() *MockNotifierWithTracking {
+	return &MockNotifierWithTracking{}
+}
+
+func (n *MockNotifierWithTracking) Notify(ctx context.Context, notification *domain.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Sent = append(n.Sent, notification)
+	return nil
 }
 
-func (s *MockEmailServiceWithTracking) SendInvestmentNotification(ctx context.Context, investorID, loanID string, agreementLetterURL string) error {
-	key := investorID + ":" + loanID
-	s.NotificationsSent[key] = true
+// Find returns the notification of event sent to the recipient, or nil
+func (n *MockNotifierWithTracking) Find(event domain.NotificationEvent, role domain.RecipientRole, recipientID string) *domain.Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, notification := range n.Sent {
+		if notification.Event == event && notification.RecipientRole == role && notification.RecipientID == recipientID {
+			return notification
+		}
+	}
 	return nil
 }
 
+// failingSaveLoanRepository fails every SaveLoan call, including those made
+// inside a unit of work, to exercise rollback of the preceding writes
+type failingSaveLoanRepository struct {
+	repository.LoanRepository
+}
+
+func (r *failingSaveLoanRepository) SaveLoan(ctx context.Context, loan *domain.Loan) error {
+	return errors.New("save loan failed")
+}
+
+func (r *failingSaveLoanRepository) WithinTx(ctx context.Context, fn func(repo repository.LoanRepository) error) error {
+	return r.LoanRepository.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		return fn(&failingSaveLoanRepository{LoanRepository: repo})
+	})
+}
+
+// conflictOnceRepository makes the first unit of work lose an optimistic
+// locking race, as if another writer had committed in between
+type conflictOnceRepository struct {
+	repository.LoanRepository
+	conflicted bool
+}
+
+func (r *conflictOnceRepository) WithinTx(ctx context.Context, fn func(repo repository.LoanRepository) error) error {
+	if !r.conflicted {
+		r.conflicted = true
+		return repository.ErrConflict
+	}
+	return r.LoanRepository.WithinTx(ctx, fn)
+}
+
+// failingOutboxLookupRepository fails GetOutboxMessage while failing is set,
+// as a transient storage error would
+type failingOutboxLookupRepository struct {
+	repository.LoanRepository
+	failing *bool
+}
+
+func (r *failingOutboxLookupRepository) GetOutboxMessage(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	if *r.failing {
+		return nil, errors.New("storage unavailable")
+	}
+	return r.LoanRepository.GetOutboxMessage(ctx, id)
+}
+
+func (r *failingOutboxLookupRepository) WithinTx(ctx context.Context, fn func(repo repository.LoanRepository) error) error {
+	return r.LoanRepository.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		return fn(&failingOutboxLookupRepository{LoanRepository: repo, failing: r.failing})
+	})
+}
+
+// mustMoney parses an IDR amount, panicking on bad input
+func mustMoney(amount string) domain.Money {
+	money, err := domain.ParseMoney(amount, "IDR")
+	if err != nil {
+		panic(err)
+	}
+	return money
+}
+
+// disbursedLoan creates a fully funded loan and disburses it on
+// disbursementDate, so its repayment schedule starts from that date
+func disbursedLoan(t *testing.T, loanService *service.LoanService, principal string, disbursementDate time.Time) *domain.Loan {
+	t.Helper()
+	ctx := context.Background()
+
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney(principal), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor123", mustMoney(principal))
+
+	loan, err := loanService.DisburseLoan(ctx, loan.ID, "agreement.pdf", "officer123", disbursementDate)
+	if err != nil {
+		t.Fatalf("Expected no error when disbursing loan, got %v", err)
+	}
+	return loan
+}
+
 func TestCreateLoan(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := service.NewMockEmailService()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Act
-	loan, err := loanService.CreateLoan(context.Background(), "borrower123", 1000.0, 0.1, 0.08)
+	loan, err := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
 
 	// Assert
 	if err != nil {
@@ -60,21 +153,21 @@ func TestCreateLoan(t *testing.T) {
 func TestInvalidLoanCreation(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := service.NewMockEmailService()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Act & Assert
-	_, err := loanService.CreateLoan(context.Background(), "", 1000.0, 0.1, 0.08)
+	_, err := loanService.CreateLoan(context.Background(), "", mustMoney("1000.00"), 0.1, 0.08)
 	if err == nil {
 		t.Error("Expected error for empty borrower ID, got nil")
 	}
 
-	_, err = loanService.CreateLoan(context.Background(), "borrower123", -100, 0.1, 0.08)
+	_, err = loanService.CreateLoan(context.Background(), "borrower123", mustMoney("-100.00"), 0.1, 0.08)
 	if err == nil {
 		t.Error("Expected error for negative principal amount, got nil")
 	}
 
-	_, err = loanService.CreateLoan(context.Background(), "borrower123", 1000.0, -0.1, 0.08)
+	_, err = loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), -0.1, 0.08)
 	if err == nil {
 		t.Error("Expected error for negative rate, got nil")
 	}
@@ -83,11 +176,11 @@ func TestInvalidLoanCreation(t *testing.T) {
 func TestApproveLoan(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := service.NewMockEmailService()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Create a loan
-	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", 1000.0, 0.1, 0.08)
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
 
 	// Act
 	approvalDate := time.Now()
@@ -124,11 +217,11 @@ func TestApproveLoan(t *testing.T) {
 func TestAddInvestment(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := NewMockEmailServiceWithTracking()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := NewMockNotifierWithTracking()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Create and approve a loan
-	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", 1000.0, 0.1, 0.08)
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
 	_, _ = loanService.ApproveLoan(
 		context.Background(),
 		loan.ID,
@@ -142,7 +235,7 @@ func TestAddInvestment(t *testing.T) {
 		context.Background(),
 		loan.ID,
 		"investor123",
-		600.0,
+		mustMoney("600.00"),
 	)
 
 	// Assert
@@ -158,8 +251,8 @@ func TestAddInvestment(t *testing.T) {
 		t.Errorf("Expected investor ID to be investor123, got %s", investment.InvestorID)
 	}
 
-	if investment.Amount != 600.0 {
-		t.Errorf("Expected investment amount to be 600.0, got %f", investment.Amount)
+	if investment.Amount != mustMoney("600.00") {
+		t.Errorf("Expected investment amount to be 600.00, got %s", investment.Amount)
 	}
 
 	// Verify loan state (should still be APPROVED after partial investment)
@@ -173,7 +266,7 @@ func TestAddInvestment(t *testing.T) {
 		context.Background(),
 		loan.ID,
 		"investor456",
-		400.0,
+		mustMoney("400.00"),
 	)
 	if err != nil {
 		t.Fatalf("Expected no error when adding second investment, got %v", err)
@@ -185,23 +278,38 @@ func TestAddInvestment(t *testing.T) {
 		t.Errorf("Expected loan state to be INVESTED after full investment, got %s", updatedLoan.State)
 	}
 
-	// Verify notifications were sent to both investors
-	if !emailService.NotificationsSent["investor123:"+loan.ID] {
+	// Verify notifications were queued, then sent to both investors on dispatch
+	if len(notifier.Sent) != 0 {
+		t.Error("Expected notifications to wait in the outbox until dispatched")
+	}
+	if _, err := loanService.DispatchOutbox(context.Background()); err != nil {
+		t.Fatalf("Expected notifications to be delivered, got %v", err)
+	}
+	if received := notifier.Find(domain.NotificationInvestmentReceived, domain.RecipientInvestor, "investor123"); received == nil || *received.Amount != mustMoney("600.00") {
+		t.Errorf("Expected first investor to be told their investment of 600.00 was received, got %+v", received)
+	}
+	if notifier.Find(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor123") == nil {
 		t.Error("Expected notification to be sent to first investor")
 	}
-	if !emailService.NotificationsSent["investor456:"+loan.ID] {
+	if notifier.Find(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor456") == nil {
 		t.Error("Expected notification to be sent to second investor")
 	}
+	if notifier.Find(domain.NotificationLoanInvested, domain.RecipientBorrower, "borrower123") == nil {
+		t.Error("Expected notification to be sent to the borrower")
+	}
+	if notifier.Find(domain.NotificationLoanInvested, domain.RecipientStaff, "") == nil {
+		t.Error("Expected notification to be sent to staff")
+	}
 }
 
 func TestDisburseLoan(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := service.NewMockEmailService()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Create, approve, and invest in a loan
-	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", 1000.0, 0.1, 0.08)
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
 	_, _ = loanService.ApproveLoan(
 		context.Background(),
 		loan.ID,
@@ -213,7 +321,7 @@ func TestDisburseLoan(t *testing.T) {
 		context.Background(),
 		loan.ID,
 		"investor123",
-		1000.0,
+		mustMoney("1000.00"),
 	)
 
 	// Act
@@ -246,16 +354,86 @@ func TestDisburseLoan(t *testing.T) {
 	if updatedLoan.Disbursement.FieldOfficerID != "officer123" {
 		t.Errorf("Expected field officer ID to be officer123, got %s", updatedLoan.Disbursement.FieldOfficerID)
 	}
+
+	schedule, err := loanService.GetRepaymentSchedule(context.Background(), loan.ID)
+	if err != nil {
+		t.Fatalf("Expected repayment schedule after disbursement, got %v", err)
+	}
+
+	if len(schedule.Installments) != service.DefaultScheduleTerms.Tenor {
+		t.Errorf("Expected %d installments, got %d", service.DefaultScheduleTerms.Tenor, len(schedule.Installments))
+	}
+
+	if schedule.TotalAmount.Decimal() != "1100.00" {
+		t.Errorf("Expected schedule total to be 1100.00, got %s", schedule.TotalAmount.Decimal())
+	}
+}
+
+func TestDisburseLoanUsesConfiguredScheduleTerms(t *testing.T) {
+	// Arrange
+	terms := domain.ScheduleTerms{Tenor: 4, Frequency: domain.RepaymentFrequencyWeekly, Method: domain.InterestMethodAnnuity}
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithScheduleTerms(terms))
+
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor123", mustMoney("1000.00"))
+
+	if _, err := loanService.GetRepaymentSchedule(context.Background(), loan.ID); err == nil {
+		t.Error("Expected no repayment schedule before disbursement")
+	}
+
+	// Act
+	_, err := loanService.DisburseLoan(context.Background(), loan.ID, "agreement.pdf", "officer123", time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error when disbursing loan, got %v", err)
+	}
+
+	schedule, err := loanService.GetRepaymentSchedule(context.Background(), loan.ID)
+	if err != nil {
+		t.Fatalf("Expected repayment schedule after disbursement, got %v", err)
+	}
+
+	if schedule.Terms != terms {
+		t.Errorf("Expected schedule terms %+v, got %+v", terms, schedule.Terms)
+	}
+}
+
+func TestListLoansAfterReturnsNextCursor(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	for i := 0; i < 3; i++ {
+		if _, err := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08); err != nil {
+			t.Fatalf("Failed to create loan: %v", err)
+		}
+	}
+
+	// Act
+	first, total, next, err := loanService.ListLoansAfter(context.Background(), domain.LoanFilter{}, domain.LoanSort{}, nil, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	last, _, end, _ := loanService.ListLoansAfter(context.Background(), domain.LoanFilter{}, domain.LoanSort{}, next, 2)
+
+	// Assert
+	if len(first) != 2 || total != 3 || next == nil || next.ID != first[1].ID {
+		t.Errorf("Expected 2 of 3 loans and a cursor after the second, got %d of %d (cursor %+v)", len(first), total, next)
+	}
+	if len(last) != 1 || end != nil {
+		t.Errorf("Expected the last loan and no further cursor, got %d loans (cursor %+v)", len(last), end)
+	}
 }
 
 func TestGetLoanInvestments(t *testing.T) {
 	// Arrange
 	repo := repository.NewMockLoanRepository()
-	emailService := service.NewMockEmailService()
-	loanService := service.NewLoanService(repo, emailService)
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
 
 	// Create and approve a loan
-	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", 1000.0, 0.1, 0.08)
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
 	_, _ = loanService.ApproveLoan(
 		context.Background(),
 		loan.ID,
@@ -265,8 +443,8 @@ func TestGetLoanInvestments(t *testing.T) {
 	)
 
 	// Add investments
-	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor1", 400.0)
-	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor2", 600.0)
+	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor1", mustMoney("400.00"))
+	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor2", mustMoney("600.00"))
 
 	// Act
 	investments, err := loanService.GetLoanInvestments(context.Background(), loan.ID)
@@ -281,12 +459,598 @@ func TestGetLoanInvestments(t *testing.T) {
 	}
 
 	// Verify total investment amount
-	var totalAmount float64
+	totalAmount := domain.ZeroMoney("IDR")
 	for _, inv := range investments {
-		totalAmount += inv.Amount
+		totalAmount, _ = totalAmount.Add(inv.Amount)
+	}
+
+	if totalAmount != mustMoney("1000.00") {
+		t.Errorf("Expected total investment amount to be 1000.00, got %s", totalAmount)
+	}
+}
+
+func TestFailuresMatchTheirErrorKind(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	notifier := service.NewMockNotifier()
+	loanService := service.NewLoanService(repo, notifier)
+	ctx := context.Background()
+
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	borrower := domain.ContextWithPrincipal(ctx, &domain.Principal{Subject: "borrower123", Roles: []domain.Role{domain.RoleBorrower}})
+
+	tests := map[string]struct {
+		call func() error
+		want error
+	}{
+		"unknown loan": {func() error {
+			_, err := loanService.GetLoanInvestments(ctx, "missing")
+			return err
+		}, domain.ErrNotFound},
+		"unknown loan history": {func() error {
+			_, err := loanService.GetLoanEvents(ctx, "missing")
+			return err
+		}, domain.ErrNotFound},
+		"empty borrower": {func() error {
+			_, err := loanService.CreateLoan(ctx, "", mustMoney("1000.00"), 0.1, 0.08)
+			return err
+		}, domain.ErrValidation},
+		"investing before approval": {func() error {
+			_, err := loanService.AddInvestment(ctx, loan.ID, "investor123", mustMoney("100.00"))
+			return err
+		}, domain.ErrInvalidState},
+		"approving as a borrower": {func() error {
+			_, err := loanService.ApproveLoan(borrower, loan.ID, "proof.jpg", "validator123", time.Now())
+			return err
+		}, domain.ErrForbidden},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			err := tt.call()
+
+			// Assert
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected an error matching %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApproveLoanRollsBackOnFailure(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+
+	failingService := service.NewLoanService(&failingSaveLoanRepository{LoanRepository: repo}, service.NewMockNotifier())
+
+	// Act
+	_, err := failingService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+
+	// Assert
+	if err == nil {
+		t.Fatal("Expected error when saving the loan fails, got nil")
+	}
+
+	savedLoan, _ := loanService.GetLoan(context.Background(), loan.ID)
+	if savedLoan.State != domain.LoanStateProposed {
+		t.Errorf("Expected loan state to remain PROPOSED, got %s", savedLoan.State)
+	}
+	if savedLoan.Approval != nil {
+		t.Error("Expected approval to be rolled back, got one")
+	}
+}
+
+func TestAddInvestmentRetriesOnConflict(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+
+	conflictingService := service.NewLoanService(&conflictOnceRepository{LoanRepository: repo}, service.NewMockNotifier())
+
+	// Act
+	_, err := conflictingService.AddInvestment(context.Background(), loan.ID, "investor123", mustMoney("600.00"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected investment to succeed after retry, got %v", err)
+	}
+
+	investments, _ := loanService.GetLoanInvestments(context.Background(), loan.ID)
+	if len(investments) != 1 {
+		t.Errorf("Expected exactly 1 investment after retry, got %d", len(investments))
+	}
+}
+
+func TestAddInvestmentConcurrent(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, NewMockNotifierWithTracking())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+
+	const investors = 300
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		invested  = domain.ZeroMoney("IDR")
+		failures  []error
+	)
+
+	// Act: every investor offers 10, so only 100 of them can be accepted
+	start := make(chan struct{})
+	for i := 0; i < investors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			investment, err := loanService.AddInvestment(context.Background(), loan.ID, "investor", mustMoney("10.00"))
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+				invested, _ = invested.Add(investment.Amount)
+			case !errors.Is(err, domain.ErrInvalidState) && !errors.Is(err, domain.ErrValidation):
+				failures = append(failures, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	if len(failures) > 0 {
+		t.Fatalf("Expected late investors to be told the loan is funded, got %d other errors, e.g. %v", len(failures), failures[0])
+	}
+	if succeeded != 100 {
+		t.Errorf("Expected exactly 100 of %d investors to succeed, got %d", investors, succeeded)
+	}
+
+	savedLoan, _ := loanService.GetLoan(context.Background(), loan.ID)
+	if savedLoan.State != domain.LoanStateInvested {
+		t.Errorf("Expected the loan to be INVESTED, got %s", savedLoan.State)
+	}
+	if total := savedLoan.TotalInvestedAmount(); total != savedLoan.PrincipalAmount || total != invested {
+		t.Errorf("Expected %s invested, matching the accepted investments %s, got %s", savedLoan.PrincipalAmount, invested, total)
+	}
+
+	investments, _ := loanService.GetLoanInvestments(context.Background(), loan.ID)
+	if len(investments) != 100 {
+		t.Errorf("Expected 100 stored investments, got %d", len(investments))
+	}
+}
+
+func TestRejectLoan(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+
+	// Act
+	rejectedLoan, err := loanService.RejectLoan(context.Background(), loan.ID, "incomplete documents", "reviewer123", time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error when rejecting loan, got %v", err)
+	}
+	if rejectedLoan.State != domain.LoanStateRejected {
+		t.Errorf("Expected loan state to be REJECTED, got %s", rejectedLoan.State)
+	}
+	if rejectedLoan.Rejection == nil || rejectedLoan.Rejection.ReviewerID != "reviewer123" {
+		t.Errorf("Expected rejection by reviewer123, got %+v", rejectedLoan.Rejection)
+	}
+
+	// A rejected loan can no longer be approved
+	_, err = loanService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+	if err == nil {
+		t.Error("Expected error approving a rejected loan, got nil")
+	}
+}
+
+func TestCancelLoanRefundsInvestments(t *testing.T) {
+	// Arrange
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(repo, service.NewMockNotifier())
+	loan, _ := loanService.CreateLoan(context.Background(), "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(context.Background(), loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(context.Background(), loan.ID, "investor1", mustMoney("400.00"))
+
+	// Act
+	cancelledLoan, err := loanService.CancelLoan(context.Background(), loan.ID, "borrower withdrew", "staff123", time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error when cancelling loan, got %v", err)
+	}
+	if cancelledLoan.State != domain.LoanStateCancelled {
+		t.Errorf("Expected loan state to be CANCELLED, got %s", cancelledLoan.State)
+	}
+
+	investments, _ := loanService.GetLoanInvestments(context.Background(), loan.ID)
+	if len(investments) != 1 || investments[0].Status != domain.InvestmentStatusRefunded {
+		t.Errorf("Expected the investment to be refunded, got %+v", investments)
+	}
+
+	savedLoan, _ := loanService.GetLoan(context.Background(), loan.ID)
+	if !savedLoan.TotalInvestedAmount().IsZero() {
+		t.Errorf("Expected no active investment after cancellation, got %s", savedLoan.TotalInvestedAmount())
+	}
+
+	// A cancelled loan accepts no further investments
+	_, err = loanService.AddInvestment(context.Background(), loan.ID, "investor2", mustMoney("100.00"))
+	if err == nil {
+		t.Error("Expected error investing in a cancelled loan, got nil")
+	}
+}
+
+func TestRecordRepaymentRepaysLoan(t *testing.T) {
+	// Arrange
+	terms := domain.ScheduleTerms{Tenor: 2, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithScheduleTerms(terms))
+	loan := disbursedLoan(t, loanService, "1000.00", time.Now())
+
+	// Act
+	first, err := loanService.RecordRepayment(context.Background(), loan.ID, mustMoney("600.00"), time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error recording repayment, got %v", err)
+	}
+	second, err := loanService.RecordRepayment(context.Background(), loan.ID, mustMoney("550.00"), time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error recording repayment, got %v", err)
+	}
+
+	// Assert
+	if first.LoanState != domain.LoanStateDisbursed || first.Status.Outstanding.Decimal() != "500.00" {
+		t.Errorf("Expected DISBURSED with 500.00 outstanding, got %s with %s", first.LoanState, first.Status.Outstanding.Decimal())
+	}
+
+	if second.LoanState != domain.LoanStateRepaid {
+		t.Errorf("Expected loan state to be REPAID, got %s", second.LoanState)
+	}
+	if second.Status.Overpaid.Decimal() != "50.00" {
+		t.Errorf("Expected 50.00 overpaid, got %s", second.Status.Overpaid.Decimal())
+	}
+
+	if _, err := loanService.RecordRepayment(context.Background(), loan.ID, mustMoney("1.00"), time.Now()); err == nil {
+		t.Error("Expected error recording repayment on a REPAID loan, got nil")
+	}
+}
+
+func TestRecordRepaymentDefaultsOverdueLoan(t *testing.T) {
+	// Arrange
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier())
+	loan := disbursedLoan(t, loanService, "1000.00", time.Now().AddDate(0, -6, 0))
+
+	// Act
+	receipt, err := loanService.RecordRepayment(context.Background(), loan.ID, mustMoney("10.00"), time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error recording repayment, got %v", err)
+	}
+
+	if receipt.LoanState != domain.LoanStateDefaulted {
+		t.Errorf("Expected loan state to be DEFAULTED, got %s", receipt.LoanState)
+	}
+	if receipt.Status.DaysPastDue < service.DefaultDelinquencyPolicy.DefaultAfterDays {
+		t.Errorf("Expected at least %d days past due, got %d", service.DefaultDelinquencyPolicy.DefaultAfterDays, receipt.Status.DaysPastDue)
+	}
+}
+
+func TestAssessDelinquency(t *testing.T) {
+	// Arrange
+	policy := domain.DelinquencyPolicy{GraceDays: 0, DefaultAfterDays: 30}
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithDelinquencyPolicy(policy))
+	overdue := disbursedLoan(t, loanService, "1000.00", time.Now().AddDate(0, -3, 0))
+	current := disbursedLoan(t, loanService, "1000.00", time.Now())
+
+	// Act
+	defaulted, err := loanService.AssessDelinquency(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error assessing delinquency, got %v", err)
+	}
+
+	if len(defaulted) != 1 || defaulted[0].ID != overdue.ID {
+		t.Fatalf("Expected only the overdue loan to default, got %v", defaulted)
+	}
+
+	stored, _ := loanService.GetLoan(context.Background(), current.ID)
+	if stored.State != domain.LoanStateDisbursed {
+		t.Errorf("Expected current loan to stay DISBURSED, got %s", stored.State)
+	}
+}
+
+func TestRecordRepaymentCreditsPayouts(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	terms := domain.ScheduleTerms{Tenor: 1, Frequency: domain.RepaymentFrequencyMonthly, Method: domain.InterestMethodFlat}
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), service.NewMockNotifier(),
+		service.WithScheduleTerms(terms))
+
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor1", mustMoney("750.00"))
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor2", mustMoney("250.00"))
+	_, _ = loanService.DisburseLoan(ctx, loan.ID, "agreement.pdf", "officer123", time.Now())
+
+	// Act
+	receipt, err := loanService.RecordRepayment(ctx, loan.ID, mustMoney("1100.00"), time.Now())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error recording repayment, got %v", err)
+	}
+
+	if receipt.Distribution.InvestorShare.Decimal() != "1080.00" || receipt.Distribution.PlatformShare.Decimal() != "20.00" {
+		t.Errorf("Expected 1080.00 to investors and 20.00 to platform, got %s and %s",
+			receipt.Distribution.InvestorShare.Decimal(), receipt.Distribution.PlatformShare.Decimal())
+	}
+
+	ledger, err := loanService.GetPayoutLedger(ctx, loan.ID)
+	if err != nil {
+		t.Fatalf("Expected no error getting payout ledger, got %v", err)
+	}
+
+	if len(ledger.Entries) != 2 || ledger.TotalPaidOut.Decimal() != "1080.00" {
+		t.Fatalf("Expected 2 entries totalling 1080.00, got %d totalling %s", len(ledger.Entries), ledger.TotalPaidOut.Decimal())
+	}
+
+	want := map[string]string{"investor1": "810.00", "investor2": "270.00"}
+	for _, total := range ledger.InvestorTotals {
+		if total.Total.Decimal() != want[total.InvestorID] {
+			t.Errorf("Expected %s for %s, got %s", want[total.InvestorID], total.InvestorID, total.Total.Decimal())
+		}
+	}
+}
+
+func TestAddInvestmentGeneratesAgreementLetter(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := document.NewMemoryStore()
+	notifier := NewMockNotifierWithTracking()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), notifier,
+		service.WithAgreementLetterGenerator(service.NewAgreementLetterGenerator(store, "https://loans.example.com/")))
+
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+	_, _ = loanService.AddInvestment(ctx, loan.ID, "investor1", mustMoney("600.00"))
+
+	partial, _ := loanService.GetLoan(ctx, loan.ID)
+	if partial.AgreementLetterURL != "" {
+		t.Errorf("Expected no agreement letter before the loan is fully invested, got %s", partial.AgreementLetterURL)
+	}
+
+	// Act
+	_, err := loanService.AddInvestment(ctx, loan.ID, "investor2", mustMoney("400.00"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error when adding investment, got %v", err)
+	}
+
+	wantURL := "https://loans.example.com/api/v1/loans/" + loan.ID + "/agreement-letter"
+	invested, _ := loanService.GetLoan(ctx, loan.ID)
+	if invested.AgreementLetterURL != wantURL {
+		t.Errorf("Expected agreement letter URL %s, got %s", wantURL, invested.AgreementLetterURL)
+	}
+
+	_, _ = loanService.DispatchOutbox(ctx)
+	for _, investorID := range []string{"investor1", "investor2"} {
+		notification := notifier.Find(domain.NotificationLoanInvested, domain.RecipientInvestor, investorID)
+		if notification == nil || notification.AgreementLetterURL != wantURL {
+			t.Errorf("Expected %s to be sent %s, got %+v", investorID, wantURL, notification)
+		}
+	}
+
+	html, err := store.Get(ctx, loan.ID+"/agreement_letter.html")
+	if err != nil || !strings.Contains(string(html), "investor2") || !strings.Contains(string(html), "1000.00 IDR") {
+		t.Errorf("Expected HTML letter listing the investors and principal, got %s", html)
+	}
+
+	pdf, err := loanService.GetAgreementLetter(ctx, loan.ID)
+	if err != nil || !strings.HasPrefix(string(pdf), "%PDF-") {
+		t.Errorf("Expected the PDF letter to be served, got %v", err)
+	}
+}
+
+// flakyNotifier fails the next `failures` notifications, then delegates to
+// the tracking mock
+type flakyNotifier struct {
+	*MockNotifierWithTracking
+	failures int
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, notification *domain.Notification) error {
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("smtp unavailable")
+	}
+	return n.MockNotifierWithTracking.Notify(ctx, notification)
+}
+
+// queueNotification puts a single notification in the outbox
+func queueNotification(t *testing.T, repo repository.LoanRepository) *domain.OutboxMessage {
+	t.Helper()
+	notification := domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientBorrower, "borrower123", "loan1")
+	message, err := domain.NewOutboxMessage(domain.TopicNotification, notification)
+	if err != nil {
+		t.Fatalf("Expected outbox message, got %v", err)
+	}
+	if err := repo.SaveOutboxMessage(context.Background(), message); err != nil {
+		t.Fatalf("Expected no error queueing notification, got %v", err)
+	}
+	return message
+}
+
+func TestDispatchOutboxRetriesThenDeadLetters(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := repository.NewMockLoanRepository()
+	notifier := &flakyNotifier{MockNotifierWithTracking: NewMockNotifierWithTracking(), failures: 2}
+	loanService := service.NewLoanService(repo, notifier,
+		service.WithRetryPolicy(service.RetryPolicy{MaxAttempts: 2, BaseDelay: 0, MaxDelay: 0}))
+	queueNotification(t, repo)
+
+	// Act
+	first, err1 := loanService.DispatchOutbox(ctx)
+	second, err2 := loanService.DispatchOutbox(ctx)
+
+	// Assert
+	if err1 != nil || err2 != nil || first != 0 || second != 0 {
+		t.Fatalf("Expected both attempts to fail without error, got %d (%v) and %d (%v)", first, err1, second, err2)
+	}
+
+	dead, total, _ := loanService.ListOutboxMessages(ctx, domain.OutboxStatusDead, 1, 10)
+	if total != 1 || dead[0].Attempts != 2 || dead[0].LastError != "smtp unavailable" {
+		t.Fatalf("Expected one DEAD message after 2 attempts, got %+v", dead)
+	}
+
+	if delivered, _ := loanService.DispatchOutbox(ctx); delivered != 0 {
+		t.Errorf("Expected DEAD message not to be redelivered, got %d deliveries", delivered)
+	}
+
+	replayed, err := loanService.ReplayOutboxMessage(ctx, dead[0].ID)
+	if err != nil || replayed.Status != domain.OutboxStatusPending || replayed.Attempts != 0 {
+		t.Fatalf("Expected message to be requeued, got %+v (%v)", replayed, err)
+	}
+
+	if delivered, _ := loanService.DispatchOutbox(ctx); delivered != 1 {
+		t.Errorf("Expected replayed message to be delivered, got %d deliveries", delivered)
+	}
+	if notifier.Find(domain.NotificationLoanApproved, domain.RecipientBorrower, "borrower123") == nil {
+		t.Error("Expected notification to be sent to the borrower after replay")
+	}
+
+	if _, err := loanService.ReplayOutboxMessage(ctx, dead[0].ID); err == nil {
+		t.Error("Expected error replaying a delivered message, got nil")
+	}
+}
+
+func TestDispatchOutboxBacksOffAfterFailure(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repo := repository.NewMockLoanRepository()
+	notifier := &flakyNotifier{MockNotifierWithTracking: NewMockNotifierWithTracking(), failures: 1}
+	loanService := service.NewLoanService(repo, notifier,
+		service.WithRetryPolicy(service.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	queueNotification(t, repo)
+
+	// Act
+	_, _ = loanService.DispatchOutbox(ctx)
+	delivered, err := loanService.DispatchOutbox(ctx)
+
+	// Assert
+	if err != nil || delivered != 0 {
+		t.Fatalf("Expected failed message to wait out its backoff, got %d deliveries (%v)", delivered, err)
+	}
+
+	pending, _, _ := loanService.ListOutboxMessages(ctx, domain.OutboxStatusPending, 1, 10)
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].NextAttemptAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("Expected one PENDING message retried in an hour, got %+v", pending)
+	}
+}
+
+func TestLifecycleNotifications(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	notifier := NewMockNotifierWithTracking()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), notifier)
+
+	// Act
+	loan := disbursedLoan(t, loanService, "1000.00", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+	if _, err := loanService.DispatchOutbox(ctx); err != nil {
+		t.Fatalf("Expected notifications to be delivered, got %v", err)
+	}
+
+	// Assert
+	for _, want := range []struct {
+		event       domain.NotificationEvent
+		role        domain.RecipientRole
+		recipientID string
+	}{
+		{domain.NotificationLoanApproved, domain.RecipientBorrower, "borrower123"},
+		{domain.NotificationLoanApproved, domain.RecipientStaff, ""},
+		{domain.NotificationInvestmentReceived, domain.RecipientInvestor, "investor123"},
+		{domain.NotificationLoanInvested, domain.RecipientInvestor, "investor123"},
+		{domain.NotificationLoanDisbursed, domain.RecipientBorrower, "borrower123"},
+		{domain.NotificationLoanDisbursed, domain.RecipientInvestor, "investor123"},
+	} {
+		notification := notifier.Find(want.event, want.role, want.recipientID)
+		if notification == nil {
+			t.Errorf("Expected %s notification to %s %s", want.event, want.role, want.recipientID)
+			continue
+		}
+		if notification.LoanID != loan.ID {
+			t.Errorf("Expected %s notification for loan %s, got %s", want.event, loan.ID, notification.LoanID)
+		}
+	}
+
+	disbursed := notifier.Find(domain.NotificationLoanDisbursed, domain.RecipientBorrower, "borrower123")
+	if disbursed.DueDate == nil || !disbursed.DueDate.Equal(time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected borrower to be told the first due date 2026-02-15, got %v", disbursed.DueDate)
+	}
+}
+
+func TestSendRepaymentRemindersStopsWhenTheOutboxCannotBeRead(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	failing := false
+	repo := repository.NewMockLoanRepository()
+	loanService := service.NewLoanService(&failingOutboxLookupRepository{LoanRepository: repo, failing: &failing}, NewMockNotifierWithTracking(), service.WithReminderLeadDays(3))
+	loan := disbursedLoan(t, loanService, "1200.00", time.Now().AddDate(0, -1, 2))
+	_, _ = loanService.SendRepaymentReminders(ctx)
+	_, _ = loanService.DispatchOutbox(ctx)
+
+	// Act
+	failing = true
+	queued, err := loanService.SendRepaymentReminders(ctx)
+
+	// Assert
+	if err == nil || queued != 0 {
+		t.Errorf("Expected the lookup failure to be returned, got %d reminders (%v)", queued, err)
+	}
+	reminder, _ := repo.GetOutboxMessage(ctx, "msg_due_"+loan.ID+"_1")
+	if reminder == nil || reminder.Status != domain.OutboxStatusDelivered {
+		t.Errorf("Expected the delivered reminder to be left alone, got %+v", reminder)
+	}
+}
+
+func TestSendRepaymentReminders(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	notifier := NewMockNotifierWithTracking()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), notifier, service.WithReminderLeadDays(3))
+	// the first installment falls due in two days
+	loan := disbursedLoan(t, loanService, "1200.00", time.Now().AddDate(0, -1, 2))
+	_, _ = loanService.DispatchOutbox(ctx)
+
+	// Act
+	queued, err := loanService.SendRepaymentReminders(ctx)
+
+	// Assert
+	if err != nil || queued != 1 {
+		t.Fatalf("Expected 1 reminder to be queued, got %d (%v)", queued, err)
+	}
+
+	if again, _ := loanService.SendRepaymentReminders(ctx); again != 0 {
+		t.Errorf("Expected installment not to be reminded twice, got %d reminders", again)
 	}
 
-	if totalAmount != 1000.0 {
-		t.Errorf("Expected total investment amount to be 1000.0, got %f", totalAmount)
+	_, _ = loanService.DispatchOutbox(ctx)
+	reminder := notifier.Find(domain.NotificationRepaymentDue, domain.RecipientBorrower, "borrower123")
+	if reminder == nil || reminder.LoanID != loan.ID || reminder.InstallmentNumber != 1 || *reminder.Amount != mustMoney("110.00") {
+		t.Errorf("Expected reminder for installment 1 of 110.00, got %+v", reminder)
 	}
 }
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"loan/internal/domain"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/notifications
+var notificationTemplateFiles embed.FS
+
+// NotificationTemplates renders each event as a subject line, plain-text
+// body and HTML body. The files for event LOAN_APPROVED are
+// loan_approved.subject.txt, loan_approved.txt and loan_approved.html.
+type NotificationTemplates struct {
+	subject map[domain.NotificationEvent]*texttemplate.Template
+	text    map[domain.NotificationEvent]*texttemplate.Template
+	html    map[domain.NotificationEvent]*htmltemplate.Template
+}
+
+// DefaultNotificationTemplates returns the templates embedded from
+// internal/service/templates/notifications
+func DefaultNotificationTemplates() *NotificationTemplates {
+	templates, err := LoadNotificationTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+// LoadNotificationTemplates parses templates from fsys, falling back to the
+// embedded default for any file fsys does not contain. A nil fsys loads the
+// defaults.
+func LoadNotificationTemplates(fsys fs.FS) (*NotificationTemplates, error) {
+	read := func(name string) (string, error) {
+		if fsys != nil {
+			data, err := fs.ReadFile(fsys, name)
+			if err == nil {
+				return string(data), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		data, err := notificationTemplateFiles.ReadFile("templates/notifications/" + name)
+		return string(data), err
+	}
+
+	templates := &NotificationTemplates{
+		subject: make(map[domain.NotificationEvent]*texttemplate.Template),
+		text:    make(map[domain.NotificationEvent]*texttemplate.Template),
+		html:    make(map[domain.NotificationEvent]*htmltemplate.Template),
+	}
+
+	for _, event := range domain.NotificationEvents {
+		base := strings.ToLower(string(event))
+
+		subject, err := read(base + ".subject.txt")
+		if err != nil {
+			return nil, err
+		}
+		if templates.subject[event], err = texttemplate.New(base + ".subject.txt").Parse(subject); err != nil {
+			return nil, err
+		}
+
+		text, err := read(base + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		if templates.text[event], err = texttemplate.New(base + ".txt").Parse(text); err != nil {
+			return nil, err
+		}
+
+		html, err := read(base + ".html")
+		if err != nil {
+			return nil, err
+		}
+		if templates.html[event], err = htmltemplate.New(base + ".html").Parse(html); err != nil {
+			return nil, err
+		}
+	}
+
+	return templates, nil
+}
+
+// notificationData is what the notification templates can refer to
+type notificationData struct {
+	Event              string
+	Role               string
+	RecipientID        string
+	RecipientName      string
+	LoanID             string
+	Amount             string
+	AgreementLetterURL string
+	InstallmentNumber  int
+	DueDate            string
+}
+
+func newNotificationData(contact *domain.Contact, notification *domain.Notification) notificationData {
+	data := notificationData{
+		Event:              string(notification.Event),
+		Role:               string(contact.Role),
+		RecipientID:        contact.ID,
+		RecipientName:      contact.Name,
+		LoanID:             notification.LoanID,
+		AgreementLetterURL: notification.AgreementLetterURL,
+		InstallmentNumber:  notification.InstallmentNumber,
+	}
+	if data.RecipientName == "" {
+		data.RecipientName = contact.ID
+	}
+	if notification.Amount != nil {
+		data.Amount = notification.Amount.String()
+	}
+	if notification.DueDate != nil {
+		data.DueDate = notification.DueDate.Format("2006-01-02")
+	}
+	return data
+}
+
+// EmailMessage is a rendered notification
+type EmailMessage struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Subject renders just the subject line, e.g. for SMS
+func (t *NotificationTemplates) Subject(contact *domain.Contact, notification *domain.Notification) (string, error) {
+	tmpl, exists := t.subject[notification.Event]
+	if !exists {
+		return "", fmt.Errorf("no template for event %s", notification.Event)
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.Execute(&subject, newNotificationData(contact, notification)); err != nil {
+		return "", fmt.Errorf("render subject: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), nil
+}
+
+// Render renders the full message for the contact
+func (t *NotificationTemplates) Render(contact *domain.Contact, notification *domain.Notification) (*EmailMessage, error) {
+	subject, err := t.Subject(contact, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	data := newNotificationData(contact, notification)
+	var text, html bytes.Buffer
+	if err := t.text[notification.Event].Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("render text body: %w", err)
+	}
+	if err := t.html[notification.Event].Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("render HTML body: %w", err)
+	}
+
+	return &EmailMessage{
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"time"
+)
+
+// queueNotifications records notifications in the outbox as part of the
+// caller's unit of work
+func queueNotifications(ctx context.Context, repo repository.LoanRepository, notifications ...*domain.Notification) error {
+	for _, notification := range notifications {
+		message, err := domain.NewOutboxMessage(domain.TopicNotification, notification)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.SaveOutboxMessage(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// investorNotifications addresses event to each of the loan's investors once,
+// with the total they have actively invested
+func investorNotifications(event domain.NotificationEvent, loan *domain.Loan) []*domain.Notification {
+	var notifications []*domain.Notification
+	byInvestor := make(map[string]*domain.Notification)
+	for _, investment := range loan.Investments {
+		if investment.Status != domain.InvestmentStatusActive {
+			continue
+		}
+
+		notification, exists := byInvestor[investment.InvestorID]
+		if !exists {
+			notification = domain.NewNotification(event, domain.RecipientInvestor, investment.InvestorID, loan.ID).
+				WithAmount(domain.ZeroMoney(loan.Currency()))
+			byInvestor[investment.InvestorID] = notification
+			notifications = append(notifications, notification)
+		}
+		notification.Amount.Amount += investment.Amount.Amount
+	}
+	return notifications
+}
+
+// SendRepaymentReminders queues a REPAYMENT_DUE notification to the borrower
+// of each DISBURSED loan for every unpaid installment falling due within the
+// reminder lead time. Each installment is reminded about once. It returns
+// how many reminders were queued.
+func (s *LoanService) SendRepaymentReminders(ctx context.Context) (int, error) {
+	loans, _, err := s.repo.ListLoans(ctx, domain.LoanFilter{States: []domain.LoanState{domain.LoanStateDisbursed}}, domain.LoanSort{}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	horizon := time.Now().AddDate(0, 0, s.reminderLeadDays)
+	queued := 0
+	for _, loan := range loans {
+		status, err := s.repaymentStatus(ctx, loan.ID)
+		if err != nil {
+			return queued, fmt.Errorf("remind loan %s: %w", loan.ID, err)
+		}
+
+		for _, installment := range status.Installments {
+			if installment.State != domain.InstallmentStatePending && installment.State != domain.InstallmentStatePartial {
+				continue
+			}
+			if installment.DueDate.After(horizon) {
+				break // installments are in due date order
+			}
+
+			dueDate := installment.DueDate
+			notification := domain.NewNotification(domain.NotificationRepaymentDue, domain.RecipientBorrower, loan.BorrowerID, loan.ID).
+				WithAmount(installment.Outstanding)
+			notification.InstallmentNumber = installment.Number
+			notification.DueDate = &dueDate
+
+			message, err := domain.NewOutboxMessage(domain.TopicNotification, notification)
+			if err != nil {
+				return queued, err
+			}
+			// The ID is derived from the installment so that a reminder
+			// already in the outbox, delivered or not, is not queued again
+			message.ID = fmt.Sprintf("msg_due_%s_%d", loan.ID, installment.Number)
+
+			added := false
+			err = s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+				_, err := repo.GetOutboxMessage(ctx, message.ID)
+				if err == nil {
+					return nil
+				}
+				if !errors.Is(err, domain.ErrNotFound) {
+					return err
+				}
+				added = true
+				return repo.SaveOutboxMessage(ctx, message)
+			})
+			if err != nil {
+				return queued, fmt.Errorf("remind loan %s: %w", loan.ID, err)
+			}
+			if added {
+				queued++
+			}
+		}
+	}
+
+	return queued, nil
+}
+
+// RunRepaymentReminders calls SendRepaymentReminders every interval until
+// ctx is cancelled, logging failures
+func (s *LoanService) RunRepaymentReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SendRepaymentReminders(ctx); err != nil && ctx.Err() == nil {
+				domain.LoggerFromContext(ctx).Error("repayment reminders failed", "error", err)
+			}
+		}
+	}
+}
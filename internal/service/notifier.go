@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loan/internal/domain"
+)
+
+// Notifier delivers a notification to its recipients
+type Notifier interface {
+	Notify(ctx context.Context, notification *domain.Notification) error
+}
+
+// Channel delivers a notification to one contact over one medium
+type Channel interface {
+	Name() domain.NotificationChannel
+	Send(ctx context.Context, contact *domain.Contact, notification *domain.Notification) error
+}
+
+// ChannelNotifier looks recipients up in a contact directory and sends the
+// notification over each channel the recipient prefers. A notification with
+// no recipient ID goes to every contact with the recipient role.
+type ChannelNotifier struct {
+	contacts ContactDirectory
+	channels map[domain.NotificationChannel]Channel
+}
+
+func NewChannelNotifier(contacts ContactDirectory, channels ...Channel) *ChannelNotifier {
+	notifier := &ChannelNotifier{
+		contacts: contacts,
+		channels: make(map[domain.NotificationChannel]Channel, len(channels)),
+	}
+	for _, channel := range channels {
+		notifier.channels[channel.Name()] = channel
+	}
+	return notifier
+}
+
+// Notify attempts every recipient and channel even when some fail, and
+// returns the failures joined. The notification is retried as a whole, so a
+// recipient may receive it more than once on the channels that succeeded.
+// Retrying cannot help a recipient missing from the directory, or a channel
+// that is not configured, so those are logged and skipped instead.
+func (n *ChannelNotifier) Notify(ctx context.Context, notification *domain.Notification) error {
+	logger := domain.LoggerFromContext(ctx)
+	recipients, err := n.recipients(ctx, notification)
+	if errors.Is(err, domain.ErrNotFound) {
+		logger.Warn("skipping notification to unknown recipient",
+			"recipient_role", notification.RecipientRole, "recipient_id", notification.RecipientID,
+			"event", notification.Event, "loan_id", notification.LoanID, "error", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, contact := range recipients {
+		for _, name := range contact.PreferredChannels() {
+			channel, exists := n.channels[name]
+			if !exists {
+				logger.Warn("skipping notification over unconfigured channel",
+					"recipient_role", contact.Role, "recipient_id", contact.ID, "channel", name,
+					"event", notification.Event, "loan_id", notification.LoanID)
+				continue
+			}
+
+			if err := channel.Send(ctx, contact, notification); err != nil {
+				errs = append(errs, fmt.Errorf("%s to %s %s: %w", name, contact.Role, contact.ID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *ChannelNotifier) recipients(ctx context.Context, notification *domain.Notification) ([]*domain.Contact, error) {
+	if notification.RecipientID == "" {
+		return n.contacts.ListContacts(ctx, notification.RecipientRole)
+	}
+
+	contact, err := n.contacts.LookupContact(ctx, notification.RecipientRole, notification.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+	return []*domain.Contact{contact}, nil
+}
+
+type MockNotifier struct{}
+
+func NewMockNotifier() *MockNotifier {
+	return &MockNotifier{}
+}
+
+func (n *MockNotifier) Notify(ctx context.Context, notification *domain.Notification) error {
+	// Logging the notification as simulation
+	recipient := notification.RecipientID
+	if recipient == "" {
+		recipient = "all"
+	}
+	domain.LoggerFromContext(ctx).Info("notifying",
+		"recipient_role", notification.RecipientRole, "recipient_id", recipient,
+		"event", notification.Event, "loan_id", notification.LoanID)
+	return nil
+}
@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"loan/internal/domain"
+	"loan/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingChannel records which contacts it was asked to notify
+type recordingChannel struct {
+	name domain.NotificationChannel
+	err  error
+	sent []string
+}
+
+func (c *recordingChannel) Name() domain.NotificationChannel {
+	return c.name
+}
+
+func (c *recordingChannel) Send(ctx context.Context, contact *domain.Contact, notification *domain.Notification) error {
+	c.sent = append(c.sent, contact.ID)
+	return c.err
+}
+
+// recordingSMSGateway records the texts it is asked to send
+type recordingSMSGateway struct {
+	texts map[string]string
+}
+
+func (g *recordingSMSGateway) SendSMS(ctx context.Context, phone, text string) error {
+	g.texts[phone] = text
+	return nil
+}
+
+func TestChannelNotifierUsesChannelPreferences(t *testing.T) {
+	// Arrange
+	email := &recordingChannel{name: domain.ChannelEmail}
+	sms := &recordingChannel{name: domain.ChannelSMS}
+	directory := service.NewStaticContactDirectory(
+		&domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Email: "b@example.com", Phone: "+62811", Channels: []domain.NotificationChannel{domain.ChannelSMS, domain.ChannelEmail}},
+		&domain.Contact{ID: "investor1", Role: domain.RecipientInvestor, Email: "i@example.com"},
+	)
+	notifier := service.NewChannelNotifier(directory, email, sms)
+
+	// Act
+	err1 := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientBorrower, "borrower1", "loan1"))
+	err2 := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanInvested, domain.RecipientInvestor, "investor1", "loan1"))
+
+	// Assert
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Expected no errors, got %v and %v", err1, err2)
+	}
+	if strings.Join(email.sent, ",") != "borrower1,investor1" {
+		t.Errorf("Expected email to borrower1 and investor1, got %v", email.sent)
+	}
+	if strings.Join(sms.sent, ",") != "borrower1" {
+		t.Errorf("Expected SMS only to borrower1, got %v", sms.sent)
+	}
+}
+
+func TestChannelNotifierBroadcastsToStaff(t *testing.T) {
+	// Arrange
+	email := &recordingChannel{name: domain.ChannelEmail}
+	directory := service.NewStaticContactDirectory(
+		&domain.Contact{ID: "staff2", Role: domain.RecipientStaff, Email: "s2@example.com"},
+		&domain.Contact{ID: "staff1", Role: domain.RecipientStaff, Email: "s1@example.com"},
+		&domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Email: "b@example.com"},
+	)
+	notifier := service.NewChannelNotifier(directory, email)
+
+	// Act
+	err := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientStaff, "", "loan1"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(email.sent, ",") != "staff1,staff2" {
+		t.Errorf("Expected email to every member of staff, got %v", email.sent)
+	}
+}
+
+func TestChannelNotifierReportsFailures(t *testing.T) {
+	// Arrange
+	email := &recordingChannel{name: domain.ChannelEmail, err: errors.New("mailbox full")}
+	sms := &recordingChannel{name: domain.ChannelSMS}
+	directory := service.NewStaticContactDirectory(
+		&domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Email: "b@example.com", Phone: "+62811",
+			Channels: []domain.NotificationChannel{domain.ChannelEmail, domain.ChannelSMS, domain.ChannelWebhook}},
+	)
+	notifier := service.NewChannelNotifier(directory, email, sms)
+
+	// Act
+	err := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientBorrower, "borrower1", "loan1"))
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "mailbox full") {
+		t.Errorf("Expected the email failure to be reported, got %v", err)
+	}
+	if len(sms.sent) != 1 {
+		t.Errorf("Expected SMS to be sent despite the email failure, got %v", sms.sent)
+	}
+}
+
+func TestChannelNotifierSkipsUnknownRecipients(t *testing.T) {
+	// Arrange
+	email := &recordingChannel{name: domain.ChannelEmail}
+	directory := service.NewStaticContactDirectory(
+		&domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Email: "b@example.com"},
+	)
+	notifier := service.NewChannelNotifier(directory, email)
+
+	// Act
+	err := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientBorrower, "missing", "loan1"))
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected a recipient missing from the directory to be skipped, got %v", err)
+	}
+	if len(email.sent) != 0 {
+		t.Errorf("Expected nothing to be sent, got %v", email.sent)
+	}
+}
+
+func TestChannelNotifierSkipsUnconfiguredChannels(t *testing.T) {
+	// Arrange
+	email := &recordingChannel{name: domain.ChannelEmail}
+	directory := service.NewStaticContactDirectory(
+		&domain.Contact{ID: "staff1", Role: domain.RecipientStaff, Email: "s1@example.com", WebhookURL: "https://example.com/hook",
+			Channels: []domain.NotificationChannel{domain.ChannelWebhook, domain.ChannelEmail}},
+		&domain.Contact{ID: "staff2", Role: domain.RecipientStaff, Email: "s2@example.com"},
+	)
+	notifier := service.NewChannelNotifier(directory, email)
+
+	// Act
+	err := notifier.Notify(context.Background(), domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientStaff, "", "loan1"))
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected the unconfigured webhook channel to be skipped, got %v", err)
+	}
+	if strings.Join(email.sent, ",") != "staff1,staff2" {
+		t.Errorf("Expected email to every member of staff, got %v", email.sent)
+	}
+}
+
+func TestSMSChannelTextsSubjectLine(t *testing.T) {
+	// Arrange
+	gateway := &recordingSMSGateway{texts: make(map[string]string)}
+	channel := service.NewSMSChannel(gateway, nil)
+	contact := &domain.Contact{ID: "borrower1", Role: domain.RecipientBorrower, Phone: "+62811"}
+	notification := domain.NewNotification(domain.NotificationRepaymentDue, domain.RecipientBorrower, "borrower1", "loan1").WithAmount(mustMoney("110.00"))
+	notification.InstallmentNumber = 2
+
+	// Act
+	err := channel.Send(context.Background(), contact, notification)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if text := gateway.texts["+62811"]; !strings.Contains(text, "Installment 2") || !strings.Contains(text, "110.00 IDR") {
+		t.Errorf("Expected SMS with installment and amount, got %q", text)
+	}
+}
+
+func TestWebhookChannelPostsNotification(t *testing.T) {
+	// Arrange
+	var received domain.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		if received.LoanID == "rejected" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	channel := service.NewWebhookChannel(server.Client())
+	contact := &domain.Contact{ID: "staff1", Role: domain.RecipientStaff, WebhookURL: server.URL}
+
+	// Act
+	err := channel.Send(context.Background(), contact, domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientStaff, "", "loan1"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.Event != domain.NotificationLoanApproved || received.LoanID != "loan1" {
+		t.Errorf("Expected LOAN_APPROVED for loan1, got %+v", received)
+	}
+
+	if err := channel.Send(context.Background(), contact, domain.NewNotification(domain.NotificationLoanApproved, domain.RecipientStaff, "", "rejected")); err == nil {
+		t.Error("Expected error when the webhook responds 500, got nil")
+	}
+}
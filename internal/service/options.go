@@ -0,0 +1,84 @@
+package service
+
+import (
+	"loan/internal/domain"
+	"net/http"
+	"time"
+)
+
+// DefaultScheduleTerms are used for repayment schedules unless overridden
+// with WithScheduleTerms: twelve monthly installments with flat interest.
+var DefaultScheduleTerms = domain.ScheduleTerms{
+	Tenor:     12,
+	Frequency: domain.RepaymentFrequencyMonthly,
+	Method:    domain.InterestMethodFlat,
+}
+
+// DefaultDelinquencyPolicy reports a loan delinquent after 3 days past due
+// and defaults it after 90, unless overridden with WithDelinquencyPolicy
+var DefaultDelinquencyPolicy = domain.DelinquencyPolicy{
+	GraceDays:        3,
+	DefaultAfterDays: 90,
+}
+
+// DefaultRetryPolicy retries failed notifications 8 times, backing off from
+// 30 seconds to at most an hour, unless overridden with WithRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    time.Hour,
+}
+
+// DefaultReminderLeadDays is how many days before an installment falls due
+// the borrower is reminded, unless overridden with WithReminderLeadDays
+const DefaultReminderLeadDays = 3
+
+// Option customises a LoanService
+type Option func(*LoanService)
+
+// WithScheduleTerms sets the terms used to generate repayment schedules when
+// a loan is disbursed
+func WithScheduleTerms(terms domain.ScheduleTerms) Option {
+	return func(s *LoanService) {
+		s.scheduleTerms = terms
+	}
+}
+
+// WithDelinquencyPolicy sets the thresholds used to flag delinquent loans and
+// move them to DEFAULTED
+func WithDelinquencyPolicy(policy domain.DelinquencyPolicy) Option {
+	return func(s *LoanService) {
+		s.delinquencyPolicy = policy
+	}
+}
+
+// WithAgreementLetterGenerator generates an agreement letter whenever a loan
+// becomes fully invested. Without one, AgreementLetterURL is left empty.
+func WithAgreementLetterGenerator(generator AgreementLetterGenerator) Option {
+	return func(s *LoanService) {
+		s.agreementLetters = generator
+	}
+}
+
+// WithRetryPolicy sets how failed outbox deliveries are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *LoanService) {
+		s.retryPolicy = policy
+	}
+}
+
+// WithReminderLeadDays sets how many days before an installment falls due
+// the borrower is sent a REPAYMENT_DUE notification
+func WithReminderLeadDays(days int) Option {
+	return func(s *LoanService) {
+		s.reminderLeadDays = days
+	}
+}
+
+// WithWebhookClient sets the HTTP client webhook events are delivered with.
+// The default times out after 10 seconds.
+func WithWebhookClient(client *http.Client) Option {
+	return func(s *LoanService) {
+		s.webhookClient = client
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"time"
+)
+
+// outboxBatchSize bounds how many due messages one dispatch pass delivers
+const outboxBatchSize = 100
+
+// RetryPolicy controls redelivery of failed outbox messages. Attempt n is
+// retried after BaseDelay * 2^(n-1), capped at MaxDelay, and a message is
+// dead-lettered once MaxAttempts deliveries have failed.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed attempts
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// DispatchOutbox delivers every outbox message that is due, recording the
+// outcome of each. It returns how many were delivered. Messages are
+// delivered at least once: one that is sent but whose outcome cannot be saved
+// will be sent again.
+func (s *LoanService) DispatchOutbox(ctx context.Context) (int, error) {
+	now := time.Now()
+	messages, err := s.repo.ListDueOutboxMessages(ctx, now, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, message := range messages {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		if err := s.deliver(ctx, message); err != nil {
+			at := time.Now()
+			var retryAt *time.Time
+			if message.Attempts+1 < s.retryPolicy.MaxAttempts {
+				next := at.Add(s.retryPolicy.backoff(message.Attempts + 1))
+				retryAt = &next
+			}
+			message.MarkFailed(err, at, retryAt)
+			if message.Status == domain.OutboxStatusDead {
+				domain.LoggerFromContext(ctx).Error("outbox message dead-lettered",
+					"message_id", message.ID, "topic", message.Topic, "attempts", message.Attempts, "error", err)
+			}
+		} else {
+			message.MarkDelivered(time.Now())
+			delivered++
+		}
+
+		if err := s.repo.SaveOutboxMessage(ctx, message); err != nil {
+			return delivered, fmt.Errorf("save outbox message %s: %w", message.ID, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// RunOutboxDispatcher calls DispatchOutbox every interval until ctx is
+// cancelled, logging failures
+func (s *LoanService) RunOutboxDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DispatchOutbox(ctx); err != nil && ctx.Err() == nil {
+				domain.LoggerFromContext(ctx).Error("outbox dispatch failed", "error", err)
+			}
+		}
+	}
+}
+
+// ListOutboxMessages pages through outbox messages, optionally restricted to
+// one status ("" for all)
+func (s *LoanService) ListOutboxMessages(ctx context.Context, status domain.OutboxStatus, page, pageSize int) ([]*domain.OutboxMessage, int, error) {
+	return s.repo.ListOutboxMessages(ctx, status, page, pageSize)
+}
+
+// ReplayOutboxMessage queues a dead-lettered message for immediate redelivery
+func (s *LoanService) ReplayOutboxMessage(ctx context.Context, id string) (*domain.OutboxMessage, error) {
+	message, err := s.repo.GetOutboxMessage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := snapshot(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := message.Replay(time.Now()); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		if err := repo.SaveOutboxMessage(ctx, message); err != nil {
+			return err
+		}
+
+		return audit(ctx, repo, domain.AuditReplayOutboxMessage, "", before, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// deliver routes a message to the service that sends it
+func (s *LoanService) deliver(ctx context.Context, message *domain.OutboxMessage) error {
+	switch message.Topic {
+	case domain.TopicNotification:
+		var notification domain.Notification
+		if err := json.Unmarshal(message.Payload, &notification); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return s.notifier.Notify(ctx, &notification)
+	case domain.TopicWebhook:
+		var dispatch domain.WebhookDispatch
+		if err := json.Unmarshal(message.Payload, &dispatch); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return s.deliverWebhook(ctx, &dispatch, message.Attempts+1)
+	default:
+		return fmt.Errorf("no handler for topic %q", message.Topic)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"loan/internal/domain"
+)
+
+// SMSGateway sends a text message to a phone number
+type SMSGateway interface {
+	SendSMS(ctx context.Context, phone, text string) error
+}
+
+// LogSMSGateway prints messages instead of sending them. It stands in until
+// an SMS provider is integrated.
+type LogSMSGateway struct{}
+
+func NewLogSMSGateway() *LogSMSGateway {
+	return &LogSMSGateway{}
+}
+
+func (g *LogSMSGateway) SendSMS(ctx context.Context, phone, text string) error {
+	domain.LoggerFromContext(ctx).Info("sending SMS", "phone", phone, "text", text)
+	return nil
+}
+
+// SMSChannel texts the contact the notification's subject line
+type SMSChannel struct {
+	gateway   SMSGateway
+	templates *NotificationTemplates
+}
+
+// NewSMSChannel uses the default templates when templates is nil
+func NewSMSChannel(gateway SMSGateway, templates *NotificationTemplates) *SMSChannel {
+	if templates == nil {
+		templates = DefaultNotificationTemplates()
+	}
+	return &SMSChannel{
+		gateway:   gateway,
+		templates: templates,
+	}
+}
+
+func (c *SMSChannel) Name() domain.NotificationChannel {
+	return domain.ChannelSMS
+}
+
+func (c *SMSChannel) Send(ctx context.Context, contact *domain.Contact, notification *domain.Notification) error {
+	if contact.Phone == "" {
+		return fmt.Errorf("%s %s has no phone number", contact.Role, contact.ID)
+	}
+
+	text, err := c.templates.Subject(contact, notification)
+	if err != nil {
+		return err
+	}
+
+	return c.gateway.SendSMS(ctx, contact.Phone, text)
+}
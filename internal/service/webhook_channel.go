@@ -0,0 +1,62 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"loan/internal/domain"
+	"net/http"
+	"time"
+)
+
+// defaultWebhookTimeout bounds a single webhook call
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookChannel POSTs the notification as JSON to the contact's webhook URL.
+// Any response other than 2xx is a failed delivery.
+type WebhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel uses a client with a 10 second timeout when client is nil
+func NewWebhookChannel(client *http.Client) *WebhookChannel {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	return &WebhookChannel{
+		client: client,
+	}
+}
+
+func (c *WebhookChannel) Name() domain.NotificationChannel {
+	return domain.ChannelWebhook
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, contact *domain.Contact, notification *domain.Notification) error {
+	if contact.WebhookURL == "" {
+		return fmt.Errorf("%s %s has no webhook URL", contact.Role, contact.ID)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, contact.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %s", contact.WebhookURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+)
+
+// SignWebhook returns the signature sent in the X-Webhook-Signature header:
+// "sha256=" followed by the hex HMAC-SHA256, keyed by the subscription
+// secret, of the X-Webhook-Timestamp value, a full stop and the body.
+// Including the timestamp lets receivers reject replayed requests.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signature produced by SignWebhook in
+// constant time
+func VerifyWebhookSignature(secret string, timestamp time.Time, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
+
+// CreateWebhookSubscription registers url for events, or every event when
+// events is empty. The returned subscription includes the signing secret,
+// which is not shown again.
+func (s *LoanService) CreateWebhookSubscription(ctx context.Context, url string, events []domain.WebhookEventType) (*domain.WebhookSubscription, error) {
+	subscription, err := domain.NewWebhookSubscription(url, events)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		if err := repo.SaveWebhookSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		return audit(ctx, repo, domain.AuditCreateWebhook, "", nil, subscription.Redacted())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (s *LoanService) GetWebhookSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	subscription, err := s.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return subscription.Redacted(), nil
+}
+
+func (s *LoanService) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	subscriptions, err := s.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, subscription := range subscriptions {
+		subscriptions[i] = subscription.Redacted()
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription deactivates a subscription. Deliveries already
+// queued for it are dropped, and its delivery log is kept.
+func (s *LoanService) DeleteWebhookSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	subscription, err := s.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !subscription.Active {
+		return nil, domain.InvalidStateError("webhook subscription is already deleted")
+	}
+
+	before, err := snapshot(subscription.Redacted())
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Deactivate(time.Now())
+	err = s.repo.WithinTx(ctx, func(repo repository.LoanRepository) error {
+		if err := repo.SaveWebhookSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		return audit(ctx, repo, domain.AuditDeleteWebhook, "", before, subscription.Redacted())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription.Redacted(), nil
+}
+
+// ListWebhookDeliveries pages through a subscription's delivery attempts,
+// most recent first
+func (s *LoanService) ListWebhookDeliveries(ctx context.Context, subscriptionID string, page, pageSize int) ([]*domain.WebhookDelivery, int, error) {
+	return s.repo.ListWebhookDeliveries(ctx, subscriptionID, page, pageSize)
+}
+
+// queueWebhookEvent queues eventType for every subscription to it as part of
+// the caller's unit of work, so events are only sent for changes that commit
+func queueWebhookEvent(ctx context.Context, repo repository.LoanRepository, eventType domain.WebhookEventType, loan *domain.Loan, investment *domain.Investment) error {
+	subscriptions, err := repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := domain.NewWebhookEvent(eventType, loan, investment)
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(eventType) {
+			continue
+		}
+
+		message, err := domain.NewOutboxMessage(domain.TopicWebhook, domain.WebhookDispatch{
+			SubscriptionID: subscription.ID,
+			Event:          event,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := repo.SaveOutboxMessage(ctx, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliverWebhook POSTs a queued event to its subscription and records the
+// attempt in the delivery log. Any response other than 2xx fails the
+// delivery, which the outbox then retries.
+func (s *LoanService) deliverWebhook(ctx context.Context, dispatch *domain.WebhookDispatch, attempt int) error {
+	subscription, err := s.repo.GetWebhookSubscription(ctx, dispatch.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if !subscription.Active {
+		return nil
+	}
+
+	body, err := json.Marshal(dispatch.Event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(dispatch.Event.Type))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(subscription.Secret, now, body))
+
+	delivery := domain.NewWebhookDelivery(subscription, dispatch.Event, attempt, now)
+	resp, sendErr := s.webhookClient.Do(req)
+	delivery.DurationMillis = time.Since(now).Milliseconds()
+	if sendErr == nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		delivery.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			sendErr = fmt.Errorf("webhook %s responded %s", subscription.URL, resp.Status)
+		}
+	}
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	} else {
+		delivery.Succeeded = true
+	}
+
+	if err := s.repo.SaveWebhookDelivery(ctx, delivery); err != nil {
+		return errors.Join(sendErr, fmt.Errorf("record webhook delivery: %w", err))
+	}
+
+	return sendErr
+}
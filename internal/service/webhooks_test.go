@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"loan/internal/domain"
+	"loan/internal/repository"
+	"loan/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the events POSTed to it, failing the first
+// `failures` requests with a 500
+type webhookReceiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	failures int
+	events   []*domain.WebhookEvent
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	unix, _ := strconv.ParseInt(r.Header.Get(service.WebhookTimestampHeader), 10, 64)
+	if !service.VerifyWebhookSignature(rcv.secret, time.Unix(unix, 0), body, r.Header.Get(service.WebhookSignatureHeader)) {
+		rcv.t.Errorf("Expected a valid signature, got %q", r.Header.Get(service.WebhookSignatureHeader))
+	}
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if rcv.failures > 0 {
+		rcv.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var event domain.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		rcv.t.Errorf("Expected a JSON event, got %v", err)
+	}
+	if r.Header.Get(service.WebhookEventHeader) != string(event.Type) {
+		rcv.t.Errorf("Expected event header %s, got %s", event.Type, r.Header.Get(service.WebhookEventHeader))
+	}
+	rcv.events = append(rcv.events, &event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rcv *webhookReceiver) received() []domain.WebhookEventType {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	var types []domain.WebhookEventType
+	for _, event := range rcv.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// subscribe registers a receiver for events with a test server
+func subscribe(t *testing.T, loanService *service.LoanService, failures int, events ...domain.WebhookEventType) (*domain.WebhookSubscription, *webhookReceiver) {
+	t.Helper()
+	receiver := &webhookReceiver{t: t, failures: failures}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	subscription, err := loanService.CreateWebhookSubscription(context.Background(), server.URL, events)
+	if err != nil {
+		t.Fatalf("Expected no error creating webhook subscription, got %v", err)
+	}
+	receiver.secret = subscription.Secret
+	return subscription, receiver
+}
+
+func TestSignWebhook(t *testing.T) {
+	// Arrange
+	timestamp := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1"}`)
+
+	// Act
+	signature := service.SignWebhook("whsec_test", timestamp, body)
+
+	// Assert
+	if signature != service.SignWebhook("whsec_test", timestamp, body) || len(signature) != len("sha256=")+64 {
+		t.Fatalf("Expected a stable sha256= hex signature, got %q", signature)
+	}
+	if !service.VerifyWebhookSignature("whsec_test", timestamp, body, signature) {
+		t.Error("Expected signature to verify")
+	}
+	if service.VerifyWebhookSignature("whsec_other", timestamp, body, signature) {
+		t.Error("Expected signature not to verify with another secret")
+	}
+	if service.VerifyWebhookSignature("whsec_test", timestamp.Add(time.Second), body, signature) {
+		t.Error("Expected signature not to verify with another timestamp")
+	}
+}
+
+func TestWebhooksDeliverSubscribedEvents(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), NewMockNotifierWithTracking())
+	_, everything := subscribe(t, loanService, 0)
+	_, disbursals := subscribe(t, loanService, 0, domain.WebhookLoanDisbursed)
+
+	// Act
+	loan := disbursedLoan(t, loanService, "1000.00", time.Now())
+	if _, err := loanService.DispatchOutbox(ctx); err != nil {
+		t.Fatalf("Expected webhooks to be delivered, got %v", err)
+	}
+
+	// Assert
+	want := []domain.WebhookEventType{domain.WebhookLoanApproved, domain.WebhookInvestmentAdded, domain.WebhookLoanInvested, domain.WebhookLoanDisbursed}
+	got := everything.received()
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for _, eventType := range want {
+		found := false
+		for _, event := range everything.events {
+			found = found || (event.Type == eventType && event.Data.Loan.ID == loan.ID)
+		}
+		if !found {
+			t.Errorf("Expected %s event for loan %s", eventType, loan.ID)
+		}
+	}
+
+	if got := disbursals.received(); len(got) != 1 || got[0] != domain.WebhookLoanDisbursed {
+		t.Errorf("Expected only the disbursement event, got %v", got)
+	}
+	if state := disbursals.events[0].Data.Loan.State; state != domain.LoanStateDisbursed {
+		t.Errorf("Expected the event to carry the DISBURSED loan, got %s", state)
+	}
+}
+
+func TestWebhooksRetryAndLogDeliveries(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), NewMockNotifierWithTracking(),
+		service.WithRetryPolicy(service.RetryPolicy{MaxAttempts: 3, BaseDelay: 0, MaxDelay: 0}))
+	subscription, receiver := subscribe(t, loanService, 1, domain.WebhookLoanApproved)
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+
+	// Act
+	_, _ = loanService.DispatchOutbox(ctx)
+	_, _ = loanService.DispatchOutbox(ctx)
+
+	// Assert
+	if got := receiver.received(); len(got) != 1 {
+		t.Fatalf("Expected the event to be delivered on retry, got %v", got)
+	}
+
+	deliveries, total, err := loanService.ListWebhookDeliveries(ctx, subscription.ID, 1, 10)
+	if err != nil || total != 2 {
+		t.Fatalf("Expected 2 logged deliveries, got %d (%v)", total, err)
+	}
+	retry, first := deliveries[0], deliveries[1]
+	if first.Attempt != 1 || first.Succeeded || first.StatusCode != http.StatusInternalServerError || first.Error == "" {
+		t.Errorf("Expected the first attempt to be logged as failed, got %+v", first)
+	}
+	if retry.Attempt != 2 || !retry.Succeeded || retry.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected the retry to be logged as succeeded, got %+v", retry)
+	}
+	if first.EventID != retry.EventID {
+		t.Errorf("Expected both attempts to carry event %s, got %s", first.EventID, retry.EventID)
+	}
+}
+
+func TestDeleteWebhookSubscriptionStopsDeliveries(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), NewMockNotifierWithTracking())
+	subscription, receiver := subscribe(t, loanService, 0)
+	loan, _ := loanService.CreateLoan(ctx, "borrower123", mustMoney("1000.00"), 0.1, 0.08)
+	_, _ = loanService.ApproveLoan(ctx, loan.ID, "proof.jpg", "validator123", time.Now())
+
+	// Act
+	deleted, err := loanService.DeleteWebhookSubscription(ctx, subscription.ID)
+	_, _ = loanService.DispatchOutbox(ctx)
+
+	// Assert
+	if err != nil || deleted.Active || deleted.Secret != "" {
+		t.Fatalf("Expected an inactive, redacted subscription, got %+v (%v)", deleted, err)
+	}
+	if got := receiver.received(); len(got) != 0 {
+		t.Errorf("Expected no deliveries after deletion, got %v", got)
+	}
+	if _, err := loanService.DeleteWebhookSubscription(ctx, subscription.ID); err == nil {
+		t.Error("Expected error deleting a subscription twice, got nil")
+	}
+
+	listed, _ := loanService.ListWebhookSubscriptions(ctx)
+	if len(listed) != 1 || listed[0].Secret != "" {
+		t.Errorf("Expected listed subscriptions to hide the secret, got %+v", listed)
+	}
+}
+
+func TestCreateWebhookSubscriptionValidates(t *testing.T) {
+	loanService := service.NewLoanService(repository.NewMockLoanRepository(), NewMockNotifierWithTracking())
+
+	for _, tc := range []struct {
+		url    string
+		events []domain.WebhookEventType
+	}{
+		{"example.com/hooks", nil},
+		{"ftp://example.com/hooks", nil},
+		{"https://example.com/hooks", []domain.WebhookEventType{"loan.exploded"}},
+	} {
+		if _, err := loanService.CreateWebhookSubscription(context.Background(), tc.url, tc.events); err == nil {
+			t.Errorf("Expected error subscribing %s to %v, got nil", tc.url, tc.events)
+		}
+	}
+}
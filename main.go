@@ -3,32 +3,105 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"loan/internal/api"
+	"loan/internal/api/middleware"
+	"loan/internal/document"
+	"loan/internal/domain"
 	"loan/internal/repository"
 	"loan/internal/service"
+
+	"github.com/gorilla/mux"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
-	repo := repository.NewMockLoanRepository()
-
-	emailService := service.NewMockEmailService()
-
-	loanService := service.NewLoanService(repo, emailService)
+	logger, err := loggerFromEnv()
+	if err != nil {
+		fatal("invalid logging configuration", err)
+	}
+	slog.SetDefault(logger)
 
-	router := api.SetupRouter(loanService)
+	repo, closeRepo, err := newRepository()
+	if err != nil {
+		fatal("could not initialise repository", err)
+	}
+	defer closeRepo()
 
 	port := "8080"
 	if envPort := os.Getenv("PORT"); envPort != "" {
 		port = envPort
 	}
 
+	notifier, err := notifierFromEnv()
+	if err != nil {
+		fatal("invalid notification configuration", err)
+	}
+
+	// Agreement letters are written to DOCUMENT_DIR and served by the API,
+	// whose public address PUBLIC_BASE_URL is used in the links sent out
+	documentDir := os.Getenv("DOCUMENT_DIR")
+	if documentDir == "" {
+		documentDir = "documents"
+	}
+	publicBaseURL := os.Getenv("PUBLIC_BASE_URL")
+	if publicBaseURL == "" {
+		publicBaseURL = "http://localhost:" + port
+	}
+	documentStore := document.NewLocalStore(documentDir)
+
+	scheduleTerms, err := scheduleTermsFromEnv()
+	if err != nil {
+		fatal("invalid repayment schedule configuration", err)
+	}
+
+	delinquencyPolicy, err := delinquencyPolicyFromEnv()
+	if err != nil {
+		fatal("invalid delinquency configuration", err)
+	}
+
+	reminderLeadDays, err := envInt("REMINDER_LEAD_DAYS", service.DefaultReminderLeadDays)
+	if err != nil {
+		fatal("invalid reminder configuration", err)
+	}
+
+	loanService := service.NewLoanService(repo, notifier,
+		service.WithScheduleTerms(scheduleTerms),
+		service.WithDelinquencyPolicy(delinquencyPolicy),
+		service.WithAgreementLetterGenerator(service.NewAgreementLetterGenerator(documentStore, publicBaseURL)),
+		service.WithReminderLeadDays(reminderLeadDays),
+	)
+
+	// Background workers run until the server shuts down
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Periodically move loans that stopped paying to DEFAULTED
+	go loanService.RunDelinquencyChecks(workersCtx, time.Hour)
+
+	// Remind borrowers of installments falling due
+	go loanService.RunRepaymentReminders(workersCtx, time.Hour)
+
+	// Deliver queued notifications in the background
+	go loanService.RunOutboxDispatcher(workersCtx, time.Second)
+
+	// Drop stored responses to idempotency keys that have expired
+	go loanService.RunIdempotencyKeyPurge(workersCtx, time.Hour)
+
+	authenticate, err := authenticatorFromEnv()
+	if err != nil {
+		fatal("invalid authentication configuration", err)
+	}
+
+	router := api.SetupRouter(loanService, authenticate)
+
 	server := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
@@ -39,9 +112,9 @@ func main() {
 
 	// Start the server in a goroutine
 	go func() {
-		fmt.Printf("Server starting on port %s...\n", port)
+		slog.Info("server starting", "port", port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not start server: %v\n", err)
+			fatal("could not start server", err)
 		}
 	}()
 
@@ -55,10 +128,197 @@ func main() {
 	defer cancel()
 
 	// Shut down the server
-	fmt.Println("Shutting down server...")
+	slog.Info("shutting down server")
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v\n", err)
+		fatal("server shutdown failed", err)
+	}
+
+	slog.Info("server exited properly")
+}
+
+// loggerFromEnv logs JSON lines to stdout, at the level in LOG_LEVEL (DEBUG,
+// INFO, WARN or ERROR; INFO by default)
+func loggerFromEnv() (*slog.Logger, error) {
+	var level slog.Level
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})), nil
+}
+
+// fatal logs err and exits
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
+}
+
+// newRepository selects the storage backend from DB_DRIVER ("memory" by
+// default, or any registered database/sql driver such as "sqlite") and
+// DB_DSN. SQL backends are migrated before use.
+func newRepository() (repository.LoanRepository, func(), error) {
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" || driver == "memory" {
+		return repository.NewMockLoanRepository(), func() {}, nil
 	}
 
-	fmt.Println("Server exited properly")
+	dsn := os.Getenv("DB_DSN")
+	if dsn == "" && driver == "sqlite" {
+		dsn = "file:loan.db?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := repository.OpenSQLDatabase(ctx, driver, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return repository.NewSQLLoanRepository(db), func() { db.Close() }, nil
+}
+
+// authenticatorFromEnv requires JWT bearer tokens on the API, signed with
+// the HS256 secret in JWT_HS256_SECRET or an RS256 key from the JWKS file at
+// JWT_JWKS_FILE, or both. JWT_ISSUER and JWT_AUDIENCE, when set, must match
+// the token's claims. Authentication is only turned off by
+// AUTH_DISABLED=true, for local development.
+func authenticatorFromEnv() (mux.MiddlewareFunc, error) {
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		slog.Warn("authentication is disabled, every API request is anonymous")
+		return nil, nil
+	}
+
+	config := middleware.JWTConfig{
+		HMACSecret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		Leeway:     time.Minute,
+	}
+
+	if jwksFile := os.Getenv("JWT_JWKS_FILE"); jwksFile != "" {
+		keys, err := middleware.LoadJWKS(jwksFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RSAKeys = keys
+	}
+
+	authenticator, err := middleware.NewJWTAuthenticator(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w (set JWT_HS256_SECRET or JWT_JWKS_FILE, or AUTH_DISABLED=true)", err)
+	}
+	return authenticator.Authenticate, nil
+}
+
+// scheduleTermsFromEnv overrides the default repayment schedule terms with
+// SCHEDULE_TENOR, SCHEDULE_FREQUENCY (WEEKLY or MONTHLY) and
+// SCHEDULE_INTEREST_METHOD (FLAT or ANNUITY) when they are set.
+func scheduleTermsFromEnv() (domain.ScheduleTerms, error) {
+	terms := service.DefaultScheduleTerms
+
+	tenor, err := envInt("SCHEDULE_TENOR", terms.Tenor)
+	if err != nil {
+		return terms, err
+	}
+	terms.Tenor = tenor
+
+	if frequency := os.Getenv("SCHEDULE_FREQUENCY"); frequency != "" {
+		terms.Frequency = domain.RepaymentFrequency(frequency)
+	}
+
+	if method := os.Getenv("SCHEDULE_INTEREST_METHOD"); method != "" {
+		terms.Method = domain.InterestMethod(method)
+	}
+
+	return terms, terms.Validate()
+}
+
+// delinquencyPolicyFromEnv overrides the default delinquency thresholds with
+// DELINQUENCY_GRACE_DAYS and DEFAULT_AFTER_DAYS when they are set.
+func delinquencyPolicyFromEnv() (domain.DelinquencyPolicy, error) {
+	policy := service.DefaultDelinquencyPolicy
+
+	graceDays, err := envInt("DELINQUENCY_GRACE_DAYS", policy.GraceDays)
+	if err != nil {
+		return policy, err
+	}
+	policy.GraceDays = graceDays
+
+	defaultAfterDays, err := envInt("DEFAULT_AFTER_DAYS", policy.DefaultAfterDays)
+	if err != nil {
+		return policy, err
+	}
+	policy.DefaultAfterDays = defaultAfterDays
+
+	return policy, policy.Validate()
+}
+
+// notifierFromEnv delivers notifications to the contacts in
+// CONTACT_DIRECTORY_FILE, a JSON array of contacts with their channel
+// preferences, and otherwise only logs them. Email is sent over SMTP when
+// SMTP_HOST is set, which also needs SMTP_FROM; SMTP_PORT (default 587),
+// SMTP_USERNAME and SMTP_PASSWORD are optional. Without SMTP_HOST, emails
+// are logged. SMS messages are always logged until a gateway is integrated.
+// NOTIFICATION_TEMPLATE_DIR is a directory of templates overriding the
+// defaults.
+func notifierFromEnv() (service.Notifier, error) {
+	directoryFile := os.Getenv("CONTACT_DIRECTORY_FILE")
+	if directoryFile == "" {
+		return service.NewMockNotifier(), nil
+	}
+	directory, err := service.LoadContactDirectory(directoryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := service.DefaultNotificationTemplates()
+	if dir := os.Getenv("NOTIFICATION_TEMPLATE_DIR"); dir != "" {
+		if templates, err = service.LoadNotificationTemplates(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("NOTIFICATION_TEMPLATE_DIR: %w", err)
+		}
+	}
+
+	var mailer service.Mailer = service.NewLogMailer()
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := envInt("SMTP_PORT", 587)
+		if err != nil {
+			return nil, err
+		}
+
+		config := service.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+		mailer = service.NewSMTPMailer(config)
+	}
+
+	return service.NewChannelNotifier(directory,
+		service.NewEmailChannel(mailer, templates),
+		service.NewSMSChannel(service.NewLogSMSGateway(), templates),
+		service.NewWebhookChannel(nil),
+	), nil
+}
+
+// envInt reads an integer environment variable, returning fallback when it
+// is unset
+func envInt(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, nil
 }